@@ -10,9 +10,12 @@ import (
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
 	Elements []Expression
+	Rbracket token.Token // the ']' token
 }
 
-func (al *ArrayLiteral) expressionNode() {}
+func (al *ArrayLiteral) expressionNode()     {}
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position { return al.Rbracket.End }
 func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
@@ -33,12 +36,15 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
-	Token token.Token // The [ token
-	Left  Expression
-	Index Expression
+	Token    token.Token // The [ token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token // The ] token
 }
 
 func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) Pos() token.Position  { return ie.Left.Pos() }
+func (ie *IndexExpression) End() token.Position  { return ie.Rbracket.End }
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
@@ -54,11 +60,13 @@ func (ie *IndexExpression) String() string {
 
 type DotExpression struct {
 	Token token.Token // The . token
-	Left  *Identifier
-	Right Expression
+	Left  Expression
+	Right *Identifier
 }
 
 func (de *DotExpression) expressionNode()      {}
+func (de *DotExpression) Pos() token.Position  { return de.Left.Pos() }
+func (de *DotExpression) End() token.Position  { return de.Right.End() }
 func (de *DotExpression) TokenLiteral() string { return de.Token.Literal }
 func (de *DotExpression) String() string {
 	var out bytes.Buffer
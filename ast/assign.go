@@ -0,0 +1,32 @@
+package ast
+
+import (
+	"bytes"
+
+	"github.com/emo-lang/emo/token"
+)
+
+// AssignExpression assigns to a variable, an index (`arr[0] = 5`) or a field
+// (`self.age = 33`). Operator is "=" or a compound operator such as "+=".
+type AssignExpression struct {
+	Token    token.Token // the assignment operator token
+	Target   Expression  // Identifier, IndexExpression or DotExpression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()     {}
+func (ae *AssignExpression) Pos() token.Position { return ae.Target.Pos() }
+func (ae *AssignExpression) End() token.Position { return ae.Value.End() }
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+
+	return out.String()
+}
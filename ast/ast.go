@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"path"
+	"strings"
 
 	"github.com/emo-lang/emo/token"
 )
@@ -9,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // position of the first character of the node
+	End() token.Position // position immediately after the node
 }
 
 type Statement interface {
@@ -26,7 +30,14 @@ type ReturnStatement struct {
 	ReturnValue Expression
 }
 
-func (rs *ReturnStatement) statementNode() {}
+func (rs *ReturnStatement) statementNode()      {}
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
@@ -44,12 +55,49 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// ImportStatement imports a module by name, `import utils`, or by path,
+// `import "lib/strings.emo"`, optionally under another name with
+// `import "lib/strings.emo" as text`.
 type ImportStatement struct {
 	Token token.Token // the 'import' token
-	Name  Expression
+	Name  Expression  // an *Identifier or a *StringLiteral
+	Alias *Identifier // nil without `as`
+}
+
+func (is *ImportStatement) statementNode()      {}
+func (is *ImportStatement) Pos() token.Position { return is.Token.Pos }
+func (is *ImportStatement) End() token.Position {
+	if is.Alias != nil {
+		return is.Alias.End()
+	}
+	if is.Name != nil {
+		return is.Name.End()
+	}
+	return is.Token.End
+}
+
+// Path returns the imported path as written, without quotes.
+func (is *ImportStatement) Path() string {
+	switch name := is.Name.(type) {
+	case *Identifier:
+		return name.Value
+	case *StringLiteral:
+		return name.Value
+	}
+	return ""
 }
 
-func (is *ImportStatement) statementNode() {}
+// Namespace returns the name the module is bound to: the alias, or the
+// file name of the path without its extension.
+func (is *ImportStatement) Namespace() string {
+	if is.Alias != nil {
+		return is.Alias.Value
+	}
+
+	base := path.Base(is.Path())
+
+	return strings.TrimSuffix(base, path.Ext(base))
+}
 func (is *ImportStatement) TokenLiteral() string {
 	return is.Token.Literal
 }
@@ -64,6 +112,11 @@ func (is *ImportStatement) String() string {
 		out.WriteString("\"")
 	}
 
+	if is.Alias != nil {
+		out.WriteString(" as ")
+		out.WriteString(is.Alias.String())
+	}
+
 	out.WriteString(";")
 
 	return out.String()
@@ -74,7 +127,9 @@ type Boolean struct {
 	Value bool
 }
 
-func (b *Boolean) expressionNode() {}
+func (b *Boolean) expressionNode()     {}
+func (b *Boolean) Pos() token.Position { return b.Token.Pos }
+func (b *Boolean) End() token.Position { return b.Token.End }
 func (b *Boolean) TokenLiteral() string {
 	return b.Token.Literal
 }
@@ -90,7 +145,14 @@ type IfExpression struct {
 	Alternative *BlockStatement
 }
 
-func (ie *IfExpression) expressionNode() {}
+func (ie *IfExpression) expressionNode()     {}
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *IfExpression) End() token.Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	return ie.Consequence.End()
+}
 func (ie *IfExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
@@ -114,9 +176,12 @@ func (ie *IfExpression) String() string {
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	Rbrace     token.Token // the } token
 }
 
-func (bs *BlockStatement) statementNode() {}
+func (bs *BlockStatement) statementNode()      {}
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position { return bs.Rbrace.End }
 func (bs *BlockStatement) TokenLiteral() string {
 	return bs.Token.Literal
 }
@@ -136,7 +201,9 @@ type StringLiteral struct {
 	Value string
 }
 
-func (sl *StringLiteral) expressionNode() {}
+func (sl *StringLiteral) expressionNode()     {}
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position { return sl.Token.End }
 func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
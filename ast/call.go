@@ -11,13 +11,32 @@ type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Token // The ')' token
 }
 
-func (ce *CallExpression) expressionNode() {}
+func (ce *CallExpression) expressionNode()     {}
+func (ce *CallExpression) Pos() token.Position { return ce.Function.Pos() }
+func (ce *CallExpression) End() token.Position { return ce.Rparen.End }
 func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+// NamedArgument is an argument passed by parameter name: `name: value`.
+type NamedArgument struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode()     {}
+func (na *NamedArgument) Pos() token.Position { return na.Name.Pos() }
+func (na *NamedArgument) End() token.Position { return na.Value.End() }
+func (na *NamedArgument) TokenLiteral() string {
+	return na.Name.TokenLiteral()
+}
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
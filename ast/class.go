@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/emo-lang/emo/token"
 )
@@ -9,19 +10,35 @@ import (
 type TypedField struct {
 	Name *Identifier
 	Type *Identifier
+
+	// only for function parameters
+	Default  Expression // the default value, nil when the parameter has none
+	Variadic bool       // `...rest: Array` collects the remaining arguments
 }
 
 func (tf *TypedField) String() string {
-	return fmt.Sprintf("<%s:%s>", tf.Name.String(), tf.Type.String())
+	name := tf.Name.String()
+	if tf.Variadic {
+		name = "..." + name
+	}
+
+	if tf.Default != nil {
+		return fmt.Sprintf("<%s:%s=%s>", name, tf.Type.String(), tf.Default.String())
+	}
+	return fmt.Sprintf("<%s:%s>", name, tf.Type.String())
 }
 
 type ClassField struct {
 	Public bool
 
 	Field *TypedField
+	Value Expression // the initializer, nil when the field has none
 }
 
 func (cf *ClassField) String() string {
+	if cf.Value != nil {
+		return fmt.Sprintf("%s=%s:%v", cf.Field.String(), cf.Value.String(), cf.Public)
+	}
 	return fmt.Sprintf("%s:%v", cf.Field.String(), cf.Public)
 }
 
@@ -35,32 +52,52 @@ func (cm *ClassMethod) String() string {
 }
 
 type ClassExpression struct {
-	Token   token.Token // the 'class' token
-	Name    *Identifier
-	Fields  map[string]*ClassField
-	Methods map[string]*ClassMethod
+	Token token.Token // the 'class' token
+	Name  *Identifier
+	Super *Identifier // the class after 'extends', nil if there is none
+	// the interfaces after 'implements'
+	Implements []*Identifier
+	Fields     map[string]*ClassField
+	Methods    map[string]*ClassMethod
+	Rbrace     token.Token // the closing '}' token
 }
 
-func (ce *ClassExpression) expressionNode() {}
+func (ce *ClassExpression) expressionNode()     {}
+func (ce *ClassExpression) Pos() token.Position { return ce.Token.Pos }
+func (ce *ClassExpression) End() token.Position { return ce.Rbrace.End }
 func (ce *ClassExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
 func (ce *ClassExpression) String() string {
+	if ce.Super != nil {
+		return "<class " + ce.Name.String() + " extends " + ce.Super.String() + ">"
+	}
 	return "<class " + ce.Name.String() + ">"
 }
 
+// NewExpression is `new(Class, args...)`. The arguments are passed to the
+// init method of the class, or else name its fields: `new(Person, name: "x")`
+// or `new(Person, {name: "x"})`.
 type NewExpression struct {
-	Token token.Token // the 'new' token
-	What  *Identifier
-	Data  Expression
+	Token     token.Token // the 'new' token
+	What      *Identifier
+	Arguments []Expression
+	Rparen    token.Token // the ')' token
 }
 
-func (ne *NewExpression) expressionNode() {}
+func (ne *NewExpression) expressionNode()     {}
+func (ne *NewExpression) Pos() token.Position { return ne.Token.Pos }
+func (ne *NewExpression) End() token.Position { return ne.Rparen.End }
 func (ne *NewExpression) TokenLiteral() string {
 	return ne.Token.Literal
 }
 
 func (ne *NewExpression) String() string {
-	return "new(" + ne.What.String() + ", " + fmt.Sprintf("%s", ne.Data) + ")"
+	args := []string{ne.What.String()}
+	for _, a := range ne.Arguments {
+		args = append(args, a.String())
+	}
+
+	return "new(" + strings.Join(args, ", ") + ")"
 }
@@ -7,12 +7,15 @@ import (
 )
 
 type DefineStatement struct {
-	Token token.Token // the token.DEFINE token
-	Name  *Identifier
-	Value Expression
+	Token  token.Token // the token.DEFINE token
+	Name   *Identifier
+	Value  Expression
+	Rparen token.Token // the ')' token
 }
 
 func (vs *DefineStatement) statementNode()       {}
+func (vs *DefineStatement) Pos() token.Position  { return vs.Token.Pos }
+func (vs *DefineStatement) End() token.Position  { return vs.Rparen.End }
 func (vs *DefineStatement) TokenLiteral() string { return vs.Token.Literal }
 
 func (vs *DefineStatement) String() string {
@@ -32,12 +35,19 @@ func (vs *DefineStatement) String() string {
 }
 
 type VarStatement struct {
-	Token token.Token // the token.VAR token
+	Token token.Token // the token.VAR or token.CONST token
 	Name  *Identifier
 	Value Expression
 }
 
-func (vs *VarStatement) statementNode()       {}
+func (vs *VarStatement) statementNode()      {}
+func (vs *VarStatement) Pos() token.Position { return vs.Token.Pos }
+func (vs *VarStatement) End() token.Position {
+	if vs.Value != nil {
+		return vs.Value.End()
+	}
+	return vs.Name.End()
+}
 func (vs *VarStatement) TokenLiteral() string { return vs.Token.Literal }
 
 func (vs *VarStatement) String() string {
@@ -59,9 +69,20 @@ func (vs *VarStatement) String() string {
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value string
+
+	// set by the resolver for the locals of functions: the local is in Slot
+	// of the environment Depth levels out of the one the name is used in
+	Resolved bool
+	Depth    int
+	Slot     int
+	// set by the resolver for the other names functions use, except the
+	// ones methods bind: the name is a global
+	Global bool
 }
 
 func (i *Identifier) expressionNode()      {}
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
+func (i *Identifier) End() token.Position  { return i.Token.End }
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string {
 	return i.Value
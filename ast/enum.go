@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/emo-lang/emo/token"
+)
+
+// EnumVariant is a variant of an enum, with the names and types of the
+// values it carries, if any: `Suspended(reason: String)`.
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*TypedField
+}
+
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+type EnumExpression struct {
+	Token    token.Token // the 'enum' token
+	Name     *Identifier
+	Variants []*EnumVariant
+	Rbrace   token.Token // the closing '}' token
+}
+
+func (ee *EnumExpression) expressionNode()     {}
+func (ee *EnumExpression) Pos() token.Position { return ee.Token.Pos }
+func (ee *EnumExpression) End() token.Position { return ee.Rbrace.End }
+func (ee *EnumExpression) TokenLiteral() string {
+	return ee.Token.Literal
+}
+
+func (ee *EnumExpression) String() string {
+	variants := []string{}
+	for _, v := range ee.Variants {
+		variants = append(variants, v.String())
+	}
+
+	return "enum " + ee.Name.String() + " { " + strings.Join(variants, ", ") + " }"
+}
@@ -8,6 +8,18 @@ type ExpressionStatement struct {
 }
 
 func (es *ExpressionStatement) statementNode() {}
+func (es *ExpressionStatement) Pos() token.Position {
+	if es.Expression != nil {
+		return es.Expression.Pos()
+	}
+	return es.Token.Pos
+}
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 func (es *ExpressionStatement) TokenLiteral() string {
 	return es.Token.Literal
 }
@@ -19,3 +31,23 @@ func (es *ExpressionStatement) String() string {
 
 	return ""
 }
+
+// ParenExpression is an expression in parentheses, kept so that its span
+// takes them in.
+type ParenExpression struct {
+	Token      token.Token // the '(' token
+	Expression Expression
+	Rparen     token.Token
+}
+
+func (pe *ParenExpression) expressionNode()     {}
+func (pe *ParenExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *ParenExpression) End() token.Position { return pe.Rparen.End }
+func (pe *ParenExpression) TokenLiteral() string {
+	return pe.Token.Literal
+}
+
+// String leaves the parentheses out: the expressions in them print their own.
+func (pe *ParenExpression) String() string {
+	return pe.Expression.String()
+}
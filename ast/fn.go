@@ -12,9 +12,13 @@ type FunctionLiteral struct {
 	Parameters  []*TypedField
 	ReturnTypes []*Identifier
 	Body        *BlockStatement
+	Locals      []string       // the slots of the locals, set by the resolver
+	Slots       map[string]int // the slot of each local, set with Locals
 }
 
-func (fd *FunctionLiteral) expressionNode() {}
+func (fd *FunctionLiteral) expressionNode()     {}
+func (fd *FunctionLiteral) Pos() token.Position { return fd.Token.Pos }
+func (fd *FunctionLiteral) End() token.Position { return fd.Body.End() }
 func (fd *FunctionLiteral) TokenLiteral() string {
 	return fd.Token.Literal
 }
@@ -42,9 +46,13 @@ type FunctionDefinition struct {
 	Parameters  []*TypedField
 	ReturnTypes []*Identifier
 	Body        *BlockStatement
+	Locals      []string       // the slots of the locals, set by the resolver
+	Slots       map[string]int // the slot of each local, set with Locals
 }
 
-func (fd *FunctionDefinition) expressionNode() {}
+func (fd *FunctionDefinition) expressionNode()     {}
+func (fd *FunctionDefinition) Pos() token.Position { return fd.Token.Pos }
+func (fd *FunctionDefinition) End() token.Position { return fd.Body.End() }
 func (fd *FunctionDefinition) TokenLiteral() string {
 	return fd.Token.Literal
 }
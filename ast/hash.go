@@ -8,11 +8,14 @@ import (
 )
 
 type HashLiteral struct {
-	Token token.Token // the '{' token
-	Pairs map[string]Expression
+	Token  token.Token // the '{' token
+	Pairs  map[string]Expression
+	Rbrace token.Token // the '}' token
 }
 
 func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position  { return hl.Rbrace.End }
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
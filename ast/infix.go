@@ -13,7 +13,9 @@ type InfixExpression struct {
 	Right    Expression
 }
 
-func (ie *InfixExpression) expressionNode() {}
+func (ie *InfixExpression) expressionNode()     {}
+func (ie *InfixExpression) Pos() token.Position { return ie.Left.Pos() }
+func (ie *InfixExpression) End() token.Position { return ie.Right.End() }
 func (ie *InfixExpression) TokenLiteral() string {
 	return ie.Token.Literal
 }
@@ -7,7 +7,9 @@ type IntegerLiteral struct {
 	Value int64
 }
 
-func (il *IntegerLiteral) expressionNode() {}
+func (il *IntegerLiteral) expressionNode()     {}
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
 func (il *IntegerLiteral) TokenLiteral() string {
 	return il.Token.Literal
 }
@@ -0,0 +1,60 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/emo-lang/emo/token"
+)
+
+// InterfaceMethod is a method signature in an interface: a method without
+// a body.
+type InterfaceMethod struct {
+	Token       token.Token // the 'func' token
+	Name        *Identifier
+	Parameters  []*TypedField
+	ReturnTypes []*Identifier
+}
+
+func (im *InterfaceMethod) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range im.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("func ")
+	out.WriteString(im.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+
+	if len(im.ReturnTypes) > 0 {
+		types := []string{}
+		for _, t := range im.ReturnTypes {
+			types = append(types, t.String())
+		}
+		out.WriteString(" -> (" + strings.Join(types, ", ") + ")")
+	}
+
+	return out.String()
+}
+
+type InterfaceExpression struct {
+	Token   token.Token // the 'interface' token
+	Name    *Identifier
+	Methods map[string]*InterfaceMethod
+	Rbrace  token.Token // the closing '}' token
+}
+
+func (ie *InterfaceExpression) expressionNode()     {}
+func (ie *InterfaceExpression) Pos() token.Position { return ie.Token.Pos }
+func (ie *InterfaceExpression) End() token.Position { return ie.Rbrace.End }
+func (ie *InterfaceExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie *InterfaceExpression) String() string {
+	return "<interface " + ie.Name.String() + ">"
+}
@@ -0,0 +1,174 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/emo-lang/emo/token"
+)
+
+// MatchExpression evaluates the body of the first arm whose pattern
+// matches the value.
+type MatchExpression struct {
+	Token  token.Token // the 'match' token
+	Value  Expression
+	Arms   []*MatchArm
+	Rbrace token.Token // the closing '}' token
+}
+
+func (me *MatchExpression) expressionNode()     {}
+func (me *MatchExpression) Pos() token.Position { return me.Token.Pos }
+func (me *MatchExpression) End() token.Position { return me.Rbrace.End }
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match ")
+	out.WriteString(me.Value.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// MatchArm is `pattern -> expression` or `pattern -> { statements }`.
+type MatchArm struct {
+	Pattern Pattern
+	Body    Node // an Expression or a *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	return ma.Pattern.String() + " -> " + ma.Body.String()
+}
+
+// Pattern is the left side of a match arm.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// WildcardPattern `_` matches any value.
+type WildcardPattern struct {
+	Token token.Token // the '_' token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) Pos() token.Position  { return wp.Token.Pos }
+func (wp *WildcardPattern) End() token.Position  { return wp.Token.End }
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// BindingPattern matches any value and binds it to a name.
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) Pos() token.Position  { return bp.Name.Pos() }
+func (bp *BindingPattern) End() token.Position  { return bp.Name.End() }
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// LiteralPattern matches an integer, string or boolean equal to Value.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) Pos() token.Position  { return lp.Value.Pos() }
+func (lp *LiteralPattern) End() token.Position  { return lp.Value.End() }
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+// ArrayPattern matches an array with an element for each pattern, or at
+// least that many when the rest of the elements are bound by `...rest`.
+type ArrayPattern struct {
+	Token    token.Token // the '[' token
+	Elements []Pattern
+	Rest     *Identifier // nil without `...rest`
+	Rbracket token.Token // the ']' token
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) Pos() token.Position  { return ap.Token.Pos }
+func (ap *ArrayPattern) End() token.Position  { return ap.Rbracket.End }
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPatternPair matches the value of a key: `name: pattern`, or `name`
+// to bind the value to the name of the key.
+type HashPatternPair struct {
+	Key     *Identifier
+	Pattern Pattern
+}
+
+// HashPattern matches a hash that has all the keys of the pattern.
+type HashPattern struct {
+	Token  token.Token // the '{' token
+	Pairs  []*HashPatternPair
+	Rbrace token.Token // the '}' token
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) Pos() token.Position  { return hp.Token.Pos }
+func (hp *HashPattern) End() token.Position  { return hp.Rbrace.End }
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Pattern.String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// VariantPattern matches a value of an enum variant: `Status.Active`, or
+// `Status.Suspended(reason)` to match the values it carries too.
+type VariantPattern struct {
+	Enum      *Identifier
+	Variant   *Identifier
+	Arguments []Pattern   // nil without parentheses
+	Rparen    token.Token // the ')' token, if any
+}
+
+func (vp *VariantPattern) patternNode()        {}
+func (vp *VariantPattern) Pos() token.Position { return vp.Enum.Pos() }
+func (vp *VariantPattern) End() token.Position {
+	if vp.Arguments != nil {
+		return vp.Rparen.End
+	}
+	return vp.Variant.End()
+}
+func (vp *VariantPattern) TokenLiteral() string { return vp.Enum.TokenLiteral() }
+func (vp *VariantPattern) String() string {
+	name := vp.Enum.String() + "." + vp.Variant.String()
+	if vp.Arguments == nil {
+		return name
+	}
+
+	args := []string{}
+	for _, arg := range vp.Arguments {
+		args = append(args, arg.String())
+	}
+
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
@@ -12,7 +12,9 @@ type PrefixExpression struct {
 	Right    Expression
 }
 
-func (pe *PrefixExpression) expressionNode() {}
+func (pe *PrefixExpression) expressionNode()     {}
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Pos }
+func (pe *PrefixExpression) End() token.Position { return pe.Right.End() }
 func (pe *PrefixExpression) TokenLiteral() string {
 	return pe.Token.Literal
 }
@@ -0,0 +1,65 @@
+package ast
+
+import (
+	"bytes"
+
+	"github.com/emo-lang/emo/token"
+)
+
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()      {}
+func (ts *ThrowStatement) Pos() token.Position { return ts.Token.Pos }
+func (ts *ThrowStatement) End() token.Position { return ts.Value.End() }
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+func (ts *ThrowStatement) String() string {
+	return "throw " + ts.Value.String() + ";"
+}
+
+// TryExpression is `try { } catch e { } finally { }`. Either Catch or Finally
+// may be nil, but not both. CatchParam is nil when the error is not bound.
+type TryExpression struct {
+	Token      token.Token // the 'try' token
+	Block      *BlockStatement
+	CatchParam *Identifier
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()     {}
+func (te *TryExpression) Pos() token.Position { return te.Token.Pos }
+func (te *TryExpression) End() token.Position {
+	if te.Finally != nil {
+		return te.Finally.End()
+	}
+	return te.Catch.End()
+}
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Block.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch ")
+		if te.CatchParam != nil {
+			out.WriteString(te.CatchParam.String() + " ")
+		}
+		out.WriteString(te.Catch.String())
+	}
+
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
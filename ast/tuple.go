@@ -0,0 +1,79 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/emo-lang/emo/token"
+)
+
+// TupleLiteral is the list of values in `return a, b`.
+type TupleLiteral struct {
+	Token    token.Token // the first token of the first element
+	Elements []Expression
+}
+
+func (tl *TupleLiteral) expressionNode()     {}
+func (tl *TupleLiteral) Pos() token.Position { return tl.Elements[0].Pos() }
+func (tl *TupleLiteral) End() token.Position { return tl.Elements[len(tl.Elements)-1].End() }
+func (tl *TupleLiteral) TokenLiteral() string {
+	return tl.Token.Literal
+}
+
+func (tl *TupleLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range tl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// DestructuringStatement declares a variable for each part of a value:
+// `var (a, b) = f()` takes the values of a tuple, `var [a, b] = arr` the
+// first elements of an array and `var {a, b} = hash` the values of the keys
+// a and b.
+type DestructuringStatement struct {
+	Token token.Token     // the 'var' token
+	Kind  token.TokenType // token.LPAREN, token.LBRACKET or token.LBRACE
+	Names []*Identifier
+	Value Expression
+}
+
+func (ds *DestructuringStatement) statementNode()      {}
+func (ds *DestructuringStatement) Pos() token.Position { return ds.Token.Pos }
+func (ds *DestructuringStatement) End() token.Position { return ds.Value.End() }
+func (ds *DestructuringStatement) TokenLiteral() string {
+	return ds.Token.Literal
+}
+
+func (ds *DestructuringStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, n := range ds.Names {
+		names = append(names, n.String())
+	}
+
+	open, close := "(", ")"
+	switch ds.Kind {
+	case token.LBRACKET:
+		open, close = "[", "]"
+	case token.LBRACE:
+		open, close = "{", "}"
+	}
+
+	out.WriteString(ds.TokenLiteral() + " ")
+	out.WriteString(open + strings.Join(names, ", ") + close)
+	out.WriteString(" = ")
+	out.WriteString(ds.Value.String())
+	out.WriteString(";")
+
+	return out.String()
+}
@@ -0,0 +1,306 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions are the bytecode of a compiled function: each instruction is
+// an opcode followed by its operands, big-endian.
+type Instructions []byte
+
+// Opcode is the first byte of an instruction.
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota
+	OpPop
+	OpDup
+	OpTrue
+	OpFalse
+	OpNil
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpLess
+	OpGreater
+	OpRange
+	OpMinus
+	OpBang
+
+	OpJump
+	OpJumpNotTruthy
+
+	OpGetGlobal
+	OpSetGlobal
+	OpDefineGlobal
+	OpCheckGlobal
+	OpCheckConst
+
+	OpGetLocal
+	OpSetLocal
+	OpGetCell
+	OpSetCell
+	OpLoadCell
+	OpGetFree
+	OpSetFree
+	OpLoadFree
+	OpFreeze
+
+	OpArray
+	OpHash
+	OpTuple
+	OpIndex
+	OpSetIndex
+	OpGetMember
+	OpSetMember
+	OpDestructure
+
+	OpClosure
+	OpCall
+	OpTailCall
+	OpReturn
+	OpJumpIfSet
+	OpSetParam
+
+	OpIter
+	OpIterNext
+
+	OpTry
+	OpEndTry
+	OpException
+	OpThrow
+	OpRethrow
+	OpRaise
+
+	OpImport
+
+	OpClass
+	OpImplement
+	OpInterface
+	OpEnum
+	OpNew
+
+	OpSame
+	OpMatchArray
+	OpMatchHash
+	OpMatchKey
+	OpMatchVariant
+	OpNoMatch
+)
+
+// Definition describes an opcode for disassembly and decoding.
+type Definition struct {
+	Name          string
+	OperandWidths []int // in bytes, 1 or 2
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant: {"OpConstant", []int{2}},
+	OpPop:      {"OpPop", []int{}},
+	OpDup:      {"OpDup", []int{}},
+	OpTrue:     {"OpTrue", []int{}},
+	OpFalse:    {"OpFalse", []int{}},
+	OpNil:      {"OpNil", []int{}},
+
+	OpAdd:      {"OpAdd", []int{}},
+	OpSub:      {"OpSub", []int{}},
+	OpMul:      {"OpMul", []int{}},
+	OpDiv:      {"OpDiv", []int{}},
+	OpEqual:    {"OpEqual", []int{}},
+	OpNotEqual: {"OpNotEqual", []int{}},
+	OpLess:     {"OpLess", []int{}},
+	OpGreater:  {"OpGreater", []int{}},
+	OpRange:    {"OpRange", []int{}},
+	OpMinus:    {"OpMinus", []int{}},
+	OpBang:     {"OpBang", []int{}},
+
+	// jump targets are offsets in the instructions of the function
+	OpJump:          {"OpJump", []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+
+	// global slot, and 1 to declare a constant
+	OpGetGlobal:    {"OpGetGlobal", []int{2}},
+	OpSetGlobal:    {"OpSetGlobal", []int{2}},
+	OpDefineGlobal: {"OpDefineGlobal", []int{2, 1}},
+	OpCheckGlobal:  {"OpCheckGlobal", []int{2}},
+	OpCheckConst:   {"OpCheckConst", []int{2}},
+
+	// local slot, or index of a free variable of the closure
+	OpGetLocal: {"OpGetLocal", []int{1}},
+	OpSetLocal: {"OpSetLocal", []int{1}},
+	OpGetCell:  {"OpGetCell", []int{1}},
+	OpSetCell:  {"OpSetCell", []int{1}},
+	OpLoadCell: {"OpLoadCell", []int{1}},
+	OpGetFree:  {"OpGetFree", []int{1}},
+	OpSetFree:  {"OpSetFree", []int{1}},
+	OpLoadFree: {"OpLoadFree", []int{1}},
+	OpFreeze:   {"OpFreeze", []int{}},
+
+	// number of elements, pairs or names, and the constant holding the
+	// names where there are any
+	OpArray:       {"OpArray", []int{2}},
+	OpHash:        {"OpHash", []int{2}},
+	OpTuple:       {"OpTuple", []int{2}},
+	OpIndex:       {"OpIndex", []int{}},
+	OpSetIndex:    {"OpSetIndex", []int{1}},
+	OpGetMember:   {"OpGetMember", []int{2}},
+	OpSetMember:   {"OpSetMember", []int{2, 1}},
+	OpDestructure: {"OpDestructure", []int{1, 2}},
+
+	// constant of the function and number of free variables; number of
+	// arguments and the constant naming the named ones
+	OpClosure:   {"OpClosure", []int{2, 1}},
+	OpCall:      {"OpCall", []int{1, 2}},
+	OpTailCall:  {"OpTailCall", []int{1, 2}},
+	OpReturn:    {"OpReturn", []int{}},
+	OpJumpIfSet: {"OpJumpIfSet", []int{1, 2}},
+	OpSetParam:  {"OpSetParam", []int{1}},
+
+	// number of loop variables and the end of the loop
+	OpIter:     {"OpIter", []int{}},
+	OpIterNext: {"OpIterNext", []int{1, 2}},
+
+	// start of the handler; constant of the raised error
+	OpTry:       {"OpTry", []int{2}},
+	OpEndTry:    {"OpEndTry", []int{}},
+	OpException: {"OpException", []int{}},
+	OpThrow:     {"OpThrow", []int{}},
+	OpRethrow:   {"OpRethrow", []int{}},
+	OpRaise:     {"OpRaise", []int{2}},
+
+	OpImport: {"OpImport", []int{2}},
+
+	// constant of the class, and the index of an interface of it after
+	// implements; constant of the interface; constant of the enum and
+	// number of its free variables
+	OpClass:     {"OpClass", []int{2}},
+	OpImplement: {"OpImplement", []int{2, 1}},
+	OpInterface: {"OpInterface", []int{2}},
+	OpEnum:      {"OpEnum", []int{2, 1}},
+	// number of arguments, the constant naming the named ones and the
+	// constant of the name of the class
+	OpNew: {"OpNew", []int{1, 2, 2}},
+
+	// the instructions testing the patterns of match arms pop the value
+	// and jump to their last operand when it does not match: number of
+	// elements and 1
+	// with a rest; constant of a key; constant naming the enum and the
+	// variant, and the number of argument patterns or NoArguments
+	OpSame:         {"OpSame", []int{}},
+	OpMatchArray:   {"OpMatchArray", []int{1, 1, 2}},
+	OpMatchHash:    {"OpMatchHash", []int{2}},
+	OpMatchKey:     {"OpMatchKey", []int{2, 2}},
+	OpMatchVariant: {"OpMatchVariant", []int{2, 1, 2}},
+	OpNoMatch:      {"OpNoMatch", []int{}},
+}
+
+// NoNames is the names operand of a call without named arguments.
+const NoNames = 1<<16 - 1
+
+// NoArguments is the operand of OpMatchVariant for a pattern of a variant
+// without parentheses.
+const NoArguments = 1<<8 - 1
+
+// assignOperators are the operators of assignments, numbered for the
+// operand of OpSetIndex and OpSetMember.
+var assignOperators = []string{"=", "+=", "-=", "*=", "/="}
+
+// AssignOperator returns the assignment operator numbered n.
+func AssignOperator(n int) string {
+	return assignOperators[n]
+}
+
+// Lookup returns the definition of op.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// Make encodes an instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[i]
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction of def and returns
+// them with the number of bytes they take.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ins[offset])
+		}
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// String disassembles the instructions, one per line.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s", i, def.Name)
+		for _, o := range operands {
+			fmt.Fprintf(&out, " %d", o)
+		}
+		out.WriteString("\n")
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
@@ -0,0 +1,1221 @@
+// Package compiler lowers programs to bytecode for the vm package.
+//
+// Compiled programs behave like evaluated ones, but resolve variables when
+// they are compiled: a name declared in a function is a local of that
+// function from its declaration on, a name of an enclosing function is a
+// free variable, and any other name is a global. The methods and the field
+// initializers of a class are compiled like functions defined where the
+// class is, and a method finds self, super and itself in the locals after
+// its parameters.
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/evaluator"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/resolver"
+	"github.com/emo-lang/emo/token"
+)
+
+// Error is an error found while compiling a program.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Message
+}
+
+type Compiler struct {
+	constants   []object.Object
+	globals     map[string]int // the globals of the program or module being compiled
+	globalNames []string
+	imports     []*Import
+
+	modules  []*Module
+	programs []*ast.Program // the programs of the modules
+	paths    map[string]int // the modules by the absolute path of their file
+
+	scope *scope
+}
+
+func New() *Compiler {
+	return &Compiler{globals: map[string]int{}, paths: map[string]int{}}
+}
+
+// Compile compiles program, whose value is the value of its last statement
+// or of a top-level return, and the files it imports.
+func (c *Compiler) Compile(program *ast.Program) (*Bytecode, error) {
+	// the compiler finds the locals itself, but the program must use them
+	// after their declaration as for the evaluator
+	if errs := resolver.Resolve(program); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	main, err := c.compileProgram(program)
+	if err != nil {
+		return nil, err
+	}
+	main.Name = "<main>"
+
+	// the modules the modules import are added as they are compiled
+	for i := 0; i < len(c.modules); i++ {
+		module := c.modules[i]
+		c.globals = map[string]int{}
+
+		fn, err := c.compileProgram(c.programs[i])
+		if err != nil {
+			return nil, err
+		}
+		fn.Name = "<module " + module.Name + ">"
+		module.Main = fn
+
+		for _, slot := range c.globals {
+			module.Globals = append(module.Globals, slot)
+		}
+		sort.Ints(module.Globals)
+	}
+
+	return &Bytecode{
+		Main:      main,
+		Constants: c.constants,
+		Globals:   c.globalNames,
+		Imports:   c.imports,
+		Modules:   c.modules,
+	}, nil
+}
+
+// compileProgram compiles the top-level statements of a program or module.
+func (c *Compiler) compileProgram(program *ast.Program) (*Function, error) {
+	c.scope = newScope(nil, nil)
+
+	if err := c.compileBlock(program.Statements, program.Pos()); err != nil {
+		return nil, err
+	}
+	c.emit(program.End(), OpReturn)
+
+	return c.function(c.scope, program.Pos())
+}
+
+// compileImport compiles an import statement. A file is compiled once,
+// however many statements import it, after the program. The errors of
+// finding and parsing files are raised when the statements run, as the
+// evaluator raises them.
+func (c *Compiler) compileImport(node *ast.ImportStatement) error {
+	imp := &Import{Node: node, Module: -1}
+
+	c.emit(node.Pos(), OpImport, len(c.imports))
+	c.imports = append(c.imports, imp)
+	c.declare(&ast.Identifier{Token: node.Token, Value: node.Namespace()}, false)
+
+	if evaluator.IsStdModule(node) {
+		return nil
+	}
+
+	path, abs, name, err := evaluator.FindModule(node)
+	if err != nil {
+		imp.Err = err
+		return nil
+	}
+
+	if i, ok := c.paths[abs]; ok {
+		imp.Module = i
+		return nil
+	}
+
+	program, err := evaluator.ParseModule(node, path)
+	if err != nil {
+		imp.Err = err
+		return nil
+	}
+
+	if errs := resolver.Resolve(program); len(errs) > 0 {
+		return errs[0]
+	}
+
+	imp.Module = len(c.modules)
+	c.paths[abs] = imp.Module
+	c.modules = append(c.modules, &Module{Name: name, Path: path})
+	c.programs = append(c.programs, program)
+
+	return nil
+}
+
+// function returns the compiled function of the code of s.
+func (c *Compiler) function(s *scope, pos token.Position) (*Function, error) {
+	if len(s.names) > 256 || len(s.free) > 256 {
+		return nil, &Error{Pos: pos, Message: "too many variables in function"}
+	}
+
+	fn := &Function{
+		Instructions: s.instructions,
+		Locals:       s.names,
+		MaxStack:     s.maxDepth,
+		Lines:        s.lines,
+	}
+
+	for _, free := range s.free {
+		fn.Free = append(fn.Free, free.name)
+	}
+
+	for _, name := range s.names {
+		if s.cells[name] {
+			fn.Cells = append(fn.Cells, s.locals[name].index)
+		}
+	}
+
+	return fn, nil
+}
+
+// compileBlock compiles statements leaving the value of the last one on
+// the stack, or nil when there are none or the last one has no value.
+func (c *Compiler) compileBlock(statements []ast.Statement, pos token.Position) error {
+	if len(statements) == 0 {
+		c.emit(pos, OpNil)
+		return nil
+	}
+
+	for _, stmt := range statements[:len(statements)-1] {
+		if err := c.compileStatement(stmt); err != nil {
+			return err
+		}
+	}
+
+	last := statements[len(statements)-1]
+	switch last := last.(type) {
+	case *ast.ExpressionStatement:
+		return c.compileExpression(last.Expression)
+	default:
+		if err := c.compileStatement(last); err != nil {
+			return err
+		}
+		c.emit(last.End(), OpNil)
+		return nil
+	}
+}
+
+// compileStatements compiles statements for their effects only.
+func (c *Compiler) compileStatements(statements []ast.Statement) error {
+	for _, stmt := range statements {
+		if err := c.compileStatement(stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileStatement(stmt ast.Statement) error {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if err := c.compileExpression(stmt.Expression); err != nil {
+			return err
+		}
+		c.emit(stmt.Pos(), OpPop)
+	case *ast.BlockStatement:
+		return c.compileStatements(stmt.Statements)
+	case *ast.VarStatement:
+		if err := c.compileExpression(stmt.Value); err != nil {
+			return err
+		}
+		c.declare(stmt.Name, stmt.Token.Type == token.CONST)
+	case *ast.DefineStatement:
+		if err := c.compileExpression(stmt.Value); err != nil {
+			return err
+		}
+		c.declare(stmt.Name, true)
+	case *ast.DestructuringStatement:
+		return c.compileDestructuring(stmt)
+	case *ast.ImportStatement:
+		return c.compileImport(stmt)
+	case *ast.ReturnStatement:
+		// a call in tail position replaces the call of the function, out
+		// of try blocks, whose handlers must see its errors
+		if call, ok := stmt.ReturnValue.(*ast.CallExpression); ok && c.scope.outer != nil && len(c.scope.tries) == 0 {
+			if err := c.compileCall(call, OpTailCall); err != nil {
+				return err
+			}
+			c.emit(stmt.Pos(), OpReturn)
+			break
+		}
+
+		if stmt.ReturnValue != nil {
+			if err := c.compileExpression(stmt.ReturnValue); err != nil {
+				return err
+			}
+		} else {
+			c.emit(stmt.Pos(), OpNil)
+		}
+
+		if err := c.leaveTries(0); err != nil {
+			return err
+		}
+		c.emit(stmt.Pos(), OpReturn)
+	case *ast.ThrowStatement:
+		if err := c.compileExpression(stmt.Value); err != nil {
+			return err
+		}
+		c.emit(stmt.Pos(), OpThrow)
+	case *ast.WhileStatement:
+		return c.compileWhile(stmt)
+	case *ast.ForStatement:
+		return c.compileFor(stmt)
+	case *ast.BreakStatement:
+		return c.compileLoopExit(stmt.Pos(), true)
+	case *ast.ContinueStatement:
+		return c.compileLoopExit(stmt.Pos(), false)
+	default:
+		return unsupported(stmt)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileExpression(node ast.Expression) error {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		c.emitConstant(node.Pos(), &object.Integer{Value: node.Value})
+	case *ast.StringLiteral:
+		c.emitConstant(node.Pos(), &object.String{Value: node.Value})
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(node.Pos(), OpTrue)
+		} else {
+			c.emit(node.Pos(), OpFalse)
+		}
+	case *ast.ParenExpression:
+		return c.compileExpression(node.Expression)
+	case *ast.PrefixExpression:
+		if err := c.compileExpression(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(node.Pos(), OpBang)
+		case "-":
+			c.emit(node.Pos(), OpMinus)
+		default:
+			return &Error{Pos: node.Pos(), Message: fmt.Sprintf("unknown operator %s", node.Operator)}
+		}
+	case *ast.InfixExpression:
+		op, ok := infixOperators[node.Operator]
+		if !ok {
+			return &Error{Pos: node.Pos(), Message: fmt.Sprintf("unknown operator %s", node.Operator)}
+		}
+
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Right); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), op)
+	case *ast.Identifier:
+		c.load(node.Pos(), c.resolve(c.scope, node.Value))
+	case *ast.IfExpression:
+		return c.compileIf(node)
+	case *ast.TryExpression:
+		return c.compileTry(node)
+	case *ast.AssignExpression:
+		return c.compileAssignment(node)
+	case *ast.ArrayLiteral:
+		if err := c.compileExpressions(node.Elements); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpArray, len(node.Elements))
+	case *ast.TupleLiteral:
+		if err := c.compileExpressions(node.Elements); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpTuple, len(node.Elements))
+	case *ast.HashLiteral:
+		keys := make([]string, 0, len(node.Pairs))
+		for key := range node.Pairs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			c.emitConstant(node.Pos(), &object.String{Value: key})
+			if err := c.compileExpression(node.Pairs[key]); err != nil {
+				return err
+			}
+		}
+		c.emit(node.Pos(), OpHash, len(keys))
+	case *ast.IndexExpression:
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Index); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpIndex)
+	case *ast.DotExpression:
+		if err := c.compileExpression(node.Left); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpGetMember, c.addConstant(&object.String{Value: node.Right.Value}))
+	case *ast.FunctionDefinition:
+		return c.compileFunctionDefinition(node)
+	case *ast.FunctionLiteral:
+		return c.compileFunction("", node.Parameters, node.ReturnTypes, node.Body, node.Pos(), "")
+	case *ast.CallExpression:
+		return c.compileCall(node, OpCall)
+	case *ast.ClassExpression:
+		return c.compileClass(node)
+	case *ast.NewExpression:
+		return c.compileNew(node)
+	case *ast.InterfaceExpression:
+		c.emit(node.Pos(), OpInterface, c.addConstant(&Interface{Node: node}))
+		c.emit(node.Pos(), OpDup)
+		c.bind(node.Name)
+	case *ast.EnumExpression:
+		return c.compileEnum(node)
+	case *ast.MatchExpression:
+		return c.compileMatch(node)
+	default:
+		return unsupported(node)
+	}
+
+	return nil
+}
+
+// DestructuringKinds are the kinds of destructuring statements, numbered
+// for the operand of OpDestructure.
+var DestructuringKinds = []token.TokenType{token.LPAREN, token.LBRACKET, token.LBRACE}
+
+var infixOperators = map[string]Opcode{
+	"+":  OpAdd,
+	"-":  OpSub,
+	"*":  OpMul,
+	"/":  OpDiv,
+	"==": OpEqual,
+	"!=": OpNotEqual,
+	"<":  OpLess,
+	">":  OpGreater,
+	"..": OpRange,
+}
+
+// unsupported reports a node the compiler cannot compile.
+func unsupported(node ast.Node) error {
+	return &Error{Pos: node.Pos(), Message: fmt.Sprintf("%T nodes are not supported by the compiler", node)}
+}
+
+func (c *Compiler) compileExpressions(exps []ast.Expression) error {
+	for _, e := range exps {
+		if err := c.compileExpression(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileIf(node *ast.IfExpression) error {
+	if err := c.compileExpression(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthy := c.emit(node.Pos(), OpJumpNotTruthy, 0)
+	depth := c.scope.depth
+
+	if err := c.compileBlock(node.Consequence.Statements, node.Consequence.Pos()); err != nil {
+		return err
+	}
+
+	jump := c.emit(node.Pos(), OpJump, 0)
+	c.patch(jumpNotTruthy)
+	c.scope.depth = depth
+
+	if node.Alternative != nil {
+		if err := c.compileBlock(node.Alternative.Statements, node.Alternative.Pos()); err != nil {
+			return err
+		}
+	} else {
+		c.emit(node.Pos(), OpNil)
+	}
+
+	c.patch(jump)
+
+	return nil
+}
+
+// compileTry compiles a try expression. A handler installed by OpTry
+// catches the errors of the try block, and of the catch block when there
+// is a finally block, which then runs before the error is raised again.
+// Leaving the blocks by break, continue or return runs the finally block
+// on the way out.
+func (c *Compiler) compileTry(node *ast.TryExpression) error {
+	depth := c.scope.depth
+
+	try := c.emit(node.Pos(), OpTry, 0)
+	c.scope.tries = append(c.scope.tries, &tryBlock{handler: true, finally: node.Finally})
+
+	if err := c.compileBlock(node.Block.Statements, node.Block.Pos()); err != nil {
+		return err
+	}
+
+	c.scope.tries = c.scope.tries[:len(c.scope.tries)-1]
+	c.emit(node.Pos(), OpEndTry)
+	done := []int{c.emit(node.Pos(), OpJump, 0)}
+
+	// the handler starts with the error on the stack
+	c.patch(try)
+	c.scope.depth = depth + 1
+
+	if node.Catch != nil {
+		c.emit(node.Pos(), OpException)
+		if node.CatchParam != nil {
+			c.declare(node.CatchParam, false)
+		} else {
+			c.emit(node.Pos(), OpPop)
+		}
+
+		if node.Finally != nil {
+			try = c.emit(node.Pos(), OpTry, 0)
+			c.scope.tries = append(c.scope.tries, &tryBlock{handler: true, finally: node.Finally})
+		}
+
+		if err := c.compileBlock(node.Catch.Statements, node.Catch.Pos()); err != nil {
+			return err
+		}
+
+		if node.Finally == nil {
+			c.patch(done[0])
+			return nil
+		}
+
+		c.scope.tries = c.scope.tries[:len(c.scope.tries)-1]
+		c.emit(node.Pos(), OpEndTry)
+		done = append(done, c.emit(node.Pos(), OpJump, 0))
+
+		c.patch(try)
+		c.scope.depth = depth + 1
+	}
+
+	// an error escaped: run the finally block and raise it again
+	pending := c.temporary()
+	c.emit(node.Pos(), OpSetLocal, pending)
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+	c.emit(node.Pos(), OpGetLocal, pending)
+	c.emit(node.Pos(), OpRethrow)
+
+	for _, jump := range done {
+		c.patch(jump)
+	}
+	c.scope.depth = depth + 1
+
+	return c.compileFinally(node.Finally)
+}
+
+// compileFinally compiles a finally block for its effects.
+func (c *Compiler) compileFinally(finally *ast.BlockStatement) error {
+	if err := c.compileBlock(finally.Statements, finally.Pos()); err != nil {
+		return err
+	}
+	c.emit(finally.Pos(), OpPop)
+
+	return nil
+}
+
+// leaveTries removes the handlers of the try blocks open above the first n
+// and runs their finally blocks, innermost first.
+func (c *Compiler) leaveTries(n int) error {
+	tries := c.scope.tries
+
+	for i := len(tries) - 1; i >= n; i-- {
+		if tries[i].handler {
+			c.emit(token.Position{}, OpEndTry)
+		}
+
+		if tries[i].finally != nil {
+			// a break in the finally block leaves the outer blocks only
+			c.scope.tries = tries[:i]
+			err := c.compileFinally(tries[i].finally)
+			c.scope.tries = tries
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileWhile(node *ast.WhileStatement) error {
+	l := &loop{depth: c.scope.depth, tries: len(c.scope.tries), start: len(c.scope.instructions)}
+
+	if err := c.compileExpression(node.Condition); err != nil {
+		return err
+	}
+	exit := c.emit(node.Pos(), OpJumpNotTruthy, 0)
+
+	if err := c.compileLoopBody(l, node.Body); err != nil {
+		return err
+	}
+	c.emit(node.Pos(), OpJump, l.start)
+
+	c.patch(exit)
+	for _, jump := range l.breaks {
+		c.patch(jump)
+	}
+
+	return nil
+}
+
+// compileFor compiles a for loop, which keeps an iterator on the stack.
+func (c *Compiler) compileFor(node *ast.ForStatement) error {
+	if err := c.compileExpression(node.Iterable); err != nil {
+		return err
+	}
+
+	names := []*ast.Identifier{node.Value}
+	if node.Key != nil {
+		names = []*ast.Identifier{node.Key, node.Value}
+	}
+
+	for _, name := range names {
+		c.checkRedeclaration(name)
+	}
+
+	c.emit(node.Pos(), OpIter)
+
+	l := &loop{depth: c.scope.depth, tries: len(c.scope.tries), start: len(c.scope.instructions)}
+	exit := c.emit(node.Pos(), OpIterNext, len(names), 0)
+
+	for i := len(names) - 1; i >= 0; i-- {
+		c.store(names[i].Pos(), c.local(names[i].Value))
+	}
+
+	if err := c.compileLoopBody(l, node.Body); err != nil {
+		return err
+	}
+	c.emit(node.Pos(), OpJump, l.start)
+
+	c.patch(exit)
+	for _, jump := range l.breaks {
+		c.patch(jump)
+	}
+	c.scope.depth = l.depth
+	c.emit(node.Pos(), OpPop)
+
+	return nil
+}
+
+func (c *Compiler) compileLoopBody(l *loop, body *ast.BlockStatement) error {
+	c.scope.loops = append(c.scope.loops, l)
+	defer func() { c.scope.loops = c.scope.loops[:len(c.scope.loops)-1] }()
+
+	depth := c.scope.depth
+	err := c.compileStatements(body.Statements)
+	c.scope.depth = depth
+
+	return err
+}
+
+// compileLoopExit compiles a break, or a continue when brk is false.
+func (c *Compiler) compileLoopExit(pos token.Position, brk bool) error {
+	l := c.scope.loops[len(c.scope.loops)-1]
+	depth := c.scope.depth
+
+	if err := c.leaveTries(l.tries); err != nil {
+		return err
+	}
+
+	for c.scope.depth > l.depth {
+		c.emit(pos, OpPop)
+	}
+
+	if brk {
+		l.breaks = append(l.breaks, c.emit(pos, OpJump, 0))
+	} else {
+		c.emit(pos, OpJump, l.start)
+	}
+
+	c.scope.depth = depth
+
+	return nil
+}
+
+func (c *Compiler) compileAssignment(node *ast.AssignExpression) error {
+	operator := -1
+	for i, op := range assignOperators {
+		if op == node.Operator {
+			operator = i
+		}
+	}
+	if operator < 0 {
+		return &Error{Pos: node.Pos(), Message: fmt.Sprintf("unknown operator %s", node.Operator)}
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		sym := c.resolve(c.scope, target.Value)
+
+		if sym.kind == globalSymbol {
+			c.emit(node.Pos(), OpCheckGlobal, sym.index)
+		} else if sym.constant {
+			c.raise(node.Pos(), object.TYPE_ERROR, "cannot assign to constant %s", target.Value)
+			c.emit(node.Pos(), OpNil)
+			return nil
+		}
+
+		if node.Operator != "=" {
+			c.load(node.Pos(), sym)
+		}
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+		if node.Operator != "=" {
+			c.emit(node.Pos(), infixOperators[node.Operator[:1]])
+		}
+
+		c.emit(node.Pos(), OpDup)
+		c.store(node.Pos(), sym)
+	case *ast.IndexExpression:
+		if err := c.compileExpression(target.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(target.Index); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpSetIndex, operator)
+	case *ast.DotExpression:
+		if err := c.compileExpression(target.Left); err != nil {
+			return err
+		}
+		if err := c.compileExpression(node.Value); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpSetMember, c.addConstant(&object.String{Value: target.Right.Value}), operator)
+	default:
+		c.raise(node.Pos(), object.RUNTIME_ERROR, "cannot assign to %s", node.Target.String())
+		c.emit(node.Pos(), OpNil)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileDestructuring(node *ast.DestructuringStatement) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+
+	kind := 0
+	for i, k := range DestructuringKinds {
+		if k == node.Kind {
+			kind = i
+		}
+	}
+
+	names := &object.Array{}
+	for _, name := range node.Names {
+		names.Elements = append(names.Elements, &object.String{Value: name.Value})
+	}
+
+	// the values are pushed last first
+	c.emit(node.Pos(), OpDestructure, kind, c.addConstant(names))
+	c.scope.depth += len(node.Names) - 1
+	c.track()
+
+	for _, name := range node.Names {
+		c.declare(name, false)
+	}
+
+	return nil
+}
+
+// compileCall compiles a call with op, OpCall or OpTailCall.
+func (c *Compiler) compileCall(node *ast.CallExpression, op Opcode) error {
+	if err := c.compileExpression(node.Function); err != nil {
+		return err
+	}
+
+	names, err := c.compileArguments(node.Pos(), node.Arguments)
+	if err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), op, len(node.Arguments), names)
+
+	return nil
+}
+
+// compileArguments compiles the arguments of a call at pos and returns the
+// names operand of the call.
+func (c *Compiler) compileArguments(pos token.Position, args []ast.Expression) (int, error) {
+	names := &object.Array{}
+	seen := map[string]bool{}
+
+	for _, arg := range args {
+		named, ok := arg.(*ast.NamedArgument)
+		if !ok {
+			names.Elements = append(names.Elements, &object.String{})
+			if err := c.compileExpression(arg); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if seen[named.Name.Value] {
+			c.raise(pos, object.ARGUMENT_ERROR, "argument %s passed more than once", named.Name.Value)
+		}
+		seen[named.Name.Value] = true
+
+		names.Elements = append(names.Elements, &object.String{Value: named.Name.Value})
+		if err := c.compileExpression(named.Value); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(seen) == 0 {
+		return NoNames, nil
+	}
+
+	return c.addConstant(names), nil
+}
+
+// compileFunctionDefinition declares a named function, which is also the
+// value of the definition.
+func (c *Compiler) compileFunctionDefinition(node *ast.FunctionDefinition) error {
+	// the function can call itself through its name
+	c.declareAhead(node.Name)
+
+	if err := c.compileFunction(node.Name.Value, node.Parameters, node.ReturnTypes, node.Body, node.Pos(), ""); err != nil {
+		return err
+	}
+
+	c.emit(node.Pos(), OpDup)
+	c.declare(node.Name, false)
+
+	return nil
+}
+
+// compileFunction compiles a function and emits the closure of it. The
+// function is compiled again when a function nested in it turns out to
+// capture locals that were not compiled as cells. A method is compiled
+// with the name of the method, empty for other functions.
+func (c *Compiler) compileFunction(name string, params []*ast.TypedField, returnTypes []*ast.Identifier, body *ast.BlockStatement, pos token.Position, method string) error {
+	// a parameter cannot shadow a constant
+	for _, param := range params {
+		c.checkRedeclaration(param.Name)
+	}
+
+	cells := map[string]bool{}
+	ahead := []string{}
+
+	for {
+		constants, imports := len(c.constants), len(c.imports)
+
+		s := newScope(c.scope, cells)
+		c.scope = s
+		err := c.compileFunctionBody(params, returnTypes, ahead, body, method)
+		c.scope = s.outer
+
+		if err != nil {
+			return err
+		}
+
+		recompile := false
+		for name := range s.captured {
+			if !cells[name] {
+				cells[name] = true
+				recompile = true
+			}
+		}
+
+		for name := range s.forward {
+			if _, ok := s.locals[name]; ok && !slices.Contains(ahead, name) {
+				cells[name] = true
+				ahead = append(ahead, name)
+				recompile = true
+			}
+		}
+		sort.Strings(ahead)
+
+		if !recompile {
+			fn, err := c.function(s, pos)
+			if err != nil {
+				return err
+			}
+			fn.Name = name
+			fn.Parameters = params
+			fn.ReturnTypes = returnTypes
+			fn.Body = body
+
+			c.loadFree(pos, s)
+			c.emit(pos, OpClosure, c.addConstant(fn), len(s.free))
+
+			return nil
+		}
+
+		c.constants, c.imports = c.constants[:constants], c.imports[:imports]
+	}
+}
+
+// compileFunctionBody compiles the parameters and the body of a function.
+// The caller leaves the parameters without arguments unset, and the
+// function sets them to their default values. The locals in ahead get
+// their slots up front, for the nested functions that use them before
+// they are declared.
+func (c *Compiler) compileFunctionBody(params []*ast.TypedField, returnTypes []*ast.Identifier, ahead []string, body *ast.BlockStatement, method string) error {
+	s := c.scope
+
+	// in strict mode, the types of the annotations are looked up where
+	// the function is defined, and the access to private members depends
+	// on the self there
+	for _, param := range params {
+		c.resolveLocal(s, param.Type.Value)
+	}
+	for _, typ := range returnTypes {
+		c.resolveLocal(s, typ.Value)
+	}
+	c.resolveLocal(s, "self")
+
+	for _, param := range params {
+		c.local(param.Name.Value)
+	}
+
+	// the caller of a method sets the slots after the parameters to self,
+	// super and the method, which the parameters may hide
+	if method != "" {
+		for _, name := range []string{"self", "super", method} {
+			if _, ok := s.locals[name]; ok {
+				c.temporary()
+			} else {
+				c.local(name)
+			}
+		}
+	}
+	for _, name := range ahead {
+		if _, ok := s.locals[name]; !ok {
+			s.ahead[name] = &symbol{name: name, kind: localSymbol, index: len(s.names), cell: true}
+			s.names = append(s.names, name)
+		}
+	}
+
+	for i, param := range params {
+		if param.Default == nil {
+			continue
+		}
+
+		jump := c.emit(param.Name.Pos(), OpJumpIfSet, i, 0)
+		if err := c.compileExpression(param.Default); err != nil {
+			return err
+		}
+		c.emit(param.Name.Pos(), OpSetParam, i)
+		c.patch(jump)
+	}
+
+	if err := c.compileBlock(body.Statements, body.Pos()); err != nil {
+		return err
+	}
+	c.emit(body.End(), OpReturn)
+
+	return nil
+}
+
+// declare binds name to the value on top of the stack, as a constant with
+// a frozen value if constant is set.
+func (c *Compiler) declare(name *ast.Identifier, constant bool) {
+	if c.scope.outer == nil {
+		flag := 0
+		if constant {
+			flag = 1
+		}
+		c.emit(name.Pos(), OpDefineGlobal, c.global(name.Value).index, flag)
+		return
+	}
+
+	sym, ok := c.scope.locals[name.Value]
+	if !ok {
+		c.checkRedeclaration(name)
+		sym = c.local(name.Value)
+	} else if sym.constant {
+		c.raise(name.Pos(), object.NAME_ERROR, "cannot redeclare constant %s", name.Value)
+	}
+
+	if constant {
+		c.emit(name.Pos(), OpFreeze)
+		sym.constant = true
+	}
+
+	c.store(name.Pos(), sym)
+}
+
+// declareAhead allocates the local name before its declaration binds it,
+// for the code that refers to what is being declared, and reports there
+// that it is bound to a constant. Globals are checked by the declaration.
+func (c *Compiler) declareAhead(name *ast.Identifier) {
+	if c.scope.outer == nil {
+		return
+	}
+
+	if _, ok := c.scope.locals[name.Value]; !ok {
+		c.checkRedeclaration(name)
+		c.local(name.Value)
+	}
+}
+
+// checkRedeclaration reports the declaration of a name that is bound to a
+// constant, which is checked when the program runs for globals.
+func (c *Compiler) checkRedeclaration(name *ast.Identifier) {
+	if c.scope.outer == nil {
+		c.emit(name.Pos(), OpCheckConst, c.global(name.Value).index)
+		return
+	}
+
+	if sym, ok := c.scope.locals[name.Value]; ok {
+		if sym.constant {
+			c.raise(name.Pos(), object.NAME_ERROR, "cannot redeclare constant %s", name.Value)
+		}
+		return
+	}
+
+	for s := c.scope.outer; s != nil && s.outer != nil; s = s.outer {
+		if sym, ok := s.locals[name.Value]; ok {
+			if sym.constant {
+				c.raise(name.Pos(), object.NAME_ERROR, "cannot redeclare constant %s", name.Value)
+			}
+			return
+		}
+	}
+
+	if index, ok := c.globals[name.Value]; ok {
+		c.emit(name.Pos(), OpCheckConst, index)
+	}
+}
+
+// resolve returns the symbol name refers to in s.
+func (c *Compiler) resolve(s *scope, name string) *symbol {
+	if s.outer != nil {
+		if sym := c.resolveLocal(s, name); sym != nil {
+			return sym
+		}
+
+		// an enclosing function may still declare it
+		for o := s.outer; o.outer != nil; o = o.outer {
+			o.forward[name] = true
+		}
+	}
+
+	return c.global(name)
+}
+
+// resolveLocal returns the local or free variable name refers to in s, or
+// nil if name is a global.
+func (c *Compiler) resolveLocal(s *scope, name string) *symbol {
+	if s.outer == nil {
+		return nil
+	}
+
+	if sym, ok := s.locals[name]; ok {
+		return sym
+	}
+
+	if sym, ok := s.freeSymbols[name]; ok {
+		return sym
+	}
+
+	if sym, ok := s.ahead[name]; ok && s != c.scope {
+		return sym
+	}
+
+	outer := c.resolveLocal(s.outer, name)
+	if outer == nil {
+		return nil
+	}
+
+	if outer.kind == localSymbol {
+		s.outer.captured[name] = true
+	}
+
+	sym := &symbol{name: name, kind: freeSymbol, index: len(s.free), constant: outer.constant}
+	s.free = append(s.free, outer)
+	s.freeSymbols[name] = sym
+
+	return sym
+}
+
+// global returns the symbol of the global name, allocating its slot.
+func (c *Compiler) global(name string) *symbol {
+	index, ok := c.globals[name]
+	if !ok {
+		index = len(c.globalNames)
+		c.globals[name] = index
+		c.globalNames = append(c.globalNames, name)
+	}
+
+	return &symbol{name: name, kind: globalSymbol, index: index}
+}
+
+// local returns the local symbol name of the current scope, or the global
+// one at the top level, declaring it if needed.
+func (c *Compiler) local(name string) *symbol {
+	s := c.scope
+	if s.outer == nil {
+		return c.global(name)
+	}
+
+	if sym, ok := s.locals[name]; ok {
+		return sym
+	}
+
+	if sym, ok := s.ahead[name]; ok {
+		delete(s.ahead, name)
+		s.locals[name] = sym
+		return sym
+	}
+
+	sym := &symbol{name: name, kind: localSymbol, index: len(s.names), cell: s.cells[name]}
+	s.locals[name] = sym
+	s.names = append(s.names, name)
+
+	return sym
+}
+
+// temporary allocates a local slot no name refers to.
+func (c *Compiler) temporary() int {
+	s := c.scope
+	s.names = append(s.names, "")
+
+	return len(s.names) - 1
+}
+
+// load pushes the value of sym.
+func (c *Compiler) load(pos token.Position, sym *symbol) {
+	switch {
+	case sym.kind == globalSymbol:
+		c.emit(pos, OpGetGlobal, sym.index)
+	case sym.kind == freeSymbol:
+		c.emit(pos, OpGetFree, sym.index)
+	case sym.cell:
+		c.emit(pos, OpGetCell, sym.index)
+	default:
+		c.emit(pos, OpGetLocal, sym.index)
+	}
+}
+
+// store pops the value on top of the stack into sym.
+func (c *Compiler) store(pos token.Position, sym *symbol) {
+	switch {
+	case sym.kind == globalSymbol:
+		c.emit(pos, OpSetGlobal, sym.index)
+	case sym.kind == freeSymbol:
+		c.emit(pos, OpSetFree, sym.index)
+	case sym.cell:
+		c.emit(pos, OpSetCell, sym.index)
+	default:
+		c.emit(pos, OpSetLocal, sym.index)
+	}
+}
+
+// raise emits an instruction raising an error found while compiling, when
+// the program reaches it.
+func (c *Compiler) raise(pos token.Position, kind string, format string, a ...any) {
+	err := &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+	c.emit(pos, OpRaise, c.addConstant(err))
+}
+
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+func (c *Compiler) emitConstant(pos token.Position, obj object.Object) {
+	c.emit(pos, OpConstant, c.addConstant(obj))
+}
+
+// emit appends an instruction compiled from the code at pos and returns
+// its offset.
+func (c *Compiler) emit(pos token.Position, op Opcode, operands ...int) int {
+	s := c.scope
+	offset := len(s.instructions)
+
+	if pos.IsValid() && (len(s.lines) == 0 || s.lines[len(s.lines)-1].Pos != pos) {
+		s.lines = append(s.lines, Line{Offset: offset, Pos: pos})
+	}
+
+	s.instructions = append(s.instructions, Make(op, operands...)...)
+	s.depth += stackEffect(op, operands)
+	c.track()
+
+	return offset
+}
+
+// track records the stack depth of the current scope.
+func (c *Compiler) track() {
+	if c.scope.depth > c.scope.maxDepth {
+		c.scope.maxDepth = c.scope.depth
+	}
+}
+
+// patch makes the jump at offset jump to the next instruction.
+func (c *Compiler) patch(offset int) {
+	ins := c.scope.instructions
+	def, _ := Lookup(ins[offset])
+
+	// the target is the last operand
+	at := offset + 1
+	for _, w := range def.OperandWidths {
+		at += w
+	}
+
+	binary.BigEndian.PutUint16(ins[at-2:], uint16(len(ins)))
+}
+
+// stackEffect is the change in the number of values on the stack made by
+// an instruction that does not jump. OpClass pops as many values as its
+// class needs, which the compiler counts itself.
+func stackEffect(op Opcode, operands []int) int {
+	switch op {
+	case OpConstant, OpDup, OpTrue, OpFalse, OpNil, OpGetGlobal, OpGetLocal, OpGetCell,
+		OpLoadCell, OpGetFree, OpLoadFree, OpImport:
+		return 1
+	case OpPop, OpAdd, OpSub, OpMul, OpDiv, OpEqual, OpNotEqual, OpLess, OpGreater, OpRange,
+		OpJumpNotTruthy, OpSetGlobal, OpDefineGlobal, OpSetLocal, OpSetCell, OpSetFree,
+		OpIndex, OpSetMember, OpReturn, OpSetParam, OpThrow, OpRethrow:
+		return -1
+	case OpSetIndex:
+		return -2
+	case OpArray, OpTuple:
+		return 1 - operands[0]
+	case OpHash:
+		return 1 - 2*operands[0]
+	case OpClosure:
+		return 1 - operands[1]
+	case OpCall, OpTailCall:
+		return -operands[0]
+	case OpIterNext:
+		return operands[0]
+	case OpImplement, OpSame, OpNoMatch:
+		return -1
+	case OpInterface, OpMatchKey:
+		return 1
+	case OpEnum:
+		return 1 - operands[1]
+	case OpNew:
+		return -operands[0]
+	case OpMatchArray:
+		return -1 + operands[0] + operands[1]
+	case OpMatchVariant:
+		if operands[1] == NoArguments {
+			return -2
+		}
+		return -2 + operands[1]
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,301 @@
+package compiler
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/parser"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		if string(instruction) != string(tt.expected) {
+			t.Errorf("wrong instruction for %d. want=%v, got=%v", tt.op, tt.expected, instruction)
+			continue
+		}
+
+		def, err := Lookup(byte(tt.op))
+		if err != nil {
+			t.Fatalf("definition not found: %q", err)
+		}
+
+		operands, n := ReadOperands(def, instruction[1:])
+		if n != len(instruction)-1 {
+			t.Errorf("wrong operand length. want=%d, got=%d", len(instruction)-1, n)
+		}
+
+		for i, want := range tt.operands {
+			if operands[i] != want {
+				t.Errorf("operand %d wrong. want=%d, got=%d", i, want, operands[i])
+			}
+		}
+	}
+}
+
+func compile(t *testing.T, input string) *Bytecode {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors in %q: %v", input, p.Errors())
+	}
+
+	bytecode, err := New().Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error in %q: %s", input, err)
+	}
+
+	return bytecode
+}
+
+func TestGlobals(t *testing.T) {
+	bytecode := compile(t, "var x = 1\nx + 2")
+
+	expected := `0000 OpConstant 0
+0003 OpDefineGlobal 0 0
+0007 OpGetGlobal 0
+0010 OpConstant 1
+0013 OpAdd
+0014 OpReturn
+`
+	if got := bytecode.Main.Instructions.String(); got != expected {
+		t.Errorf("wrong instructions.\nwant=%q\ngot=%q", expected, got)
+	}
+
+	if len(bytecode.Globals) != 1 || bytecode.Globals[0] != "x" {
+		t.Errorf("wrong globals. got=%v", bytecode.Globals)
+	}
+}
+
+func TestCapturedLocals(t *testing.T) {
+	bytecode := compile(t, "func f(a: Int) { var b = a\nreturn func() { return b } }")
+
+	inner, ok := bytecode.Constants[0].(*Function)
+	if !ok {
+		t.Fatalf("constant 0 is not a Function. got=%T", bytecode.Constants[0])
+	}
+
+	if len(inner.Free) != 1 || inner.Free[0] != "b" {
+		t.Errorf("wrong free variables. got=%v", inner.Free)
+	}
+
+	outer, ok := bytecode.Constants[1].(*Function)
+	if !ok {
+		t.Fatalf("constant 1 is not a Function. got=%T", bytecode.Constants[1])
+	}
+
+	expected := `0000 OpGetLocal 0
+0002 OpSetCell 1
+0004 OpLoadCell 1
+0006 OpClosure 0 1
+0010 OpReturn
+0011 OpNil
+0012 OpReturn
+`
+	if got := outer.Instructions.String(); got != expected {
+		t.Errorf("wrong instructions.\nwant=%q\ngot=%q", expected, got)
+	}
+
+	if len(outer.Cells) != 1 || outer.Cells[0] != 1 {
+		t.Errorf("wrong cells. got=%v", outer.Cells)
+	}
+}
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match 1 {\n  [" + strings.Repeat("_, ", 256) + "] -> 2\n}", "2:3: too many elements in pattern"},
+		{"match 1 {\n  E.A(" + strings.Repeat("_, ", 255) + ") -> 2\n}", "2:3: too many arguments in pattern"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors in %q: %v", tt.input, p.Errors())
+		}
+
+		_, err := New().Compile(program)
+		if err == nil {
+			t.Errorf("no compiler error for %q", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong compiler error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	bytecode := compile(t, `import strings as s
+func greet(name: String, greeting: String = "hi", ...rest: Array) -> String {
+  const SEP = ", "
+  return func() { return greeting + SEP + name }()
+}
+try { greet("ann") } catch e { e.message }`)
+
+	data, err := Encode(bytecode)
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	decoded, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	if decoded.Main.Instructions.String() != bytecode.Main.Instructions.String() {
+		t.Errorf("wrong main instructions. want=%q, got=%q", bytecode.Main.Instructions, decoded.Main.Instructions)
+	}
+
+	if len(decoded.Constants) != len(bytecode.Constants) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(bytecode.Constants), len(decoded.Constants))
+	}
+
+	for i, want := range bytecode.Constants {
+		if got := decoded.Constants[i]; got.Inspect() != want.Inspect() {
+			t.Errorf("constant %d wrong. want=%q, got=%q", i, want.Inspect(), got.Inspect())
+		}
+	}
+
+	imp := decoded.Imports[0].Node
+	if decoded.Imports[0].Module != -1 || imp.Path() != "strings" || imp.Namespace() != "s" || imp.Pos().String() != "1:1" {
+		t.Errorf("wrong import. got=%s as %s at %s", imp.Path(), imp.Namespace(), imp.Pos())
+	}
+
+	if pos := decoded.Main.Pos(len(decoded.Main.Instructions) - 1); pos != bytecode.Main.Pos(len(bytecode.Main.Instructions)-1) {
+		t.Errorf("wrong line table. got=%s", pos)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data, err := Encode(compile(t, "1 + 2"))
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	version := append([]byte{}, data...)
+	version[len(Magic)+1] = FormatVersion + 1
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(data)/2] ^= 0xff
+
+	tests := []struct {
+		data     []byte
+		expected string
+	}{
+		{[]byte("1 + 2"), "not an emo bytecode file"},
+		{data[:len(Magic)+3], "bytecode file is truncated"},
+		{version, "bytecode file has format version 2, this emo runs version 1; rebuild it with emo build"},
+		{corrupt, "bytecode file is corrupt: checksum mismatch"},
+	}
+
+	for _, tt := range tests {
+		_, err := Decode(tt.data)
+		if err == nil {
+			t.Errorf("no error decoding %q", tt.data)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestDecodeBadInstructions(t *testing.T) {
+	tests := []struct {
+		instructions []byte
+		expected     string
+	}{
+		{
+			slices.Concat(Make(OpConstant, 0), []byte{200}, Make(OpReturn)),
+			"bytecode file is malformed: <main> at 3: opcode 200 undefined",
+		},
+		{
+			slices.Concat(Make(OpConstant, 7), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: OpConstant has bad constant 7",
+		},
+		{
+			slices.Concat(Make(OpGetGlobal, 3), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: OpGetGlobal has bad global 3",
+		},
+		{
+			slices.Concat(Make(OpGetMember, 0), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: OpGetMember has constant 0 that is not a string",
+		},
+		{
+			slices.Concat(Make(OpGetLocal, 0), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: OpGetLocal has bad local 0",
+		},
+		{
+			slices.Concat(Make(OpJump, 1), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: bad jump target 1",
+		},
+		{
+			slices.Concat(Make(OpConstant, 0), Make(OpPop)),
+			"bytecode file is malformed: <main> does not end with OpReturn",
+		},
+		{
+			Make(OpConstant, 0)[:2],
+			"bytecode file is malformed: <main> at 0: OpConstant is truncated",
+		},
+		{
+			slices.Concat(Make(OpPop), Make(OpConstant, 0), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: OpPop takes 1 values from a stack of 0",
+		},
+		{
+			slices.Concat(Make(OpConstant, 0), Make(OpCall, 200, NoNames), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 3: OpCall takes 201 values from a stack of 1",
+		},
+		{
+			slices.Concat(Make(OpConstant, 0), Make(OpConstant, 0), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 3: OpConstant pushes more than 1 values",
+		},
+		{
+			slices.Concat(Make(OpEndTry), Make(OpConstant, 0), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 0: OpEndTry without a handler",
+		},
+		{
+			slices.Concat(Make(OpTrue), Make(OpJumpNotTruthy, 8), Make(OpConstant, 0), Make(OpReturn), Make(OpNil), Make(OpNil), Make(OpReturn)),
+			"bytecode file is malformed: <main> at 9: OpNil pushes more than 1 values",
+		},
+	}
+
+	for _, tt := range tests {
+		bytecode := compile(t, "1")
+		bytecode.Main.Instructions = tt.instructions
+
+		data, err := Encode(bytecode)
+		if err != nil {
+			t.Fatalf("Encode failed: %s", err)
+		}
+
+		_, err = Decode(data)
+		if err == nil {
+			t.Errorf("no error decoding %s", Instructions(tt.instructions))
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
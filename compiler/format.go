@@ -0,0 +1,995 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// Magic starts every bytecode file.
+const Magic = "EMOC"
+
+// FormatVersion is the version of the bytecode file format. It changes with
+// the format and with the instruction set, as files built for other
+// instructions cannot run.
+const FormatVersion = 1
+
+// A bytecode file is the magic, the version as a big-endian uint16, the
+// strings of the program, the program itself with the modules it imports
+// and a big-endian CRC-32 of everything before it. Numbers are varints,
+// strings are indexes into the table of strings, and source positions are
+// kept for the errors and the stack traces of the program.
+//
+// The files keep the parameter defaults, the field initializers and the
+// bodies of functions as source text, which is all the machine needs of
+// them: the defaults and the initializers are compiled into functions and
+// the bodies are only rendered.
+
+const (
+	tagInteger byte = iota
+	tagString
+	tagError
+	tagArray
+	tagFunction
+	tagClass
+	tagInterface
+	tagEnum
+)
+
+// IsBytecode reports whether data starts like a bytecode file.
+func IsBytecode(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Encode serializes bytecode for Decode.
+func Encode(bytecode *Bytecode) ([]byte, error) {
+	e := &encoder{strings: map[string]int{}}
+
+	e.strs(bytecode.Globals)
+
+	e.uint(len(bytecode.Imports))
+	for _, imp := range bytecode.Imports {
+		node := imp.Node
+		e.pos(node.Pos())
+		_, isName := node.Name.(*ast.Identifier)
+		e.bool(isName)
+		e.str(node.Path())
+		e.bool(node.Alias != nil)
+		if node.Alias != nil {
+			e.str(node.Alias.Value)
+		}
+
+		e.int(int64(imp.Module))
+		e.bool(imp.Err != nil)
+		if imp.Err != nil {
+			e.str(imp.Err.Kind)
+			e.str(imp.Err.Message)
+		}
+	}
+
+	e.uint(len(bytecode.Constants))
+	for _, obj := range bytecode.Constants {
+		if err := e.constant(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	e.function(bytecode.Main)
+
+	e.uint(len(bytecode.Modules))
+	for _, module := range bytecode.Modules {
+		e.str(module.Name)
+		e.str(module.Path)
+		e.function(module.Main)
+		e.uint(len(module.Globals))
+		for _, slot := range module.Globals {
+			e.uint(slot)
+		}
+	}
+
+	out := bytes.NewBufferString(Magic)
+	binary.Write(out, binary.BigEndian, uint16(FormatVersion))
+
+	table := &encoder{}
+	table.uint(len(e.table))
+	for _, s := range e.table {
+		table.uint(len(s))
+		table.buf.WriteString(s)
+	}
+	out.Write(table.buf.Bytes())
+	out.Write(e.buf.Bytes())
+
+	binary.Write(out, binary.BigEndian, crc32.ChecksumIEEE(out.Bytes()))
+
+	return out.Bytes(), nil
+}
+
+type encoder struct {
+	buf     bytes.Buffer
+	strings map[string]int
+	table   []string
+}
+
+func (e *encoder) uint(n int) {
+	e.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (e *encoder) int(n int64) {
+	e.buf.Write(binary.AppendVarint(nil, n))
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *encoder) str(s string) {
+	i, ok := e.strings[s]
+	if !ok {
+		i = len(e.table)
+		e.strings[s] = i
+		e.table = append(e.table, s)
+	}
+	e.uint(i)
+}
+
+func (e *encoder) strs(ss []string) {
+	e.uint(len(ss))
+	for _, s := range ss {
+		e.str(s)
+	}
+}
+
+func (e *encoder) pos(pos token.Position) {
+	e.str(pos.Filename)
+	e.uint(pos.Offset)
+	e.uint(pos.Line)
+	e.uint(pos.Column)
+}
+
+func (e *encoder) ident(id *ast.Identifier) {
+	e.str(id.Value)
+	e.pos(id.Pos())
+}
+
+func (e *encoder) params(params []*ast.TypedField) {
+	e.uint(len(params))
+	for _, param := range params {
+		e.str(param.Name.Value)
+		e.str(param.Type.Value)
+		e.bool(param.Variadic)
+		e.bool(param.Default != nil)
+		if param.Default != nil {
+			e.str(param.Default.String())
+		}
+	}
+}
+
+func (e *encoder) types(types []*ast.Identifier) {
+	e.uint(len(types))
+	for _, typ := range types {
+		e.str(typ.Value)
+	}
+}
+
+func (e *encoder) constant(obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		e.buf.WriteByte(tagInteger)
+		e.int(obj.Value)
+	case *object.String:
+		e.buf.WriteByte(tagString)
+		e.str(obj.Value)
+	case *object.Error:
+		e.buf.WriteByte(tagError)
+		e.str(obj.Kind)
+		e.str(obj.Message)
+	case *object.Array:
+		e.buf.WriteByte(tagArray)
+		e.uint(len(obj.Elements))
+		for _, el := range obj.Elements {
+			if err := e.constant(el); err != nil {
+				return err
+			}
+		}
+	case *Function:
+		e.buf.WriteByte(tagFunction)
+		e.function(obj)
+	case *Class:
+		e.buf.WriteByte(tagClass)
+		e.class(obj)
+	case *Interface:
+		e.buf.WriteByte(tagInterface)
+		e.ident(obj.Node.Name)
+		e.uint(len(obj.Node.Methods))
+		for _, name := range slices.Sorted(maps.Keys(obj.Node.Methods)) {
+			method := obj.Node.Methods[name]
+			e.ident(method.Name)
+			e.params(method.Parameters)
+			e.types(method.ReturnTypes)
+		}
+	case *Enum:
+		e.buf.WriteByte(tagEnum)
+		e.ident(obj.Node.Name)
+		e.uint(len(obj.Node.Variants))
+		for _, variant := range obj.Node.Variants {
+			e.ident(variant.Name)
+			e.params(variant.Fields)
+		}
+		e.strs(obj.Free)
+	default:
+		return fmt.Errorf("cannot encode constant of type %s", obj.Type())
+	}
+
+	return nil
+}
+
+// class writes the declarations of a class, which the machine creates
+// with the closures of its methods and of its field initializers.
+func (e *encoder) class(class *Class) {
+	node := class.Node
+	e.ident(node.Name)
+
+	e.bool(node.Super != nil)
+	if node.Super != nil {
+		e.ident(node.Super)
+	}
+
+	e.uint(len(node.Implements))
+	for _, name := range node.Implements {
+		e.ident(name)
+	}
+
+	e.uint(len(node.Fields))
+	for _, name := range slices.Sorted(maps.Keys(node.Fields)) {
+		field := node.Fields[name]
+		e.bool(field.Public)
+		e.ident(field.Field.Name)
+		e.str(field.Field.Type.Value)
+		e.bool(field.Value != nil)
+		if field.Value != nil {
+			e.str(field.Value.String())
+		}
+	}
+
+	e.uint(len(node.Methods))
+	for _, name := range slices.Sorted(maps.Keys(node.Methods)) {
+		method := node.Methods[name]
+		e.bool(method.Public)
+		e.ident(method.Function.Name)
+		e.params(method.Function.Parameters)
+		e.types(method.Function.ReturnTypes)
+	}
+}
+
+func (e *encoder) function(fn *Function) {
+	e.str(fn.Name)
+	e.params(fn.Parameters)
+	e.types(fn.ReturnTypes)
+
+	e.bool(fn.Body != nil)
+	if fn.Body != nil {
+		e.str(fn.Body.String())
+	}
+
+	e.uint(len(fn.Instructions))
+	e.buf.Write(fn.Instructions)
+	e.strs(fn.Locals)
+	e.strs(fn.Free)
+	e.uint(len(fn.Cells))
+	for _, slot := range fn.Cells {
+		e.uint(slot)
+	}
+	e.uint(fn.MaxStack)
+
+	e.uint(len(fn.Lines))
+	for _, line := range fn.Lines {
+		e.uint(line.Offset)
+		e.pos(line.Pos)
+	}
+}
+
+// Decode reads a bytecode file written by Encode.
+func Decode(data []byte) (*Bytecode, error) {
+	if !IsBytecode(data) {
+		return nil, errors.New("not an emo bytecode file")
+	}
+
+	header := len(Magic) + 2
+	if len(data) < header+4 {
+		return nil, errors.New("bytecode file is truncated")
+	}
+
+	if version := binary.BigEndian.Uint16(data[len(Magic):]); version != FormatVersion {
+		return nil, fmt.Errorf("bytecode file has format version %d, this emo runs version %d; rebuild it with emo build", version, FormatVersion)
+	}
+
+	end := len(data) - 4
+	if crc32.ChecksumIEEE(data[:end]) != binary.BigEndian.Uint32(data[end:]) {
+		return nil, errors.New("bytecode file is corrupt: checksum mismatch")
+	}
+
+	d := &decoder{data: data[header:end]}
+
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		d.table = append(d.table, string(d.bytes(d.uint())))
+	}
+
+	bytecode := &Bytecode{Globals: d.strs()}
+
+	n = d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		node := &ast.ImportStatement{Token: token.Token{Type: token.IMPORT, Literal: "import", Pos: d.pos()}}
+		isName := d.bool()
+		path := d.str()
+		if isName {
+			node.Name = &ast.Identifier{Value: path}
+		} else {
+			node.Name = &ast.StringLiteral{Value: path}
+		}
+		if d.bool() {
+			node.Alias = &ast.Identifier{Value: d.str()}
+		}
+
+		imp := &Import{Node: node, Module: int(d.int())}
+		if d.bool() {
+			imp.Err = &object.Error{Kind: d.str(), Message: d.str()}
+		}
+		bytecode.Imports = append(bytecode.Imports, imp)
+	}
+
+	n = d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		bytecode.Constants = append(bytecode.Constants, d.constant())
+	}
+
+	bytecode.Main = d.function()
+
+	n = d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		module := &Module{Name: d.str(), Path: d.str(), Main: d.function()}
+		m := d.uint()
+		for j := 0; j < m && d.err == nil; j++ {
+			module.Globals = append(module.Globals, d.uint())
+		}
+		bytecode.Modules = append(bytecode.Modules, module)
+	}
+
+	if d.err == nil && d.offset != len(d.data) {
+		d.err = errors.New("bytecode file has trailing data")
+	}
+	if d.err == nil {
+		d.validate(bytecode)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return bytecode, nil
+}
+
+// decoder reads the parts of a bytecode file. The first error stops the
+// reading, and the reads after it return zero values.
+type decoder struct {
+	data   []byte
+	offset int
+	table  []string
+	err    error
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("bytecode file is malformed: "+format, a...)
+	}
+}
+
+func (d *decoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+
+	n, size := binary.Uvarint(d.data[d.offset:])
+	if size <= 0 || n > math.MaxInt32 {
+		d.fail("bad number at offset %d", d.offset)
+		return 0
+	}
+	d.offset += size
+
+	return int(n)
+}
+
+func (d *decoder) int() int64 {
+	if d.err != nil {
+		return 0
+	}
+
+	n, size := binary.Varint(d.data[d.offset:])
+	if size <= 0 {
+		d.fail("bad number at offset %d", d.offset)
+		return 0
+	}
+	d.offset += size
+
+	return n
+}
+
+func (d *decoder) byte() byte {
+	b := d.bytes(1)
+	if len(b) == 0 {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() == 1
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if n > len(d.data)-d.offset {
+		d.fail("unexpected end of data")
+		return nil
+	}
+	b := d.data[d.offset : d.offset+n]
+	d.offset += n
+
+	return b
+}
+
+func (d *decoder) str() string {
+	i := d.uint()
+	if i >= len(d.table) {
+		d.fail("bad string %d", i)
+		return ""
+	}
+	return d.table[i]
+}
+
+func (d *decoder) strs() []string {
+	var ss []string
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		ss = append(ss, d.str())
+	}
+	return ss
+}
+
+func (d *decoder) pos() token.Position {
+	return token.Position{Filename: d.str(), Offset: d.uint(), Line: d.uint(), Column: d.uint()}
+}
+
+func (d *decoder) ident() *ast.Identifier {
+	value := d.str()
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: value, Pos: d.pos()}, Value: value}
+}
+
+func (d *decoder) params() []*ast.TypedField {
+	var params []*ast.TypedField
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		param := &ast.TypedField{
+			Name:     &ast.Identifier{Value: d.str()},
+			Type:     &ast.Identifier{Value: d.str()},
+			Variadic: d.bool(),
+		}
+		if d.bool() {
+			param.Default = &ast.Identifier{Value: d.str()}
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func (d *decoder) types() []*ast.Identifier {
+	var types []*ast.Identifier
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		types = append(types, &ast.Identifier{Value: d.str()})
+	}
+	return types
+}
+
+func (d *decoder) constant() object.Object {
+	switch tag := d.byte(); tag {
+	case tagInteger:
+		return &object.Integer{Value: d.int()}
+	case tagString:
+		return &object.String{Value: d.str()}
+	case tagError:
+		return &object.Error{Kind: d.str(), Message: d.str()}
+	case tagArray:
+		arr := &object.Array{}
+		n := d.uint()
+		for i := 0; i < n && d.err == nil; i++ {
+			arr.Elements = append(arr.Elements, d.constant())
+		}
+		return arr
+	case tagFunction:
+		return d.function()
+	case tagClass:
+		return d.class()
+	case tagInterface:
+		node := &ast.InterfaceExpression{Name: d.ident(), Methods: map[string]*ast.InterfaceMethod{}}
+		n := d.uint()
+		for i := 0; i < n && d.err == nil; i++ {
+			method := &ast.InterfaceMethod{Name: d.ident(), Parameters: d.params(), ReturnTypes: d.types()}
+			node.Methods[method.Name.Value] = method
+		}
+		return &Interface{Node: node}
+	case tagEnum:
+		node := &ast.EnumExpression{Name: d.ident()}
+		n := d.uint()
+		for i := 0; i < n && d.err == nil; i++ {
+			node.Variants = append(node.Variants, &ast.EnumVariant{Name: d.ident(), Fields: d.params()})
+		}
+		return &Enum{Node: node, Free: d.strs()}
+	default:
+		d.fail("bad constant tag %d", tag)
+		return nil
+	}
+}
+
+func (d *decoder) class() *Class {
+	node := &ast.ClassExpression{
+		Name:    d.ident(),
+		Fields:  map[string]*ast.ClassField{},
+		Methods: map[string]*ast.ClassMethod{},
+	}
+
+	if d.bool() {
+		node.Super = d.ident()
+	}
+
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		node.Implements = append(node.Implements, d.ident())
+	}
+
+	n = d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		field := &ast.ClassField{Public: d.bool()}
+		field.Field = &ast.TypedField{Name: d.ident(), Type: &ast.Identifier{Value: d.str()}}
+		if d.bool() {
+			field.Value = &ast.Identifier{Value: d.str()}
+		}
+		node.Fields[field.Field.Name.Value] = field
+	}
+
+	n = d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		method := &ast.ClassMethod{Public: d.bool()}
+		method.Function = &ast.FunctionDefinition{
+			Token:       token.Token{Type: token.FUNCTION, Literal: "func"},
+			Name:        d.ident(),
+			Parameters:  d.params(),
+			ReturnTypes: d.types(),
+			Body:        &ast.BlockStatement{},
+		}
+		node.Methods[method.Function.Name.Value] = method
+	}
+
+	return &Class{Node: node}
+}
+
+func (d *decoder) function() *Function {
+	fn := &Function{Name: d.str(), Parameters: d.params(), ReturnTypes: d.types()}
+
+	if d.bool() {
+		fn.Body = &ast.BlockStatement{Statements: []ast.Statement{
+			&ast.ExpressionStatement{Expression: &ast.Identifier{Value: d.str()}},
+		}}
+	}
+
+	fn.Instructions = Instructions(bytes.Clone(d.bytes(d.uint())))
+	fn.Locals = d.strs()
+	fn.Free = d.strs()
+	n := d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		fn.Cells = append(fn.Cells, d.uint())
+	}
+	fn.MaxStack = d.uint()
+
+	n = d.uint()
+	for i := 0; i < n && d.err == nil; i++ {
+		fn.Lines = append(fn.Lines, Line{Offset: d.uint(), Pos: d.pos()})
+	}
+
+	return fn
+}
+
+// validate checks that the instructions of every function of bytecode
+// decode, that their operands are in range and name the constants, slots
+// and jump targets the machine expects, and that they keep the stack in
+// bounds, so that a file with a valid checksum but a bad program is
+// rejected here. The kinds of the values on the stack are left to the
+// machine, which ends a program that gets them wrong with an error.
+func (d *decoder) validate(bytecode *Bytecode) {
+	for _, imp := range bytecode.Imports {
+		if imp.Module < -1 || imp.Module >= len(bytecode.Modules) {
+			d.fail("import of %s has bad module %d", imp.Node.Path(), imp.Module)
+		}
+	}
+
+	fns := []*Function{bytecode.Main}
+	for _, module := range bytecode.Modules {
+		for _, slot := range module.Globals {
+			if slot >= len(bytecode.Globals) {
+				d.fail("module %s has bad global %d", module.Name, slot)
+			}
+		}
+		fns = append(fns, module.Main)
+	}
+	for _, obj := range bytecode.Constants {
+		if fn, ok := obj.(*Function); ok {
+			fns = append(fns, fn)
+		}
+	}
+
+	for _, fn := range fns {
+		d.instructions(bytecode, fn)
+	}
+}
+
+// instructions checks the instructions of fn, a function of bytecode.
+func (d *decoder) instructions(bytecode *Bytecode, fn *Function) {
+	name := fn.DisplayName()
+
+	for _, slot := range fn.Cells {
+		if slot >= len(fn.Locals) {
+			d.fail("%s has bad cell %d", name, slot)
+		}
+	}
+	if len(fn.Parameters) > len(fn.Locals) {
+		d.fail("%s has more parameters than locals", name)
+	}
+
+	// the offsets of the instructions, for the jump targets
+	starts := map[int]bool{}
+	type jump struct{ at, target int }
+	var jumps []jump
+
+	ins := fn.Instructions
+	last := -1
+	for ip := 0; ip < len(ins) && d.err == nil; {
+		def, err := Lookup(ins[ip])
+		if err != nil {
+			d.fail("%s at %d: %s", name, ip, err)
+			return
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if ip+1+width > len(ins) {
+			d.fail("%s at %d: %s is truncated", name, ip, def.Name)
+			return
+		}
+
+		operands, _ := ReadOperands(def, ins[ip+1:])
+		bad := func(format string, a ...any) {
+			d.fail("%s at %d: %s has "+format, append([]any{name, ip, def.Name}, a...)...)
+		}
+		constant := func(i int) object.Object {
+			if i >= len(bytecode.Constants) {
+				bad("bad constant %d", i)
+				return nil
+			}
+			return bytecode.Constants[i]
+		}
+		str := func(i int) {
+			if _, ok := constant(i).(*object.String); !ok && d.err == nil {
+				bad("constant %d that is not a string", i)
+			}
+		}
+		strs := func(i, n int) {
+			arr, ok := constant(i).(*object.Array)
+			if d.err != nil {
+				return
+			}
+			if !ok || (n >= 0 && len(arr.Elements) != n) {
+				bad("constant %d that is not the names it needs", i)
+				return
+			}
+			for _, el := range arr.Elements {
+				if _, ok := el.(*object.String); !ok {
+					bad("constant %d that is not the names it needs", i)
+					return
+				}
+			}
+		}
+		names := func(i, argc int) {
+			if i != NoNames {
+				strs(i, argc)
+			}
+		}
+		local := func(slot int, captured bool) {
+			if slot >= len(fn.Locals) {
+				bad("bad local %d", slot)
+			} else if slices.Contains(fn.Cells, slot) != captured {
+				bad("local %d of the wrong kind", slot)
+			}
+		}
+		global := func(i int) {
+			if i >= len(bytecode.Globals) {
+				bad("bad global %d", i)
+			}
+		}
+		free := func(i int) {
+			if i >= len(fn.Free) {
+				bad("bad free variable %d", i)
+			}
+		}
+		assign := func(op int) {
+			if op >= len(assignOperators) {
+				bad("bad assignment operator %d", op)
+			}
+		}
+		target := func(target int) {
+			jumps = append(jumps, jump{ip, target})
+		}
+
+		switch Opcode(ins[ip]) {
+		case OpConstant:
+			constant(operands[0])
+		case OpJump, OpJumpNotTruthy, OpTry, OpMatchHash:
+			target(operands[0])
+		case OpGetGlobal, OpSetGlobal, OpDefineGlobal, OpCheckGlobal, OpCheckConst:
+			global(operands[0])
+		case OpGetLocal, OpSetLocal:
+			local(operands[0], false)
+		case OpGetCell, OpSetCell, OpLoadCell:
+			local(operands[0], true)
+		case OpGetFree, OpSetFree, OpLoadFree:
+			free(operands[0])
+		case OpSetIndex:
+			assign(operands[0])
+		case OpGetMember:
+			str(operands[0])
+		case OpSetMember:
+			str(operands[0])
+			assign(operands[1])
+		case OpDestructure:
+			if operands[0] >= len(DestructuringKinds) {
+				bad("bad kind %d", operands[0])
+			}
+			strs(operands[1], -1)
+		case OpClosure:
+			if f, ok := constant(operands[0]).(*Function); !ok && d.err == nil {
+				bad("constant %d that is not a function", operands[0])
+			} else if ok && len(f.Free) != operands[1] {
+				bad("%d free variables, want %d", operands[1], len(f.Free))
+			}
+		case OpCall, OpTailCall:
+			names(operands[1], operands[0])
+		case OpJumpIfSet:
+			if operands[0] >= len(fn.Locals) {
+				bad("bad local %d", operands[0])
+			}
+			target(operands[1])
+		case OpSetParam:
+			if operands[0] >= len(fn.Parameters) {
+				bad("bad parameter %d", operands[0])
+			}
+		case OpIterNext:
+			if operands[0] != 1 && operands[0] != 2 {
+				bad("%d loop variables", operands[0])
+			}
+			target(operands[1])
+		case OpRaise:
+			if _, ok := constant(operands[0]).(*object.Error); !ok && d.err == nil {
+				bad("constant %d that is not an error", operands[0])
+			}
+		case OpImport:
+			if operands[0] >= len(bytecode.Imports) {
+				bad("bad import %d", operands[0])
+			}
+		case OpClass:
+			if _, ok := constant(operands[0]).(*Class); !ok && d.err == nil {
+				bad("constant %d that is not a class", operands[0])
+			}
+		case OpImplement:
+			if class, ok := constant(operands[0]).(*Class); !ok && d.err == nil {
+				bad("constant %d that is not a class", operands[0])
+			} else if ok && operands[1] >= len(class.Node.Implements) {
+				bad("bad interface %d", operands[1])
+			}
+		case OpInterface:
+			if _, ok := constant(operands[0]).(*Interface); !ok && d.err == nil {
+				bad("constant %d that is not an interface", operands[0])
+			}
+		case OpEnum:
+			if enum, ok := constant(operands[0]).(*Enum); !ok && d.err == nil {
+				bad("constant %d that is not an enum", operands[0])
+			} else if ok && len(enum.Free) != operands[1] {
+				bad("%d free variables, want %d", operands[1], len(enum.Free))
+			}
+		case OpNew:
+			names(operands[1], operands[0])
+			str(operands[2])
+		case OpMatchArray:
+			target(operands[2])
+		case OpMatchKey:
+			str(operands[0])
+			target(operands[1])
+		case OpMatchVariant:
+			strs(operands[0], 2)
+			target(operands[2])
+		}
+
+		starts[ip] = true
+		last = ip
+		ip += 1 + width
+	}
+	if d.err != nil {
+		return
+	}
+
+	if last < 0 || Opcode(ins[last]) != OpReturn {
+		d.fail("%s does not end with OpReturn", name)
+		return
+	}
+	for _, j := range jumps {
+		if !starts[j.target] {
+			d.fail("%s at %d: bad jump target %d", name, j.at, j.target)
+			return
+		}
+	}
+
+	d.stack(bytecode, fn)
+}
+
+// stackState is the number of values a function has on the stack before
+// an instruction, and the number of try handlers it has.
+type stackState struct {
+	height, tries int
+}
+
+// stack follows every path through the instructions of fn, which
+// instructions checks first, and checks that no instruction pops values
+// the function did not push or pushes more than fn.MaxStack, that the
+// handlers are removed by OpEndTry only after OpTry adds them, and that
+// the paths reaching an instruction agree on both.
+func (d *decoder) stack(bytecode *Bytecode, fn *Function) {
+	name := fn.DisplayName()
+	ins := fn.Instructions
+
+	states := map[int]stackState{0: {}}
+	work := []int{0}
+
+	// reach records the state of the paths reaching the instruction at ip
+	reach := func(from, ip int, state stackState) {
+		if seen, ok := states[ip]; ok {
+			if seen != state {
+				d.fail("%s at %d: the paths to %d leave %d values on the stack and %d, or %d handlers and %d",
+					name, from, ip, seen.height, state.height, seen.tries, state.tries)
+			}
+			return
+		}
+		states[ip] = state
+		work = append(work, ip)
+	}
+
+	for len(work) > 0 && d.err == nil {
+		ip := work[len(work)-1]
+		work = work[:len(work)-1]
+		state := states[ip]
+
+		op := Opcode(ins[ip])
+		def, _ := Lookup(ins[ip])
+		operands, read := ReadOperands(def, ins[ip+1:])
+		next := ip + 1 + read
+
+		pops, effect := stackPops(op, operands), stackEffect(op, operands)
+		switch op {
+		case OpDestructure:
+			effect = len(bytecode.Constants[operands[1]].(*object.Array).Elements) - 1
+		case OpClass:
+			pops = classValues(bytecode.Constants[operands[0]].(*Class))
+			effect = 1 - pops
+		}
+
+		if state.height < pops {
+			d.fail("%s at %d: %s takes %d values from a stack of %d", name, ip, def.Name, pops, state.height)
+			return
+		}
+		after := stackState{state.height + effect, state.tries}
+		if after.height > fn.MaxStack {
+			d.fail("%s at %d: %s pushes more than %d values", name, ip, def.Name, fn.MaxStack)
+			return
+		}
+
+		switch op {
+		case OpReturn, OpThrow, OpRethrow, OpRaise, OpNoMatch:
+			// the function returns or the error goes to a handler
+		case OpJump:
+			reach(ip, operands[0], state)
+		case OpJumpNotTruthy:
+			reach(ip, operands[0], after)
+			reach(ip, next, after)
+		case OpJumpIfSet:
+			reach(ip, operands[1], state)
+			reach(ip, next, after)
+		case OpIterNext:
+			reach(ip, operands[1], state)
+			reach(ip, next, after)
+		case OpTry:
+			// the handler starts with the error on the stack
+			reach(ip, operands[0], stackState{state.height + 1, state.tries})
+			reach(ip, next, stackState{state.height, state.tries + 1})
+		case OpEndTry:
+			if state.tries == 0 {
+				d.fail("%s at %d: OpEndTry without a handler", name, ip)
+				return
+			}
+			reach(ip, next, stackState{state.height, state.tries - 1})
+		case OpMatchArray, OpMatchHash, OpMatchKey:
+			reach(ip, operands[len(operands)-1], stackState{state.height - 1, state.tries})
+			reach(ip, next, after)
+		case OpMatchVariant:
+			reach(ip, operands[2], stackState{state.height - 2, state.tries})
+			reach(ip, next, after)
+		default:
+			reach(ip, next, after)
+		}
+
+	}
+}
+
+// stackPops is the number of values an instruction takes from the stack.
+func stackPops(op Opcode, operands []int) int {
+	switch op {
+	case OpPop, OpDup, OpMinus, OpBang, OpJumpNotTruthy, OpSetGlobal, OpDefineGlobal,
+		OpSetLocal, OpSetCell, OpSetFree, OpFreeze, OpGetMember, OpDestructure, OpReturn,
+		OpSetParam, OpIter, OpIterNext, OpException, OpThrow, OpRethrow, OpMatchArray,
+		OpMatchHash, OpMatchKey, OpNoMatch:
+		return 1
+	case OpAdd, OpSub, OpMul, OpDiv, OpEqual, OpNotEqual, OpLess, OpGreater, OpRange,
+		OpIndex, OpSetMember, OpImplement, OpSame, OpMatchVariant:
+		return 2
+	case OpSetIndex:
+		return 3
+	case OpArray, OpTuple:
+		return operands[0]
+	case OpHash:
+		return 2 * operands[0]
+	case OpClosure, OpEnum:
+		return operands[1]
+	case OpCall, OpTailCall, OpNew:
+		return operands[0] + 1
+	default:
+		return 0
+	}
+}
+
+// classValues is the number of values OpClass takes from the stack for
+// class: the superclass and the closures of its methods and initializers.
+func classValues(class *Class) int {
+	n := len(class.Node.Methods)
+	if class.Node.Super != nil {
+		n++
+	}
+	for _, field := range class.Node.Fields {
+		if field.Value != nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,104 @@
+package compiler
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// Bytecode is a compiled program, run by the vm package, with the files it
+// imports.
+type Bytecode struct {
+	Main      *Function       // the top-level statements
+	Constants []object.Object // literals and compiled code
+	Globals   []string        // names of the global slots
+	Imports   []*Import
+	Modules   []*Module
+}
+
+// Import is an import statement of a compiled program. It imports the
+// module of the standard library named by Node when Module is -1, and else
+// Modules[Module] of the program, or it raises Err, the error the compiler
+// found importing the file.
+type Import struct {
+	Node   *ast.ImportStatement
+	Module int
+	Err    *object.Error
+}
+
+// Module is a file imported by a compiled program, compiled with it. Its
+// top-level names have global slots of their own.
+type Module struct {
+	Name    string // the file name without its extension
+	Path    string
+	Main    *Function // the top-level statements
+	Globals []int     // the slots of its globals
+}
+
+// Function is a compiled function. Its locals live in slots numbered from
+// 0, the parameters first. Locals captured by nested functions are kept in
+// cells shared with the closures.
+type Function struct {
+	Name         string // empty for function literals
+	Parameters   []*ast.TypedField
+	ReturnTypes  []*ast.Identifier
+	Body         *ast.BlockStatement
+	Instructions Instructions
+	Locals       []string // names of the local slots
+	Free         []string // names of the free variables
+	Cells        []int    // slots of the locals captured by closures
+	MaxStack     int      // the most values the function pushes on the stack
+	Lines        []Line
+}
+
+// Line maps the instructions from Offset up to the next Line to the source
+// position they were compiled from.
+type Line struct {
+	Offset int
+	Pos    token.Position
+}
+
+func (f *Function) Type() object.ObjectType { return object.COMPILED_FUNCTION_OBJ }
+
+// Inspect renders the function like the evaluator renders functions.
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	if f.Body != nil {
+		out.WriteString(f.Body.String())
+	}
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+// DisplayName is the name used for the function in stack traces.
+func (f *Function) DisplayName() string {
+	if f.Name == "" {
+		return "<anonymous>"
+	}
+	return f.Name
+}
+
+// Pos returns the source position of the instruction at offset.
+func (f *Function) Pos(offset int) token.Position {
+	i := sort.Search(len(f.Lines), func(i int) bool { return f.Lines[i].Offset > offset })
+	if i == 0 {
+		return token.Position{}
+	}
+
+	return f.Lines[i-1].Pos
+}
@@ -0,0 +1,198 @@
+package compiler
+
+import (
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// matchArm is the arm of a match expression being compiled.
+type matchArm struct {
+	fails    []matchFail
+	slots    map[string]int    // temporary slots of the bound values
+	bindings []*ast.Identifier // bound names in order of appearance
+}
+
+// matchFail is a jump taken when the value does not match a pattern, with
+// the stack depth after it.
+type matchFail struct {
+	offset int
+	depth  int
+}
+
+// compileMatch compiles a match expression. The value stays on the stack
+// while the arms test it. A pattern consumes a copy of the value and the
+// parts it takes apart, keeping the values it binds in temporary slots,
+// and the arm whose pattern matches declares them before running its body.
+func (c *Compiler) compileMatch(node *ast.MatchExpression) error {
+	if err := c.compileExpression(node.Value); err != nil {
+		return err
+	}
+	depth := c.scope.depth
+
+	var fails []matchFail
+	var ends []int
+
+	for _, arm := range node.Arms {
+		c.unwindFails(node.Pos(), fails, depth)
+
+		m := &matchArm{slots: map[string]int{}}
+
+		c.emit(arm.Pattern.Pos(), OpDup)
+		if err := c.compilePattern(node, arm.Pattern, m); err != nil {
+			return err
+		}
+		c.emit(arm.Pattern.Pos(), OpPop)
+
+		// the names are bound where the evaluator binds them, at the match
+		for _, name := range m.bindings {
+			c.emit(node.Pos(), OpGetLocal, m.slots[name.Value])
+			c.declare(&ast.Identifier{Token: node.Token, Value: name.Value}, false)
+		}
+
+		var err error
+		if body, ok := arm.Body.(*ast.BlockStatement); ok {
+			err = c.compileBlock(body.Statements, body.Pos())
+		} else {
+			err = c.compileExpression(arm.Body.(ast.Expression))
+		}
+		if err != nil {
+			return err
+		}
+
+		ends = append(ends, c.emit(node.Pos(), OpJump, 0))
+		c.scope.depth = depth
+		fails = m.fails
+	}
+
+	c.unwindFails(node.Pos(), fails, depth)
+	c.emit(node.Pos(), OpNoMatch)
+
+	for _, end := range ends {
+		c.patch(end)
+	}
+	c.scope.depth = depth
+
+	return nil
+}
+
+// unwindFails compiles the code the jumps of fails go to, which pops the
+// parts of the value they left on the stack down to depth.
+func (c *Compiler) unwindFails(pos token.Position, fails []matchFail, depth int) {
+	top := depth
+	for _, fail := range fails {
+		top = max(top, fail.depth)
+	}
+
+	c.scope.depth = top
+	c.track()
+
+	for d := top; d >= depth; d-- {
+		for _, fail := range fails {
+			if fail.depth == d {
+				c.patch(fail.offset)
+			}
+		}
+
+		if d > depth {
+			c.emit(pos, OpPop)
+		}
+	}
+}
+
+// compilePattern compiles the test of the value on top of the stack
+// against pattern, which pops the value.
+func (c *Compiler) compilePattern(node *ast.MatchExpression, pattern ast.Pattern, m *matchArm) error {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		c.emit(pattern.Pos(), OpPop)
+	case *ast.BindingPattern:
+		c.emit(pattern.Pos(), OpSetLocal, m.slot(c, pattern.Name))
+	case *ast.LiteralPattern:
+		if err := c.compileExpression(pattern.Value); err != nil {
+			return err
+		}
+		c.emit(pattern.Pos(), OpSame)
+		m.fail(c, c.emit(pattern.Pos(), OpJumpNotTruthy, 0), 0)
+	case *ast.ArrayPattern:
+		n := len(pattern.Elements)
+		if n > 255 {
+			return &Error{Pos: pattern.Pos(), Message: "too many elements in pattern"}
+		}
+
+		rest := 0
+		if pattern.Rest != nil {
+			rest = 1
+		}
+
+		// the rest is pushed first and the first element last
+		m.fail(c, c.emit(pattern.Pos(), OpMatchArray, n, rest, 0), n+rest)
+		for _, element := range pattern.Elements {
+			if err := c.compilePattern(node, element, m); err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil {
+			c.emit(pattern.Rest.Pos(), OpSetLocal, m.slot(c, pattern.Rest))
+		}
+	case *ast.HashPattern:
+		m.fail(c, c.emit(pattern.Pos(), OpMatchHash, 0), 1)
+		for _, pair := range pattern.Pairs {
+			key := c.addConstant(&object.String{Value: pair.Key.Value})
+			m.fail(c, c.emit(pair.Key.Pos(), OpMatchKey, key, 0), 2)
+			if err := c.compilePattern(node, pair.Pattern, m); err != nil {
+				return err
+			}
+		}
+		c.emit(pattern.Pos(), OpPop)
+	case *ast.VariantPattern:
+		if err := c.compileExpression(pattern.Enum); err != nil {
+			return err
+		}
+
+		args := NoArguments
+		if pattern.Arguments != nil {
+			args = len(pattern.Arguments)
+			if args >= NoArguments {
+				return &Error{Pos: pattern.Pos(), Message: "too many arguments in pattern"}
+			}
+		}
+
+		names := &object.Array{Elements: []object.Object{
+			&object.String{Value: pattern.Enum.Value},
+			&object.String{Value: pattern.Variant.Value},
+		}}
+
+		// the value of the first argument is pushed last
+		m.fail(c, c.emit(node.Pos(), OpMatchVariant, c.addConstant(names), args, 0), len(pattern.Arguments))
+		for _, arg := range pattern.Arguments {
+			if err := c.compilePattern(node, arg, m); err != nil {
+				return err
+			}
+		}
+	default:
+		return unsupported(pattern)
+	}
+
+	return nil
+}
+
+// slot returns the temporary slot of the value bound to name.
+func (m *matchArm) slot(c *Compiler, name *ast.Identifier) int {
+	slot, ok := m.slots[name.Value]
+	if !ok {
+		slot = c.temporary()
+		m.slots[name.Value] = slot
+		m.bindings = append(m.bindings, name)
+	}
+
+	return slot
+}
+
+// fail records the jump of the instruction just emitted at offset, which
+// leaves n more values on the stack when the value matches than when it
+// jumps.
+func (m *matchArm) fail(c *Compiler, offset int, n int) {
+	m.fails = append(m.fails, matchFail{offset: offset, depth: c.scope.depth - n})
+}
@@ -0,0 +1,78 @@
+package compiler
+
+import "github.com/emo-lang/emo/ast"
+
+type symbolKind int
+
+const (
+	globalSymbol symbolKind = iota
+	localSymbol
+	freeSymbol
+)
+
+// symbol is a variable as seen from the function being compiled.
+type symbol struct {
+	name     string
+	kind     symbolKind
+	index    int  // global slot, local slot or index of the free variable
+	cell     bool // a local kept in a cell, as closures capture it
+	constant bool // declared with const or define
+}
+
+// scope is the symbol table and the code of a function being compiled. The
+// top-level scope declares globals, its locals are only the temporaries of
+// the compiler.
+type scope struct {
+	outer *scope
+
+	locals map[string]*symbol
+	names  []string
+
+	// free variables by their index in the closure, as symbols of the
+	// enclosing scope
+	free        []*symbol
+	freeSymbols map[string]*symbol
+
+	cells    map[string]bool // locals to keep in cells
+	captured map[string]bool // locals captured by nested functions
+
+	// locals declared after a nested function refers to them, which the
+	// nested function sees before they are declared here
+	ahead   map[string]*symbol
+	forward map[string]bool // names nested functions found no local for
+
+	instructions Instructions
+	lines        []Line
+	depth        int // number of values on the stack
+	maxDepth     int
+
+	loops []*loop
+	tries []*tryBlock
+}
+
+func newScope(outer *scope, cells map[string]bool) *scope {
+	return &scope{
+		outer:       outer,
+		locals:      map[string]*symbol{},
+		freeSymbols: map[string]*symbol{},
+		cells:       cells,
+		captured:    map[string]bool{},
+		ahead:       map[string]*symbol{},
+		forward:     map[string]bool{},
+	}
+}
+
+// loop is a loop being compiled, the target of break and continue.
+type loop struct {
+	depth  int   // stack depth at the start of each iteration
+	tries  int   // number of try blocks open around the loop
+	start  int   // offset continue jumps to
+	breaks []int // offsets of the jumps to the end of the loop
+}
+
+// tryBlock is the region of a try expression being compiled. Leaving it by
+// break, continue or return removes its handler and runs its finally block.
+type tryBlock struct {
+	handler bool
+	finally *ast.BlockStatement // nil without finally
+}
@@ -0,0 +1,159 @@
+package compiler
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// Class is a compiled class expression. The machine creates the class from
+// its declarations and from the closures of its methods and of its field
+// initializers, which are compiled like functions defined where the class
+// is.
+type Class struct {
+	Node *ast.ClassExpression
+}
+
+func (c *Class) Type() object.ObjectType { return object.COMPILED_CLASS_OBJ }
+func (c *Class) Inspect() string         { return "class " + c.Node.Name.Value }
+
+// Interface is a compiled interface expression.
+type Interface struct {
+	Node *ast.InterfaceExpression
+}
+
+func (i *Interface) Type() object.ObjectType { return object.COMPILED_INTERFACE_OBJ }
+func (i *Interface) Inspect() string         { return "interface " + i.Node.Name.Value }
+
+// Enum is a compiled enum expression. The types of the fields of its
+// variants are looked up in the variables named Free, which the enum
+// captures like a closure, and then in the globals.
+type Enum struct {
+	Node *ast.EnumExpression
+	Free []string
+}
+
+func (e *Enum) Type() object.ObjectType { return object.COMPILED_ENUM_OBJ }
+func (e *Enum) Inspect() string         { return "enum " + e.Node.Name.Value }
+
+// compileClass compiles a class expression, which binds the class to its
+// name. The superclass, if any, and the closures of the methods and of the
+// field initializers, sorted by name, are on the stack when the class is
+// created.
+func (c *Compiler) compileClass(node *ast.ClassExpression) error {
+	n := 0
+
+	if node.Super != nil {
+		if err := c.compileExpression(node.Super); err != nil {
+			return err
+		}
+		n++
+	}
+
+	// the methods see the class by its name, also in their annotations
+	c.declareAhead(node.Name)
+
+	for _, name := range slices.Sorted(maps.Keys(node.Methods)) {
+		def := node.Methods[name].Function
+		if err := c.compileFunction(node.Name.Value+"."+name, def.Parameters, def.ReturnTypes, def.Body, def.Pos(), name); err != nil {
+			return err
+		}
+		n++
+	}
+
+	// a field initializer is run like a function without parameters
+	for _, name := range slices.Sorted(maps.Keys(node.Fields)) {
+		field := node.Fields[name]
+		if field.Value == nil {
+			continue
+		}
+
+		body := &ast.BlockStatement{Statements: []ast.Statement{&ast.ExpressionStatement{Expression: field.Value}}}
+		if err := c.compileFunction(node.Name.Value+"."+name, nil, nil, body, field.Value.Pos(), ""); err != nil {
+			return err
+		}
+		n++
+	}
+
+	class := c.addConstant(&Class{Node: node})
+	c.emit(node.Pos(), OpClass, class)
+	c.scope.depth -= n - 1
+	c.track()
+
+	for i, name := range node.Implements {
+		if err := c.compileExpression(name); err != nil {
+			return err
+		}
+		c.emit(node.Pos(), OpImplement, class, i)
+	}
+
+	c.emit(node.Pos(), OpDup)
+	c.bind(node.Name)
+
+	return nil
+}
+
+// compileNew compiles the creation of an instance of a class.
+func (c *Compiler) compileNew(node *ast.NewExpression) error {
+	if err := c.compileExpression(node.What); err != nil {
+		return err
+	}
+
+	names, err := c.compileArguments(node.Pos(), node.Arguments)
+	if err != nil {
+		return err
+	}
+
+	name := c.addConstant(&object.String{Value: node.What.Value})
+	c.emit(node.Pos(), OpNew, len(node.Arguments), names, name)
+
+	return nil
+}
+
+// compileEnum compiles an enum expression, which binds the enum to its
+// name. The enum captures the variables the types of its fields may name.
+func (c *Compiler) compileEnum(node *ast.EnumExpression) error {
+	// the fields may be of the enum itself
+	c.declareAhead(node.Name)
+
+	s := newScope(c.scope, nil)
+	for _, variant := range node.Variants {
+		for _, field := range variant.Fields {
+			c.resolveLocal(s, field.Type.Value)
+		}
+	}
+
+	enum := &Enum{Node: node}
+	for _, free := range s.free {
+		enum.Free = append(enum.Free, free.name)
+	}
+
+	c.loadFree(node.Pos(), s)
+	c.emit(node.Pos(), OpEnum, c.addConstant(enum), len(s.free))
+	c.emit(node.Pos(), OpDup)
+	c.bind(node.Name)
+
+	return nil
+}
+
+// bind pops the value on top of the stack into name, declared in the
+// current scope, as the evaluator binds classes, interfaces and enums,
+// which cannot replace constants.
+func (c *Compiler) bind(name *ast.Identifier) {
+	c.declare(name, false)
+}
+
+// loadFree pushes the cells of the free variables of s, which the closure
+// or the enum of s captures.
+func (c *Compiler) loadFree(pos token.Position, s *scope) {
+	for _, free := range s.free {
+		if free.kind == freeSymbol {
+			c.emit(pos, OpLoadFree, free.index)
+		} else {
+			c.emit(pos, OpLoadCell, free.index)
+		}
+	}
+}
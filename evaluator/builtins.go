@@ -6,7 +6,7 @@ var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1", len(args))
 			}
 
 			switch arg := args[0].(type) {
@@ -15,18 +15,18 @@ var builtins = map[string]*object.Builtin{
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			default:
-				return newError("argument to `len` not supported, got %s", args[0].Type())
+				return newKindError(object.TYPE_ERROR, "argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"first": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s",
+				return newKindError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -42,11 +42,11 @@ var builtins = map[string]*object.Builtin{
 	"last": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `last` must be ARRAY, got %s",
+				return newKindError(object.TYPE_ERROR, "argument to `last` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -63,11 +63,11 @@ var builtins = map[string]*object.Builtin{
 	"rest": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s",
+				return newKindError(object.TYPE_ERROR, "argument to `rest` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -86,11 +86,11 @@ var builtins = map[string]*object.Builtin{
 	"push": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2",
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `push` must be ARRAY, got %s",
+				return newKindError(object.TYPE_ERROR, "argument to `push` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -105,6 +105,30 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 
+	"is_a?": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2",
+					len(args))
+			}
+
+			instance, ok := args[0].(*object.ClassInstance)
+
+			switch what := args[1].(type) {
+			case *object.Class:
+				return nativeBoolToBooleanObject(ok && instance.Klass.IsA(what))
+			case *object.Interface:
+				return nativeBoolToBooleanObject(ok && instance.Klass.Implements(what))
+			case *object.Enum:
+				value, ok := args[0].(*object.EnumValue)
+				return nativeBoolToBooleanObject(ok && value.Variant.Enum == what)
+			default:
+				return newKindError(object.TYPE_ERROR, "second argument to `is_a?` must be CLASS, INTERFACE or ENUM, got %s",
+					args[1].Type())
+			}
+		},
+	},
+
 	"println": {
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) == 0 {
@@ -0,0 +1,168 @@
+package evaluator
+
+import (
+	"slices"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// MaxCallDepth is the number of calls to functions that can be in progress
+// at once, as `emo run --max-depth` sets it. A call beyond it raises a
+// RecursionError, where runaway recursion would otherwise overflow the Go
+// stack and crash the process. Tail calls do not count: they replace the
+// call making them.
+var MaxCallDepth = 10000
+
+// callDepth is the number of calls to functions in progress.
+var callDepth int
+
+// RecursionError is the error of a call beyond MaxCallDepth.
+func RecursionError() *object.Error {
+	return newKindError(object.RECURSION_ERROR, "maximum call depth of %d exceeded", MaxCallDepth)
+}
+
+// MaxTailFrames is the number of frames of tail calls kept for the stack
+// traces of errors, the latest ones, when a call makes more of them.
+const MaxTailFrames = 100
+
+// tailCall is a call in tail position, `return f(...)`. The function making
+// it returns the call instead of its result, and runFunction makes the call
+// in its place, so tail recursion runs without growing the Go stack.
+type tailCall struct {
+	fn    *object.Function
+	args  []object.Object
+	named []namedArgument
+	pos   token.Position
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call to " + tc.fn.DisplayName() }
+
+// evalReturnCall evaluates `return call`, returning the call to be made by
+// the caller when it calls a function of the script.
+func evalReturnCall(call *ast.CallExpression, env *object.Environment) object.Object {
+	function := Eval(call.Function, env)
+	if isError(function) {
+		return function
+	}
+
+	args, named, err := evalArguments(call.Arguments, env)
+	if err != nil {
+		return err
+	}
+
+	if fn, ok := function.(*object.Function); ok {
+		return &object.ReturnValue{Value: &tailCall{fn: fn, args: args, named: named, pos: call.Pos()}}
+	}
+
+	val := finishCall(applyFunction(function, args, named, call.Pos()), call.Pos())
+	if isError(val) {
+		return val
+	}
+
+	return &object.ReturnValue{Value: val}
+}
+
+// finishTailCall makes the tail call obj is, for the places a return does
+// not leave a function: the top level of a program and try blocks, whose
+// errors must be caught.
+func finishTailCall(obj object.Object) object.Object {
+	call, ok := obj.(*tailCall)
+	if !ok {
+		return obj
+	}
+
+	return finishCall(runFunction(call.fn, call.args, call.named, call.pos), call.pos)
+}
+
+// finishCall locates an error raised by a call at the call, as Eval does.
+func finishCall(result object.Object, pos token.Position) object.Object {
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = pos
+	}
+
+	return result
+}
+
+// runFunction calls fn and then the functions it calls in tail position,
+// in a loop. Errors get the frames of all the calls in their stack trace,
+// and in strict mode the result is checked against the return types of
+// every function that made one, as the result of the first call.
+func runFunction(fn *object.Function, args []object.Object, named []namedArgument, callPos token.Position) object.Object {
+	if callDepth >= MaxCallDepth {
+		return RecursionError()
+	}
+
+	callDepth++
+	defer func() { callDepth-- }()
+
+	// the frames of the call and of the tail calls, outermost first
+	frames := []object.StackFrame{{Function: fn.DisplayName(), Call: callPos}}
+	var checks []*object.Function
+
+	for {
+		env, err := extendFunctionEnv(fn, args, named)
+		if err != nil {
+			return raiseAt(err, frames)
+		}
+
+		result := unwrapReturnValue(Eval(fn.Body, env))
+
+		call, ok := result.(*tailCall)
+		if !ok {
+			if err, ok := result.(*object.Error); ok {
+				return raise(err, frames)
+			}
+
+			if Strict {
+				checks = append(checks, fn)
+				for i := len(checks) - 1; i >= 0; i-- {
+					if err := checkResult(checks[i].DisplayName(), checks[i].ReturnTypes, result, checks[i].Env); err != nil {
+						return raiseAt(err, frames[:1])
+					}
+				}
+			}
+
+			return result
+		}
+
+		if Strict && !slices.Contains(checks, fn) {
+			checks = append(checks, fn)
+		}
+
+		fn, args, named = call.fn, call.args, call.named
+		frames = append(TrimTailFrames(frames), object.StackFrame{Function: fn.DisplayName(), Call: call.pos})
+	}
+}
+
+// TrimTailFrames drops the oldest frames of tail calls from frames but the
+// first when there are many more than MaxTailFrames.
+func TrimTailFrames(frames []object.StackFrame) []object.StackFrame {
+	if len(frames) <= 2*MaxTailFrames {
+		return frames
+	}
+
+	return append(frames[:1], frames[len(frames)-MaxTailFrames:]...)
+}
+
+// raise adds the frames of the calls in progress to err, innermost first.
+func raise(err *object.Error, frames []object.StackFrame) *object.Error {
+	for i := len(frames) - 1; i >= 0; i-- {
+		err.Stack = append(err.Stack, frames[i])
+	}
+
+	return err
+}
+
+// raiseAt is raise for an error of the innermost call itself rather than of
+// the function it calls, which is located at the call.
+func raiseAt(err *object.Error, frames []object.StackFrame) *object.Error {
+	n := len(frames) - 1
+	if !err.Pos.IsValid() {
+		err.Pos = frames[n].Call
+	}
+
+	return raise(err, frames[:n])
+}
@@ -0,0 +1,141 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emo-lang/emo/object"
+)
+
+// Capabilities are what scripts may do outside the interpreter through the
+// os and fs modules, and which files they may import. The zero value allows
+// nothing.
+type Capabilities struct {
+	FS      bool     // read and write files
+	FSRoots []string // if not empty, the only directories FS allows, with everything under them
+	Env     bool     // read environment variables
+	Exit    bool     // end the process
+	// the directories modules may be imported from besides FSRoots, when
+	// FS is not allowed everywhere
+	ImportRoots []string
+}
+
+// AllCapabilities allows scripts to do everything, as `emo run` does
+// unless told otherwise.
+func AllCapabilities() Capabilities {
+	return Capabilities{FS: true, Env: true, Exit: true}
+}
+
+// Caps are the capabilities of the scripts being evaluated.
+var Caps = AllCapabilities()
+
+// Args are the command-line arguments of the script, returned by os.args.
+var Args []string
+
+// exitProcess ends the process for os.exit.
+var exitProcess = os.Exit
+
+// checkPath reports whether the scripts may touch the file at path, for
+// the function name of the fs module, and returns the path to open: with
+// FSRoots, the real path that was checked, so the file opened is the file
+// allowed.
+func checkPath(name, path string) (string, *object.Error) {
+	if !Caps.FS {
+		return "", newKindError(object.PERMISSION_ERROR, "%s: filesystem access is not allowed", name)
+	}
+
+	if len(Caps.FSRoots) == 0 {
+		return path, nil
+	}
+
+	if real := realPath(path); within(real, Caps.FSRoots) {
+		return real, nil
+	}
+
+	return "", newKindError(object.PERMISSION_ERROR, "%s: access to %s is not allowed", name, path)
+}
+
+// canImport reports whether the scripts may import the file at path. With
+// FS allowed everywhere they may import any file, otherwise only the files
+// under FSRoots, if FS is allowed, and ImportRoots.
+func canImport(path string) bool {
+	real := realPath(path)
+	if !Caps.FS {
+		return within(real, Caps.ImportRoots)
+	}
+
+	return len(Caps.FSRoots) == 0 || within(real, Caps.FSRoots) || within(real, Caps.ImportRoots)
+}
+
+// within reports whether the real path real is one of roots or under one
+// of them.
+func within(real string, roots []string) bool {
+	for _, root := range roots {
+		rel, err := filepath.Rel(realPath(root), real)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maxLinks is the most symbolic links realPath follows, as the system
+// gives up on loops.
+const maxLinks = 255
+
+// realPath returns the absolute path of path with symbolic links resolved,
+// so links cannot lead out of the allowed directories. The components are
+// resolved one at a time, as the system opens them: a `..` after a link
+// leaves the directory the link points to, not the one holding the link.
+// The components from the first one that does not exist are kept as they
+// are.
+func realPath(path string) string {
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return filepath.Clean(path)
+		}
+		path = wd + string(filepath.Separator) + path
+	}
+
+	real := string(filepath.Separator)
+	rest := strings.Split(path, string(filepath.Separator))
+	links := 0
+
+	for len(rest) > 0 {
+		part := rest[0]
+		rest = rest[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			real = filepath.Dir(real)
+			continue
+		}
+
+		next := filepath.Join(real, part)
+		info, err := os.Lstat(next)
+		if err != nil {
+			return filepath.Join(append([]string{next}, rest...)...)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			real = next
+			continue
+		}
+
+		target, err := os.Readlink(next)
+		links++
+		if err != nil || links > maxLinks {
+			return filepath.Join(append([]string{next}, rest...)...)
+		}
+		if filepath.IsAbs(target) {
+			real = string(filepath.Separator)
+		}
+		rest = append(strings.Split(target, string(filepath.Separator)), rest...)
+	}
+
+	return real
+}
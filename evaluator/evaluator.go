@@ -2,20 +2,44 @@ package evaluator
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/emo-lang/emo/ast"
 	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/resolver"
+	"github.com/emo-lang/emo/token"
 )
 
 var (
 	NIL   = &object.Nil{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	// errors are located at the innermost node that produced them
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
+		if errs := resolver.Resolve(node); len(errs) > 0 {
+			return &object.Error{Kind: object.NAME_ERROR, Message: errs[0].Message, Pos: errs[0].Pos}
+		}
+
 		return evalProgram(node, env)
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
@@ -23,6 +47,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Integer{Value: node.Value}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.ParenExpression:
+		return Eval(node.Expression, env)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -45,14 +71,31 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalBlockStatement(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		return newThrownError(val)
 	case *ast.DefineStatement:
-		if _, ok := env.Get(node.Name.Value); !ok {
-			val := Eval(node.Value, env)
-			if isError(val) {
-				return val
-			}
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
 
-			env.Set(node.Name.Value, val)
+		if err := declare(env, node.Name, val, true); err != nil {
+			return err
 		}
 	case *ast.VarStatement:
 		val := Eval(node.Value, env)
@@ -60,7 +103,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 
-		env.Set(node.Name.Value, val)
+		if err := declare(env, node.Name, val, node.Token.Type == token.CONST); err != nil {
+			return err
+		}
+	case *ast.DestructuringStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		return evalDestructuring(node, val, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.StringLiteral:
@@ -72,6 +126,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 
 		return &object.Array{Elements: elements}
+	case *ast.TupleLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+
+		return &object.Tuple{Elements: elements}
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -90,13 +151,23 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		params := node.Parameters
 		body := node.Body
 
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		if err := checkParameters(params, env); err != nil {
+			return err
+		}
+
+		return &object.Function{Parameters: params, ReturnTypes: node.ReturnTypes, Body: body, Locals: node.Locals, Slots: node.Slots, Env: env}
 	case *ast.FunctionDefinition:
 		params := node.Parameters
 		body := node.Body
 
-		fn := &object.Function{Parameters: params, Body: body, Env: env}
-		env.Set(node.Name.Value, fn)
+		if err := checkParameters(params, env); err != nil {
+			return err
+		}
+
+		fn := &object.Function{Name: node.Name.Value, Parameters: params, ReturnTypes: node.ReturnTypes, Body: body, Locals: node.Locals, Slots: node.Slots, Env: env}
+		if err := declare(env, node.Name, fn, false); err != nil {
+			return err
+		}
 
 		return fn
 	case *ast.CallExpression:
@@ -105,98 +176,740 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, named, err := evalArguments(node.Arguments, env)
+		if err != nil {
+			return err
 		}
 
-		return applyFunction(function, args)
+		return applyFunction(function, args, named, node.Pos())
 
 	case *ast.DotExpression:
-		receiver := Eval(node.Left, env)
+		return evalDotExpression(node, env)
+	case *ast.ClassExpression:
+		return evalClassExpression(node, env)
+	case *ast.InterfaceExpression:
+		iface := &object.Interface{Name: node.Name, Methods: node.Methods}
+		if err := declare(env, node.Name, iface, false); err != nil {
+			return err
+		}
 
-		if receiver.Type() != object.CLASS_INSTANCE_OBJ {
-			return NIL
+		return iface
+	case *ast.EnumExpression:
+		return evalEnumExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.NewExpression:
+		return evalNewExpression(node, env)
+	case *ast.ReturnStatement:
+		if call, ok := node.ReturnValue.(*ast.CallExpression); ok {
+			return evalReturnCall(call, env)
 		}
 
-		switch receiver := receiver.(type) {
-		case *object.ClassInstance:
-			switch right := node.Right.(type) {
-			case *ast.Identifier:
-				// lookup field
-				if val, ok := receiver.Fields[right.Value]; ok {
-					return val
-				}
+		val := Eval(node.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
 
-				// then lookup method for method call
-				objectEnv := object.NewEnclosedEnvironment(env)
-				objectEnv.Set("self", receiver)
+		return &object.ReturnValue{Value: val}
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	}
 
-				if method, ok := receiver.Klass.Methods[right.Value]; ok {
-					return Eval(method.Function, objectEnv)
-				}
+	return nil
+}
+
+func evalClassExpression(node *ast.ClassExpression, env *object.Environment) object.Object {
+	klass := &object.Class{Name: node.Name, Fields: node.Fields, Methods: node.Methods, Env: env}
+
+	for _, name := range slices.Sorted(maps.Keys(node.Methods)) {
+		if err := checkParameters(node.Methods[name].Function.Parameters, env); err != nil {
+			return err
+		}
+	}
+
+	if node.Super != nil {
+		super := Eval(node.Super, env)
+		if isError(super) {
+			return super
+		}
+
+		if err := extend(klass, super); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range node.Implements {
+		val := Eval(name, env)
+		if isError(val) {
+			return val
+		}
+
+		if err := implement(klass, val, name); err != nil {
+			return err
+		}
+	}
+
+	if err := declare(env, node.Name, klass, false); err != nil {
+		return err
+	}
+
+	return klass
+}
+
+// extend makes klass a subclass of super.
+func extend(klass *object.Class, super object.Object) *object.Error {
+	superClass, ok := super.(*object.Class)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "%s cannot extend %s", klass.Name.Value, super.Type())
+	}
+
+	klass.Super = superClass
+
+	return nil
+}
+
+// implement adds the interface val, named name after implements, to the
+// interfaces of klass, which must conform to it.
+func implement(klass *object.Class, val object.Object, name *ast.Identifier) *object.Error {
+	iface, ok := val.(*object.Interface)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "%s cannot implement %s", klass.Name.Value, val.Type())
+	}
+
+	if err := checkConformance(klass, iface); err != nil {
+		err.Pos = name.Pos()
+		return err
+	}
+
+	klass.Interfaces = append(klass.Interfaces, iface)
+
+	return nil
+}
+
+// checkConformance verifies that klass, with the methods it inherits, has
+// a public method for every method of iface, with the same number of
+// parameters and the same return types.
+func checkConformance(klass *object.Class, iface *object.Interface) *object.Error {
+	names := make([]string, 0, len(iface.Methods))
+	for name := range iface.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := iface.Methods[name]
+
+		method, _ := klass.LookupMethod(name)
+		if method == nil {
+			return newConformanceError(klass, iface, "missing method %s", name)
+		}
+
+		if !method.Public {
+			return newConformanceError(klass, iface, "method %s is private", name)
+		}
+
+		fn := method.Function
+		if len(fn.Parameters) != len(want.Parameters) {
+			return newConformanceError(klass, iface, "method %s takes %d parameters, want %d",
+				name, len(fn.Parameters), len(want.Parameters))
+		}
+
+		if !sameTypes(fn.ReturnTypes, want.ReturnTypes) {
+			return newConformanceError(klass, iface, "method %s returns %s, want %s",
+				name, typeList(fn.ReturnTypes), typeList(want.ReturnTypes))
+		}
+	}
+
+	return nil
+}
+
+func newConformanceError(klass *object.Class, iface *object.Interface, format string, a ...any) *object.Error {
+	return newKindError(object.TYPE_ERROR, "%s does not implement %s: %s",
+		klass.Name.Value, iface.Name.Value, fmt.Sprintf(format, a...))
+}
+
+func sameTypes(a, b []*ast.Identifier) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Value != b[i].Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// typeList formats return types for error messages: `(Int, String)`, or
+// `nothing` when there are none.
+func typeList(types []*ast.Identifier) string {
+	if len(types) == 0 {
+		return "nothing"
+	}
+
+	names := []string{}
+	for _, t := range types {
+		names = append(names, t.Value)
+	}
+
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+// evalNewExpression creates an instance of a class. Fields start with their
+// initializer in the class body or the zero value of their type. Then the
+// init method of the class is called with the arguments of new; classes
+// without init take the initial field values by name instead.
+func evalNewExpression(node *ast.NewExpression, env *object.Environment) object.Object {
+	what := Eval(node.What, env)
+	if isError(what) {
+		return what
+	}
+
+	klass, ok := what.(*object.Class)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "cannot create an instance of %s", what.Type())
+	}
+
+	args, named, err := evalArguments(node.Arguments, env)
+	if err != nil {
+		return err
+	}
+
+	instance, err := newInstance(klass, node.What)
+	if err != nil {
+		return err
+	}
+
+	if init, definedBy := klass.LookupMethod("init"); init != nil {
+		fn := bindMethod(instance, init, definedBy)
+
+		if result := applyFunction(fn, args, named, node.Pos()); isError(result) {
+			return result
+		}
+
+		return instance
+	}
+
+	if err := setFields(instance, args, named); err != nil {
+		return err
+	}
+
+	return instance
+}
 
-				return NIL
-			case *ast.CallExpression:
-				// call method
-				switch fn := right.Function.(type) {
-				case *ast.Identifier:
+// newInstance returns an instance of klass named name with the initial
+// values of its fields.
+func newInstance(klass *object.Class, name *ast.Identifier) (*object.ClassInstance, *object.Error) {
+	instance := &object.ClassInstance{Klass: klass, Name: name, Fields: map[string]object.Object{}}
 
-					objectEnv := object.NewEnclosedEnvironment(env)
-					objectEnv.Set("self", receiver)
+	// initialize inherited fields first, so subclasses can redeclare them
+	chain := []*object.Class{}
+	for c := klass; c != nil; c = c.Super {
+		chain = append([]*object.Class{c}, chain...)
+	}
 
-					if method, ok := receiver.Klass.Methods[fn.Value]; ok {
-						return applyFunction(Eval(method.Function, objectEnv), evalExpressions(right.Arguments, objectEnv))
-					}
+	for _, c := range chain {
+		names := make([]string, 0, len(c.Fields))
+		for name := range c.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			field := c.Fields[name]
+
+			switch {
+			case field.Value == nil:
+				instance.Fields[name] = zeroValue(field.Field.Type)
+			case c.Compiled != nil:
+				val := c.Compiled.InitialValue(name)
+				if err, ok := val.(*object.Error); ok {
+					return nil, err
+				}
+				instance.Fields[name] = val
+			default:
+				val := Eval(field.Value, c.Env)
+				if err, ok := val.(*object.Error); ok {
+					return nil, err
 				}
+				instance.Fields[name] = val
 			}
 		}
+	}
+
+	return instance, nil
+}
+
+// setFields sets the fields of an instance of a class without init to the
+// arguments of new, which name them.
+func setFields(instance *object.ClassInstance, args []object.Object, named []namedArgument) *object.Error {
+	klass := instance.Klass
+
+	// new(Person, {name: "x"}) passes the fields as a hash
+	if len(args) == 1 {
+		if hash, ok := args[0].(*object.Hash); ok {
+			for _, pair := range hash.SortedPairs() {
+				named = append(named, namedArgument{name: pair.Key.Inspect(), value: pair.Value})
+			}
+			args = nil
+		}
+	}
 
+	if len(args) > 0 {
+		return newKindError(object.ARGUMENT_ERROR, "%s has no init method, fields must be passed by name", klass.Name.Value)
+	}
+
+	for _, arg := range named {
+		if field, _ := klass.LookupField(arg.name); field == nil {
+			return newKindError(object.ARGUMENT_ERROR, "%s has no field %s", klass.Name.Value, arg.name)
+		}
+
+		instance.Fields[arg.name] = arg.value
+	}
+
+	return nil
+}
+
+// zeroValue is the value of an uninitialized field of the given type.
+func zeroValue(typ *ast.Identifier) object.Object {
+	switch typ.Value {
+	case "Int":
+		return &object.Integer{Value: 0}
+	case "String":
+		return &object.String{Value: ""}
+	case "Bool":
+		return FALSE
+	default:
+		return NIL
+	}
+}
+
+func evalDotExpression(node *ast.DotExpression, env *object.Environment) object.Object {
+	receiver := Eval(node.Left, env)
+	if isError(receiver) {
 		return receiver
-	case *ast.ClassExpression:
-		klass := &object.Class{Name: node.Name, Fields: node.Fields, Methods: node.Methods, Env: env}
+	}
 
-		env.Set(node.Name.Value, klass)
+	return evalMember(receiver, node.Right, env)
+}
 
-		return klass
-	case *ast.NewExpression:
-		// create new object from class
-		klass := Eval(node.What, env).(*object.Class)
-		hash := Eval(node.Data, env)
+// evalMember returns the member of receiver, the left side of a dot
+// expression evaluated in scope.
+func evalMember(receiver object.Object, member *ast.Identifier, scope object.Scope) object.Object {
+	switch receiver := receiver.(type) {
+	case *object.ClassInstance:
+		return evalInstanceMember(receiver, receiver.Klass, member, scope)
+	case *object.Super:
+		return evalInstanceMember(receiver.Instance, receiver.Klass, member, scope)
+	case *object.Exception:
+		return evalExceptionMember(receiver, member)
+	case *object.Enum:
+		return evalEnumMember(receiver, member)
+	case *object.EnumValue:
+		return evalEnumValueMember(receiver, member)
+	case *object.Module:
+		return evalModuleMember(receiver, member)
+	}
 
-		var objectFields = make(map[string]object.Object)
+	return NIL
+}
 
-		switch hash := hash.(type) {
-		case *object.Hash:
-			for key := range klass.Fields {
-				keyObj := object.String{Value: key}
+// evalInstanceMember looks up a member of instance starting at klass, which
+// is the class of the instance or, for `super`, one of its superclasses.
+func evalInstanceMember(instance *object.ClassInstance, klass *object.Class, member *ast.Identifier, scope object.Scope) object.Object {
+	// lookup field
+	if field, declaredBy := klass.LookupField(member.Value); field != nil {
+		if !field.Public && !canAccessPrivate(declaredBy, scope) {
+			return newPrivateAccessError(declaredBy, "field", member)
+		}
 
-				if pair, ok := hash.Pairs[keyObj.HashKey()]; ok {
-					objectFields[key] = pair.Value
-				} else {
-					objectFields[key] = &object.String{Value: "<default value>"}
-				}
+		if val, ok := instance.Fields[member.Value]; ok {
+			return val
+		}
+	}
+
+	// then lookup method for method call
+	if method, definedBy := klass.LookupMethod(member.Value); method != nil {
+		if !method.Public && !canAccessPrivate(definedBy, scope) {
+			return newPrivateAccessError(definedBy, "method", member)
+		}
+
+		return bindMethod(instance, method, definedBy)
+	}
+
+	return newKindError(object.NAME_ERROR, "%s has no member %s", klass.Name.Value, member.Value)
+}
+
+// canAccessPrivate reports whether code running in scope may use the private
+// members declared by klass, which is only the case within methods of klass
+// and its subclasses.
+func canAccessPrivate(klass *object.Class, scope object.Scope) bool {
+	self, ok := scope.Get("self")
+	if !ok {
+		return false
+	}
+
+	selfInstance, ok := self.(*object.ClassInstance)
+
+	return ok && selfInstance.Klass.IsA(klass)
+}
+
+func newPrivateAccessError(klass *object.Class, kind string, member *ast.Identifier) *object.Error {
+	return newKindError(object.ACCESS_ERROR, "cannot access private %s %s of %s outside the class",
+		kind, member.Value, klass.Name.Value)
+}
+
+// evalExceptionMember returns e.message, e.kind, e.value or e.stack of a
+// caught error.
+func evalExceptionMember(ex *object.Exception, member *ast.Identifier) object.Object {
+	switch member.Value {
+	case "message":
+		return &object.String{Value: ex.Error.Message}
+	case "kind":
+		return &object.String{Value: ex.Error.Kind}
+	case "value":
+		if ex.Error.Value == nil {
+			return NIL
+		}
+		return ex.Error.Value
+	case "stack":
+		frames := []object.Object{}
+		for _, frame := range ex.Error.Frames() {
+			frames = append(frames, &object.String{Value: frame})
+		}
+		return &object.Array{Elements: frames}
+	default:
+		return newKindError(object.NAME_ERROR, "unknown error member: %s", member.Value)
+	}
+}
+
+// newThrownError turns the operand of a throw statement into an error. Caught
+// errors are thrown again with their kind and message, hashes may carry a
+// "kind" and "message", and any other value becomes the message.
+func newThrownError(val object.Object) *object.Error {
+	switch val := val.(type) {
+	case *object.Exception:
+		return &object.Error{Kind: val.Error.Kind, Message: val.Error.Message, Value: val.Error.Value}
+	case *object.String:
+		return &object.Error{Kind: object.ERROR, Message: val.Value, Value: val}
+	case *object.Hash:
+		err := &object.Error{Kind: object.ERROR, Message: val.Inspect(), Value: val}
+
+		if kind, ok := val.Pairs[(&object.String{Value: "kind"}).HashKey()]; ok {
+			err.Kind = kind.Value.Inspect()
+		}
+		if message, ok := val.Pairs[(&object.String{Value: "message"}).HashKey()]; ok {
+			err.Message = message.Value.Inspect()
+		}
+
+		return err
+	default:
+		return &object.Error{Kind: object.ERROR, Message: val.Inspect(), Value: val}
+	}
+}
+
+// evalTryExpression evaluates the try block, hands an error raised by it to
+// the catch block, and always runs the finally block. A return or error from
+// the finally block replaces the outcome of the other blocks.
+func evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(te.Block, env)
+
+	// a tail call in the block is made here, where its errors are caught
+	if rv, ok := result.(*object.ReturnValue); ok {
+		if val := finishTailCall(rv.Value); isError(val) {
+			result = val
+		} else {
+			result = &object.ReturnValue{Value: val}
+		}
+	}
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		if te.CatchParam != nil {
+			if err := declare(env, te.CatchParam, &object.Exception{Error: err}, false); err != nil {
+				return err
 			}
 		}
 
-		return &object.ClassInstance{Klass: klass, Name: node.What, Fields: objectFields, Env: env}
-	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		result = Eval(te.Catch, env)
+	}
+
+	if te.Finally != nil {
+		finally := Eval(te.Finally, env)
+		if interruptsBlock(finally) {
+			return finally
+		}
+	}
+
+	if result == nil {
+		return NIL
+	}
+
+	return result
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := lookup(env, target)
+		if !ok {
+			return newKindError(object.NAME_ERROR, "cannot assign to undeclared variable: %s", target.Value)
+		}
+
+		if isConst(env, target) {
+			return newKindError(object.TYPE_ERROR, "cannot assign to constant %s", target.Value)
+		}
+
+		val := evalAssignedValue(node, current, env)
 		if isError(val) {
 			return val
 		}
 
-		return &object.ReturnValue{Value: val}
-	case *ast.ImportStatement:
-		val := Eval(node.Name, env)
-		// fmt.Printf("Importing: '%s'\n", val.Inspect())
+		switch {
+		case target.Resolved:
+			env.AssignLocal(target.Depth, target.Slot, target.Value, val)
+		case target.Global:
+			env.Global().Assign(target.Value, val)
+		default:
+			env.Assign(target.Value, val)
+		}
+
+		return val
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+
+		return assignIndex(left, index, func(current object.Object) object.Object {
+			return evalAssignedValue(node, current, env)
+		})
+	case *ast.DotExpression:
+		receiver := Eval(target.Left, env)
+		if isError(receiver) {
+			return receiver
+		}
+
+		return assignField(receiver, target.Right, env, func(current object.Object) object.Object {
+			return evalAssignedValue(node, current, env)
+		})
+	default:
+		return newError("cannot assign to %s", node.Target.String())
+	}
+}
+
+// evalAssignedValue evaluates the right hand side of an assignment and, for
+// compound operators such as +=, combines it with the current value.
+func evalAssignedValue(node *ast.AssignExpression, current object.Object, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) || node.Operator == "=" {
+		return val
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
 
-		env.Set(val.Inspect(), val)
+	return evalInfixExpression(operator, current, val)
+}
+
+// assignIndex sets left[index] to the result of value, which is called with
+// the current element once the assignment is known to be valid.
+func assignIndex(left, index object.Object, value func(current object.Object) object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		if left.Frozen {
+			return newKindError(object.TYPE_ERROR, "cannot modify frozen array")
+		}
+
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "array index must be INTEGER, got %s", index.Type())
+		}
+
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newKindError(object.INDEX_ERROR, "index out of range: %d", idx.Value)
+		}
+
+		val := value(left.Elements[idx.Value])
+		if isError(val) {
+			return val
+		}
+
+		left.Elements[idx.Value] = val
+
+		return val
+	case *object.Hash:
+		if left.Frozen {
+			return newKindError(object.TYPE_ERROR, "cannot modify frozen hash")
+		}
+
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+		}
+
+		var current object.Object = NIL
+		if pair, ok := left.Pairs[key.HashKey()]; ok {
+			current = pair.Value
+		}
+
+		val := value(current)
+		if isError(val) {
+			return val
+		}
+
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
+
+		return val
+	default:
+		return newKindError(object.TYPE_ERROR, "index assignment not supported: %s", left.Type())
+	}
+}
+
+// assignField sets the field of receiver to the result of value, which is
+// called with the current value of the field once the assignment is known
+// to be allowed from code running in scope.
+func assignField(receiver object.Object, field *ast.Identifier, scope object.Scope, value func(current object.Object) object.Object) object.Object {
+	instance, ok := receiver.(*object.ClassInstance)
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "cannot assign field %s on %s", field.Value, receiver.Type())
+	}
+
+	declared, declaredBy := instance.Klass.LookupField(field.Value)
+	if declared == nil {
+		return newKindError(object.NAME_ERROR, "%s has no field %s", instance.Klass.Name.Value, field.Value)
+	}
+
+	if !declared.Public && !canAccessPrivate(declaredBy, scope) {
+		return newPrivateAccessError(declaredBy, "field", field)
+	}
+
+	current, ok := instance.Fields[field.Value]
+	if !ok {
+		current = NIL
+	}
+
+	val := value(current)
+	if isError(val) {
+		return val
+	}
+
+	instance.Fields[field.Value] = val
+
+	return val
+}
+
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		if stop := evalLoopBody(ws.Body, env); stop != nil {
+			return exitLoop(stop)
+		}
+	}
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	for _, name := range []*ast.Identifier{fs.Key, fs.Value} {
+		if name != nil && isConst(env, name) {
+			return newConstantError(name.Value)
+		}
+	}
+
+	stop := forEach(iterable, func(key, value object.Object) object.Object {
+		if fs.Key != nil {
+			bind(env, fs.Key, key)
+			bind(env, fs.Value, value)
+		} else if iterable.Type() == object.HASH_OBJ {
+			bind(env, fs.Value, key)
+		} else {
+			bind(env, fs.Value, value)
+		}
+
+		return evalLoopBody(fs.Body, env)
+	})
+
+	if stop != nil {
+		return exitLoop(stop)
 	}
 
 	return nil
 }
 
+// forEach calls fn with the index and element of arrays and strings, the key
+// and value of hashes, and the index and number of ranges. fn stops the
+// iteration by returning a non-nil object, which forEach returns.
+func forEach(iterable object.Object, fn func(key, value object.Object) object.Object) object.Object {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		for i, el := range iterable.Elements {
+			if stop := fn(&object.Integer{Value: int64(i)}, el); stop != nil {
+				return stop
+			}
+		}
+	case *object.Hash:
+		for _, pair := range iterable.SortedPairs() {
+			if stop := fn(pair.Key, pair.Value); stop != nil {
+				return stop
+			}
+		}
+	case *object.String:
+		for i, ch := range []rune(iterable.Value) {
+			if stop := fn(&object.Integer{Value: int64(i)}, &object.String{Value: string(ch)}); stop != nil {
+				return stop
+			}
+		}
+	case *object.Range:
+		for n := iterable.Start; n < iterable.End; n++ {
+			if stop := fn(&object.Integer{Value: n - iterable.Start}, &object.Integer{Value: n}); stop != nil {
+				return stop
+			}
+		}
+	default:
+		return newKindError(object.TYPE_ERROR, "cannot iterate over %s", iterable.Type())
+	}
+
+	return nil
+}
+
+// evalLoopBody runs one iteration and returns the object that ends the loop:
+// a break, a return or an error. It returns nil to keep looping.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) object.Object {
+	result := Eval(body, env)
+
+	if interruptsBlock(result) && result.Type() != object.CONTINUE_OBJ {
+		return result
+	}
+
+	return nil
+}
+
+// exitLoop returns the result of a loop that was ended by stop.
+func exitLoop(stop object.Object) object.Object {
+	if stop.Type() == object.BREAK_OBJ {
+		return nil
+	}
+
+	return stop
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
@@ -219,19 +932,32 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.TUPLE_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalTupleIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newKindError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
 	}
 }
 
+func evalTupleIndexExpression(tuple, index object.Object) object.Object {
+	elements := tuple.(*object.Tuple).Elements
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(elements)) {
+		return newKindError(object.INDEX_ERROR, "index %d out of range for tuple of %d values", idx, len(elements))
+	}
+
+	return elements[idx]
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
@@ -254,31 +980,182 @@ func evalArrayIndexExpression(left, index object.Object) object.Object {
 	return array.Elements[idx]
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// bindMethod returns the method of klass as a function value with self bound
+// to the receiver and super to the superclass of klass.
+func bindMethod(receiver *object.ClassInstance, method *ast.ClassMethod, klass *object.Class) object.Object {
+	def := method.Function
+	if klass.Compiled != nil {
+		return klass.Compiled.Method(receiver, def.Name.Value)
+	}
+
+	objectEnv := object.NewEnclosedEnvironment(klass.Env)
+	objectEnv.Set("self", receiver)
+
+	if klass.Super != nil {
+		objectEnv.Set("super", &object.Super{Instance: receiver, Klass: klass.Super})
+	}
+
+	fn := &object.Function{
+		Name:        klass.Name.Value + "." + def.Name.Value,
+		Parameters:  def.Parameters,
+		ReturnTypes: def.ReturnTypes,
+		Body:        def.Body,
+		Locals:      def.Locals,
+		Slots:       def.Slots,
+		Env:         objectEnv,
+	}
+	objectEnv.Set(def.Name.Value, fn)
+
+	return fn
+}
+
+// applyFunction calls fn with positional and named arguments. callPos is the
+// location of the call and is recorded in the stack trace of any error
+// raised by the callee.
+func applyFunction(fn object.Object, args []object.Object, named []namedArgument, callPos token.Position) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-
-		return unwrapReturnValue(evaluated)
+		return runFunction(fn, args, named, callPos)
 
 	case *object.Builtin:
+		if len(named) > 0 {
+			return newKindError(object.ARGUMENT_ERROR, "builtin functions take no named arguments, got %s", named[0].name)
+		}
+
 		return fn.Fn(args...)
+	case *object.EnumVariant:
+		return applyEnumVariant(fn, args, named)
+	case object.Callable:
+		if len(named) > 0 {
+			return newKindError(object.ARGUMENT_ERROR, "named arguments cannot be passed to compiled functions from evaluated code, got %s", named[0].name)
+		}
+
+		return fn.Call(args...)
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newKindError(object.TYPE_ERROR, "not a function: %s", fn.Type())
+	}
+
+}
+
+// extendFunctionEnv binds the arguments of a call to the parameters of fn
+// in a new environment. Named arguments fill the parameters after the
+// positional ones, parameters left out take their default value, and a
+// variadic last parameter collects the remaining positional arguments.
+func extendFunctionEnv(fn *object.Function, args []object.Object, named []namedArgument) (*object.Environment, *object.Error) {
+	params := fn.Parameters
+
+	var rest *ast.TypedField
+	if n := len(params); n > 0 && params[n-1].Variadic {
+		params, rest = params[:n-1], params[n-1]
+	}
+
+	if len(args) > len(params) && rest == nil {
+		return nil, newArityError(fn.DisplayName(), fn.Parameters, len(args)+len(named))
+	}
+
+	values := make([]object.Object, len(params))
+	copy(values, args)
+
+	for _, arg := range named {
+		idx := -1
+		for i, param := range params {
+			if param.Name.Value == arg.name {
+				idx = i
+			}
+		}
+
+		if idx < 0 {
+			return nil, newKindError(object.ARGUMENT_ERROR, "%s has no parameter %s", fn.DisplayName(), arg.name)
+		}
+
+		if values[idx] != nil {
+			return nil, newKindError(object.ARGUMENT_ERROR, "argument %s passed more than once", arg.name)
+		}
+
+		values[idx] = arg.value
+	}
+
+	var env *object.Environment
+	if fn.Locals != nil {
+		env = object.NewFunctionEnvironment(fn.Env, fn.Locals, fn.Slots)
+	} else {
+		env = object.NewEnclosedEnvironment(fn.Env)
+	}
+
+	for i, param := range params {
+		val := values[i]
+
+		if val == nil {
+			if param.Default == nil {
+				if len(named) == 0 {
+					return nil, newArityError(fn.DisplayName(), fn.Parameters, len(args))
+				}
+				return nil, newKindError(object.ARGUMENT_ERROR, "missing argument %s to %s", param.Name.Value, fn.DisplayName())
+			}
+
+			// defaults can refer to the parameters before them
+			val = Eval(param.Default, env)
+			if err, ok := val.(*object.Error); ok {
+				return nil, err
+			}
+		}
+
+		if err := bindParameter(env, fn, param, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if rest != nil {
+		elements := []object.Object{}
+		if len(args) > len(params) {
+			elements = append(elements, args[len(params):]...)
+		}
+
+		if err := bindParameter(env, fn, rest, &object.Array{Elements: elements}); err != nil {
+			return nil, err
+		}
 	}
 
+	return env, nil
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+// bindParameter sets the parameter of fn to val, checking the type of val
+// in strict mode.
+func bindParameter(env *object.Environment, fn *object.Function, param *ast.TypedField, val object.Object) *object.Error {
+	if Strict {
+		if err := checkArgument(fn.DisplayName(), param, val, fn.Env); err != nil {
+			return err
+		}
+	}
+
+	bind(env, param.Name, val)
+
+	return nil
+}
+
+func newArityError(name string, params []*ast.TypedField, got int) *object.Error {
+	return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to %s: got %d, want %s",
+		name, got, arity(params))
+}
 
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Name.Value, args[paramIdx])
+// arity describes how many arguments a function with params takes.
+func arity(params []*ast.TypedField) string {
+	required, max := 0, 0
+	for _, param := range params {
+		switch {
+		case param.Variadic:
+			return fmt.Sprintf("at least %d", required)
+		case param.Default == nil:
+			required++
+		}
+		max++
 	}
 
-	return env
+	if required == max {
+		return fmt.Sprint(required)
+	}
+	return fmt.Sprintf("%d to %d", required, max)
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -289,6 +1166,46 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
+// namedArgument is an evaluated `name: value` call argument.
+type namedArgument struct {
+	name  string
+	value object.Object
+}
+
+// evalArguments evaluates call arguments, separating positional arguments
+// from named ones, which are kept in source order.
+func evalArguments(exps []ast.Expression, env *object.Environment) ([]object.Object, []namedArgument, *object.Error) {
+	args := []object.Object{}
+	named := []namedArgument{}
+
+	for _, e := range exps {
+		if arg, ok := e.(*ast.NamedArgument); ok {
+			for _, other := range named {
+				if other.name == arg.Name.Value {
+					return nil, nil, newKindError(object.ARGUMENT_ERROR, "argument %s passed more than once", arg.Name.Value)
+				}
+			}
+
+			val := Eval(arg.Value, env)
+			if err, ok := val.(*object.Error); ok {
+				return nil, nil, err
+			}
+
+			named = append(named, namedArgument{name: arg.Name.Value, value: val})
+			continue
+		}
+
+		val := Eval(e, env)
+		if err, ok := val.(*object.Error); ok {
+			return nil, nil, err
+		}
+
+		args = append(args, val)
+	}
+
+	return args, named, nil
+}
+
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
@@ -305,8 +1222,7 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-
-	if val, ok := env.Get(node.Value); ok {
+	if val, ok := lookup(env, node); ok {
 		return val
 	}
 
@@ -314,7 +1230,41 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newKindError(object.NAME_ERROR, "identifier not found: %s", node.Value)
+}
+
+// lookup returns the value name is bound to in env, by the slot the
+// resolver found for it if it is the local of a function, and in the global
+// environment if the resolver found it is a global.
+func lookup(env *object.Environment, name *ast.Identifier) (object.Object, bool) {
+	switch {
+	case name.Resolved:
+		return env.GetLocal(name.Depth, name.Slot, name.Value)
+	case name.Global:
+		return env.Global().Get(name.Value)
+	}
+	return env.Get(name.Value)
+}
+
+// isConst reports whether the binding of name in env is a constant.
+func isConst(env *object.Environment, name *ast.Identifier) bool {
+	switch {
+	case name.Resolved:
+		return env.IsConstLocal(name.Depth, name.Slot, name.Value)
+	case name.Global:
+		return env.Global().IsConst(name.Value)
+	}
+	return env.IsConst(name.Value)
+}
+
+// bind binds name to val in env, in the slot the resolver found for it if
+// it is the local of a function.
+func bind(env *object.Environment, name *ast.Identifier, val object.Object) {
+	if name.Resolved {
+		env.SetLocal(name.Slot, name.Value, val)
+	} else {
+		env.Set(name.Value, val)
+	}
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
@@ -356,21 +1306,26 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	return &object.String{Value: leftVal + rightVal}
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
 }
 
 func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
@@ -385,6 +1340,9 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -394,8 +1352,10 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "..":
+		return &object.Range{Start: leftVal, End: rightVal}
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 
 }
@@ -414,7 +1374,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -433,7 +1393,7 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newKindError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
 
 	value := right.(*object.Integer).Value
@@ -448,7 +1408,7 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
-			return result.Value
+			return finishTailCall(result.Value)
 		case *object.Error:
 			return result
 		}
@@ -463,19 +1423,35 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
-		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
-				return result
-			}
+		if interruptsBlock(result) {
+			return result
 		}
 	}
 
 	return result
 }
 
+// interruptsBlock reports whether obj ends the evaluation of a block early:
+// a return, an error, or a break or continue.
+func interruptsBlock(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+
+	switch obj.Type() {
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
 func newError(format string, a ...any) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return newKindError(object.RUNTIME_ERROR, format, a...)
+}
+
+func newKindError(kind string, format string, a ...any) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 func isError(obj object.Object) bool {
@@ -484,3 +1460,114 @@ func isError(obj object.Object) bool {
 	}
 	return false
 }
+
+// evalDestructuring declares the variables of a destructuring statement
+// from the parts of val.
+func evalDestructuring(node *ast.DestructuringStatement, val object.Object, env *object.Environment) object.Object {
+	values, err := destructure(node.Kind, node.Names, val)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range node.Names {
+		if err := declare(env, name, values[i], false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destructure returns the parts of val bound to names by a destructuring
+// of the given kind. A tuple must have exactly as many values as there are
+// names, an array at least as many elements, and a hash every key.
+func destructure(kind token.TokenType, names []*ast.Identifier, val object.Object) ([]object.Object, *object.Error) {
+	values := make([]object.Object, len(names))
+
+	switch kind {
+	case token.LPAREN:
+		tuple, ok := val.(*object.Tuple)
+		if !ok {
+			return nil, newKindError(object.TYPE_ERROR, "cannot destructure %s as a tuple", val.Type())
+		}
+
+		if len(tuple.Elements) != len(names) {
+			return nil, newKindError(object.TYPE_ERROR, "cannot destructure %d values into %d names",
+				len(tuple.Elements), len(names))
+		}
+
+		copy(values, tuple.Elements)
+	case token.LBRACKET:
+		array, ok := val.(*object.Array)
+		if !ok {
+			return nil, newKindError(object.TYPE_ERROR, "cannot destructure %s as an array", val.Type())
+		}
+
+		if len(array.Elements) < len(names) {
+			return nil, newKindError(object.INDEX_ERROR, "cannot destructure %d elements into %d names",
+				len(array.Elements), len(names))
+		}
+
+		copy(values, array.Elements)
+	case token.LBRACE:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return nil, newKindError(object.TYPE_ERROR, "cannot destructure %s as a hash", val.Type())
+		}
+
+		for i, name := range names {
+			key := &object.String{Value: name.Value}
+
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return nil, newKindError(object.KEY_ERROR, "hash has no key %s", name.Value)
+			}
+
+			values[i] = pair.Value
+		}
+	}
+
+	return values, nil
+}
+
+// declare binds name to val in env, as a constant with a frozen value if
+// constant is set. Constants cannot be declared again, in env or in the
+// environments it encloses.
+func declare(env *object.Environment, name *ast.Identifier, val object.Object, constant bool) *object.Error {
+	if isConst(env, name) {
+		return newConstantError(name.Value)
+	}
+
+	if constant {
+		val = object.Freeze(val)
+	}
+
+	switch {
+	case name.Resolved && constant:
+		env.SetLocalConst(name.Slot, name.Value, val)
+	case constant:
+		env.SetConst(name.Value, val)
+	default:
+		bind(env, name, val)
+	}
+
+	return nil
+}
+
+// checkParameters reports a parameter of a function created in env that
+// is named after a constant, which it would shadow.
+func checkParameters(params []*ast.TypedField, env *object.Environment) *object.Error {
+	for _, param := range params {
+		if env.IsConst(param.Name.Value) {
+			err := newConstantError(param.Name.Value)
+			err.Pos = param.Name.Pos()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newConstantError(name string) *object.Error {
+	return newKindError(object.NAME_ERROR, "cannot redeclare constant %s", name)
+}
@@ -1,6 +1,9 @@
 package evaluator
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/emo-lang/emo/lexer"
@@ -405,3 +408,1327 @@ func TestArrayLiterals(t *testing.T) {
 	testIntegerObject(t, result.Elements[1], 4)
 	testIntegerObject(t, result.Elements[2], 6)
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+	}{
+		{"5 + true", "1:1"},
+		{"var a = 1\nvar b = a + foo", "2:13"},
+		{"if (1 > 0) {\n  -true\n}", "2:3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position. expected=%q, got=%q",
+				tt.expectedPos, errObj.Pos.String())
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `func inner() {
+  return missing
+}
+
+func outer() {
+  return inner()
+}
+
+outer()`
+
+	evaluated := testEval(input)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	expected := `  at inner (2:10)
+  at outer (6:10)
+  at <main> (9:1)
+`
+	if errObj.StackTrace() != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, errObj.StackTrace())
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch e { 2 }`, 1},
+		{`try { throw "boom" } catch e { 2 }`, 2},
+		{`try { throw "boom" } catch e { e.message }`, "boom"},
+		{`try { throw "boom" } catch e { e.kind }`, "Error"},
+		{`try { len(1) } catch e { e.kind }`, "TypeError"},
+		{`try { push(1) } catch e { e.kind }`, "ArgumentError"},
+		{`try { foo } catch e { e.kind }`, "NameError"},
+		{`try { 1 / 0 } catch e { e.message }`, "division by zero"},
+		{`try { throw {kind: "ParseError", message: "bad"} } catch e { e.kind + ": " + e.message }`, "ParseError: bad"},
+		{`try { throw 42 } catch e { e.value }`, 42},
+		{`try { try { throw "a" } catch e { throw e } } catch e { e.message }`, "a"},
+		{"var x = 0\ntry { x } finally { 5 }", 0},
+		{"func f() { try { return 1 } finally { 5 } }\nf()", 1},
+		{"func f() { try { return 1 } finally { return 2 } }\nf()", 2},
+		{`try { throw "a" } finally { 1 }`, "a"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestCaughtErrorStack(t *testing.T) {
+	input := `func fail() {
+  throw "boom"
+}
+
+try { fail() } catch e { e.stack }`
+
+	evaluated := testEval(input)
+
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []string{"at fail (2:3)", "at <main> (5:7)"}
+	if len(arr.Elements) != len(expected) {
+		t.Fatalf("wrong number of frames. got=%d", len(arr.Elements))
+	}
+
+	for i, frame := range expected {
+		if arr.Elements[i].Inspect() != frame {
+			t.Errorf("frame %d wrong. expected=%q, got=%q", i, frame, arr.Elements[i].Inspect())
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var i = 0\nwhile i < 5 { var i = i + 1 }\ni", 5},
+		{"var i = 0\nwhile true { var i = i + 1\nif i == 3 { break } }\ni", 3},
+		{"var s = 0\nfor x in [1, 2, 3] { var s = s + x }\ns", 6},
+		{"var s = 0\nfor i, x in [10, 20] { var s = s + i }\ns", 1},
+		{"var s = 0\nfor n in 1..5 { if n == 2 { continue }\nvar s = s + n }\ns", 8},
+		{"var s = 0\nfor k, v in {a: 1, b: 2} { var s = s + v }\ns", 3},
+		{"var s = \"\"\nfor k in {b: 1, a: 2} { var s = s + k }\nlen(s)", 2},
+		{"var n = 0\nfor c in \"abc\" { var n = n + 1 }\nn", 3},
+		{"func f() { for x in 0..10 { if x == 4 { return x } } }\nf()", 4},
+		{"var n = 0\nfor i in 0..3 { for j in 0..3 { if j == 1 { break }\nvar n = n + 1 } }\nn", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestHashIterationOrder(t *testing.T) {
+	input := `var keys = ""
+for k in {c: 3, a: 1, b: 2} { var keys = keys + k }
+keys`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "abc" {
+		t.Errorf("keys in wrong order. got=%q", str.Value)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var x = 1\nx = 2\nx", 2},
+		{"var x = 1\nx += 4\nx", 5},
+		{"var x = 10\nx -= 4\nx *= 2\nx /= 3\nx", 4},
+		{"var x = 1\nfunc f() { x = 5 }\nf()\nx", 5},
+		{"var x = 1\nfunc f() { var x = 2\nx = 3 }\nf()\nx", 1},
+		{"var a = [1, 2, 3]\na[1] = 9\na[1]", 9},
+		{"var a = [1, 2, 3]\nvar b = a\nb[0] += 10\na[0]", 11},
+		{"var h = {a: 1}\nh[\"a\"] += 1\nh[\"b\"] = 5\nh[\"a\"] + h[\"b\"]", 7},
+		{"class P { public var n: Int\nfunc inc() { self.n += 1 } }\nvar p = new(P, {n: 1})\np.inc()\np.inc()\np.n", 3},
+		{"y = 1", "cannot assign to undeclared variable: y"},
+		{"var a = [1]\na[3] = 1", "index out of range: 3"},
+		{"class P { var n: Int }\nvar p = new(P, {n: 1})\np.m = 2", "P has no field m"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPrivateMemberAccess(t *testing.T) {
+	class := `class Person {
+  public var name: String
+  var age: Int
+
+  func older?(other: Person) -> Bool {
+    return self.age > other.age
+  }
+
+  private func secret() {
+    return self.age
+  }
+
+  func reveal() {
+    return self.secret()
+  }
+}
+var a = new(Person, {name: "A", age: 40})
+var b = new(Person, {name: "B", age: 30})
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"a.name", "A"},
+		{"a.older?(b)", true},
+		{"a.reveal()", 40},
+		{"a.age", "cannot access private field age of Person outside the class"},
+		{"a.age = 1", "cannot access private field age of Person outside the class"},
+		{"a.secret()", "cannot access private method secret of Person outside the class"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(class + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Kind != object.ACCESS_ERROR {
+					t.Errorf("wrong error kind. got=%q", obj.Kind)
+				}
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestClassFieldDefaults(t *testing.T) {
+	class := `class Account {
+  public var owner: String
+  public var balance: Int
+  public var active: Bool
+  public var tags: Array
+  public var limit: Int = 100 * 2
+  public var history: Array = []
+}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"new(Account).owner", ""},
+		{"new(Account).balance", 0},
+		{"new(Account).active", false},
+		{"new(Account).tags", nil},
+		{"new(Account).limit", 200},
+		{"new(Account, {limit: 5}).limit", 5},
+		{"var a = new(Account)\nvar b = new(Account)\na.history = push(a.history, 1)\nlen(b.history)", 0},
+		{"new(Account, {owner: \"x\", age: 3})", "Account has no field age"},
+		{"new(Account).missing", "Account has no member missing"},
+		{"var x = 1\nnew(x)", "cannot create an instance of INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(class + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case nil:
+			testNilObject(t, evaluated)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestClassInit(t *testing.T) {
+	class := `class Person {
+  public var name: String
+  public var age: Int
+  public var adult: Bool
+
+  func init(name: String, age: Int) {
+    if age < 0 {
+      throw "age must not be negative"
+    }
+
+    self.name = name
+    self.age = age
+    self.adult = age > 17
+  }
+}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`new(Person, "Ann", 30).age`, 30},
+		{`new(Person, "Ann", 30).adult`, true},
+		{`new(Person, age: 12, name: "Bob").adult`, false},
+		{`new(Person, "Bob", age: 12).name`, "Bob"},
+		{`new(Person, "Bob", -1)`, "age must not be negative"},
+		{`new(Person, "Bob", old: 1)`, "Person.init has no parameter old"},
+		{`new(Person, "Bob", name: "x")`, "argument name passed more than once"},
+		{`new(Person, age: 3)`, "missing argument name to Person.init"},
+		{"class P { public var x: Int }\nnew(P, x: 5).x", 5},
+		{"class P { public var x: Int }\nnew(P, 5)", "P has no init method, fields must be passed by name"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(class + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestClassInheritance(t *testing.T) {
+	classes := `class User {
+  public var name: String
+  var role: String = "user"
+
+  func init(name: String) {
+    self.name = name
+  }
+
+  func describe() -> String {
+    return self.name + " is a " + self.role
+  }
+}
+
+class Admin extends User {
+  public var level: Int
+
+  func init(name: String, level: Int) {
+    super.init(name)
+    self.level = level
+    self.role = "admin"
+  }
+
+  func describe() -> String {
+    return super.describe() + "!"
+  }
+}
+
+class Guest extends User {}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`new(Admin, "Ann", 2).level`, 2},
+		{`new(Admin, "Ann", 2).name`, "Ann"},
+		{`new(Admin, "Ann", 2).describe()`, "Ann is a admin!"},
+		{`new(Guest, "Bob").describe()`, "Bob is a user"},
+		{`is_a?(new(Admin, "Ann", 2), User)`, true},
+		{`is_a?(new(Guest, "Bob"), Admin)`, false},
+		{`is_a?(1, User)`, false},
+		{`new(Admin, "Ann", 2).role`, "cannot access private field role of User outside the class"},
+		{"var x = 1\nclass Bad extends x {}", "Bad cannot extend INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(classes + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestInterfaces(t *testing.T) {
+	interfaces := `interface Greeter {
+  func greet(name: String) -> String
+}
+
+class Person implements Greeter {
+  func greet(name: String) -> String {
+    return "hello " + name
+  }
+}
+
+class Student extends Person {}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`new(Person).greet("bob")`, "hello bob"},
+		{`is_a?(new(Person), Greeter)`, true},
+		{`is_a?(new(Student), Greeter)`, true},
+		{`class Other {}` + "\n" + `is_a?(new(Other), Greeter)`, false},
+		{`class Heir extends Person implements Greeter {}` + "\n" + `new(Heir).greet("ann")`, "hello ann"},
+		{`class Rock implements Greeter {}`, "Rock does not implement Greeter: missing method greet"},
+		{`class Rock implements Greeter {
+  private func greet(name: String) -> String { return name }
+}`, "Rock does not implement Greeter: method greet is private"},
+		{`class Rock implements Greeter {
+  func greet() -> String { return "" }
+}`, "Rock does not implement Greeter: method greet takes 0 parameters, want 1"},
+		{`class Rock implements Greeter {
+  func greet(name: String) { return name }
+}`, "Rock does not implement Greeter: method greet returns nothing, want (String)"},
+		{`class Rock implements Greeter {
+  func greet(name: String) -> (String, Int) { return name }
+}`, "Rock does not implement Greeter: method greet returns (String, Int), want (String)"},
+		{`class Rock implements Person {}`, "Rock cannot implement CLASS"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(interfaces + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	Strict = true
+	defer func() { Strict = false }()
+
+	defs := `func add(a: Int, b: Int) -> Int { return a + b }
+func name(n: Int) -> String { return n }
+func nothing() -> Int {}
+func apply(f: Func, x: Any) { return f(x) }
+class User {}
+class Admin extends User {}
+interface Named {}
+func greet(u: User) -> Bool { return true }
+func named(n: Named) -> Bool { return true }
+func odd(w: Widget) {}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`add(1, 2)`, 3},
+		{`add(1, "2")`, "argument b to add must be Int, got String"},
+		{`add(true, 2)`, "argument a to add must be Int, got Bool"},
+		{`name(1)`, "name must return String, got Int"},
+		{`nothing()`, "nothing must return Int, got Nil"},
+		{`apply(len, "abc")`, 3},
+		{`apply(1, 2)`, "argument f to apply must be Func, got Int"},
+		{`greet(new(Admin))`, true},
+		{`greet(new(User))`, true},
+		{`greet([1])`, "argument u to greet must be User, got Array"},
+		{`named(new(User))`, "argument n to named must be Named, got User"},
+		{`odd(1)`, "unknown type Widget"},
+		{`func(x: String) { return x }(1)`, "argument x to <anonymous> must be String, got Int"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(defs + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if err.Kind != object.TYPE_ERROR || err.Message != expected {
+				t.Errorf("wrong error. expected=%s %q, got=%s %q", object.TYPE_ERROR, expected, err.Kind, err.Message)
+			}
+		}
+	}
+}
+
+func TestNonStrictModeIgnoresAnnotations(t *testing.T) {
+	evaluated := testEval(`func name(n: Int) -> String { return n }
+name(1)`)
+
+	testIntegerObject(t, evaluated, 1)
+}
+
+func TestFunctionArguments(t *testing.T) {
+	defs := `func sub(a: Int, b: Int) { return a - b }
+func inc(n: Int, by: Int = 1) { return n + by }
+func span(from: Int, to: Int = from + 10) { return to - from }
+func count(first: Int, ...rest: Array) { return first + len(rest) }
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`sub(5, 2)`, 3},
+		{`sub(b: 2, a: 5)`, 3},
+		{`sub(5, b: 2)`, 3},
+		{`inc(1)`, 2},
+		{`inc(1, 5)`, 6},
+		{`inc(by: 3, n: 1)`, 4},
+		{`span(5)`, 10},
+		{`count(10)`, 10},
+		{`count(10, 1, 2, 3)`, 13},
+		{`sub(1)`, "wrong number of arguments to sub: got 1, want 2"},
+		{`sub(1, 2, 3)`, "wrong number of arguments to sub: got 3, want 2"},
+		{`inc()`, "wrong number of arguments to inc: got 0, want 1 to 2"},
+		{`count()`, "wrong number of arguments to count: got 0, want at least 1"},
+		{`sub(a: 1)`, "missing argument b to sub"},
+		{`sub(1, c: 2)`, "sub has no parameter c"},
+		{`sub(1, a: 2)`, "argument a passed more than once"},
+		{`sub(1, b: 2, b: 3)`, "argument b passed more than once"},
+		{`len(value: "x")`, "builtin functions take no named arguments, got value"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(defs + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if err.Kind != object.ARGUMENT_ERROR || err.Message != expected {
+				t.Errorf("wrong error. expected=%s %q, got=%s %q", object.ARGUMENT_ERROR, expected, err.Kind, err.Message)
+			}
+		}
+	}
+}
+
+func TestTuplesAndDestructuring(t *testing.T) {
+	defs := `func divmod(a: Int, b: Int) -> (Int, Int) {
+  return a / b, a - (a / b) * b
+}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var (q, r) = divmod(17, 5)\nq * 10 + r", 32},
+		{"divmod(17, 5)[1]", 2},
+		{"var [a, b] = [1, 2, 3]\na + b", 3},
+		{"var {name, age} = {name: \"ann\", age: 30}\nage", 30},
+		{"var (a, b, c) = divmod(1, 1)", "cannot destructure 2 values into 3 names"},
+		{"var (a, b) = [1, 2]", "cannot destructure ARRAY as a tuple"},
+		{"var [a, b] = [1]", "cannot destructure 1 elements into 2 names"},
+		{"var [a] = 1", "cannot destructure INTEGER as an array"},
+		{"var {name, age} = {name: \"ann\"}", "hash has no key age"},
+		{"var {name} = [1]", "cannot destructure ARRAY as a hash"},
+		{"divmod(1, 1)[2]", "index 2 out of range for tuple of 2 values"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(defs + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if err.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, err.Message)
+			}
+		}
+	}
+
+	tuple, ok := testEval(defs + "divmod(7, 2)").(*object.Tuple)
+	if !ok {
+		t.Fatalf("object is not Tuple")
+	}
+
+	if tuple.Inspect() != "(3, 1)" {
+		t.Errorf("tuple.Inspect() wrong. got=%q", tuple.Inspect())
+	}
+}
+
+func TestStrictMultipleReturnValues(t *testing.T) {
+	Strict = true
+	defer func() { Strict = false }()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`func f() -> (Int, String) { return 1, "x" }
+f()`, ""},
+		{`func f() -> (Int, String) { return 1, 2 }
+f()`, "f must return String, got Int"},
+		{`func f() -> (Int, String) { return 1 }
+f()`, "f must return 2 values, got Int"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		err, isErr := evaluated.(*object.Error)
+		switch {
+		case tt.expected == "" && isErr:
+			t.Errorf("unexpected error for %q: %s", tt.input, err.Message)
+		case tt.expected != "" && !isErr:
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+		case tt.expected != "" && err.Message != tt.expected:
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, err.Message)
+		}
+	}
+}
+
+func TestEnumsAndMatch(t *testing.T) {
+	defs := `enum Status {
+  Active
+  Suspended(reason: String, days: Int)
+}
+func describe(s: Status) {
+  return match s {
+    Status.Active -> "active"
+    Status.Suspended(reason, 0) -> reason
+    Status.Suspended(_, days) -> { "suspended for " + days }
+  }
+}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`describe(Status.Active)`, "active"},
+		{`describe(Status.Suspended("late", 0))`, "late"},
+		{`Status.Suspended("late", 3).days`, 3},
+		{`Status.Active == Status.Active`, true},
+		{`is_a?(Status.Active, Status)`, true},
+		{`match 2 { 1 -> "one", 2 -> "two", _ -> "many" }`, "two"},
+		{`match -1 { -1 -> "minus one", n -> n }`, "minus one"},
+		{`match 5 { 1 -> 1, n -> n * 2 }`, 10},
+		{`match [1, 2, 3] { [] -> 0, [x] -> x, [x, ...rest] -> len(rest) }`, 2},
+		{`match [1, 2] { [1, x] -> x, _ -> 0 }`, 2},
+		{`match {name: "ann", age: 30} { {name: "bob"} -> 0, {age} -> age }`, 30},
+		{`match true { false -> 0, true -> 1 }`, 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(defs + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if str.Value != expected {
+				t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, str.Value)
+			}
+		}
+	}
+
+	value := testEval(defs + `Status.Suspended("late", 3)`)
+	if value.Inspect() != "Status.Suspended(late, 3)" {
+		t.Errorf("value.Inspect() wrong. got=%q", value.Inspect())
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	defs := `enum Status { Active, Suspended(reason: String) }
+`
+
+	tests := []struct {
+		input    string
+		kind     string
+		expected string
+	}{
+		{`match Status.Suspended("x") { Status.Active -> 1 }`, object.MATCH_ERROR, "match on Status is not exhaustive: no arm matches Status.Suspended"},
+		{`match 3 { 1 -> 1 }`, object.MATCH_ERROR, "no arm matches 3"},
+		{`Status.Closed`, object.NAME_ERROR, "Status has no variant Closed"},
+		{`Status.Suspended()`, object.ARGUMENT_ERROR, "wrong number of arguments to Status.Suspended: got 0, want 1"},
+		{`Status.Suspended("x").days`, object.NAME_ERROR, "Status.Suspended has no field days"},
+		{`match 1 { Status.Suspended(a, b) -> 1 }`, object.TYPE_ERROR, "Status.Suspended has 1 fields, pattern has 2"},
+		{`var n = 1
+match 1 { n.Active -> 1 }`, object.TYPE_ERROR, "n is not an enum"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(defs + tt.input)
+
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if err.Kind != tt.kind || err.Message != tt.expected {
+			t.Errorf("wrong error for %q. expected=%s: %q, got=%s: %q", tt.input, tt.kind, tt.expected, err.Kind, err.Message)
+		}
+	}
+}
+
+func TestStrictEnums(t *testing.T) {
+	Strict = true
+	defer func() { Strict = false }()
+
+	defs := `enum Status { Active, Suspended(reason: String) }
+func f(s: Status) { return 1 }
+`
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Status.Suspended(1)`, "argument reason to Status.Suspended must be String, got Int"},
+		{`f(1)`, "argument s to f must be Status, got Int"},
+	}
+
+	for _, tt := range tests {
+		err, ok := testEval(defs + tt.input).(*object.Error)
+		if !ok || err.Message != tt.expected {
+			t.Errorf("wrong result for %q. expected error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	testIntegerObject(t, testEval(defs+`f(Status.Active)`), 1)
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const MAX = 3\nMAX * 2", 6},
+		{"define(MAX, 3)\nfunc f() { return MAX }\nf()", 3},
+		// functions may declare names that shadow variables, not constants
+		{"var n = 1\nfunc f() { var n = 2\nreturn n }\nf() + n", 3},
+		{"const MAX = 3\nfunc f(MAX: Int) { return MAX }\nf(4)", "cannot redeclare constant MAX"},
+		{"const MAX = 3\nvar f = func(x: Int, MAX: Int) { return MAX }", "cannot redeclare constant MAX"},
+		{"const MAX = 3\nclass A { func get(MAX: Int) { return MAX } }", "cannot redeclare constant MAX"},
+		{"func f(MAX: Int) { return MAX }\nconst MAX = 3\nf(4)", 4},
+		{"const MAX = 3\nMAX = 4", "cannot assign to constant MAX"},
+		{"define(MAX, 3)\nMAX += 1", "cannot assign to constant MAX"},
+		{"define(MAX, 3)\nvar MAX = 99", "cannot redeclare constant MAX"},
+		{"define(MAX, 3)\ndefine(MAX, 4)", "cannot redeclare constant MAX"},
+		{"const MAX = 3\nfunc f() { var MAX = 4 }\nf()", "cannot redeclare constant MAX"},
+		{"const MAX = 3\nfunc MAX() {}", "cannot redeclare constant MAX"},
+		{"const P = 1\nclass P {}", "cannot redeclare constant P"},
+		{"define(G, 2)\ninterface G { func f() }", "cannot redeclare constant G"},
+		{"const E = 3\nenum E { A }", "cannot redeclare constant E"},
+		{"func f() { const E = 3\nenum E { A } }\nf()", "cannot redeclare constant E"},
+		{"const X = 3\nfor X in 0..2 {}", "cannot redeclare constant X"},
+		{"const X = 3\nvar [X] = [1]", "cannot redeclare constant X"},
+		{"const X = 3\nmatch 1 { X -> X }", "cannot redeclare constant X"},
+		{"const XS = [1, [2]]\nXS[0] = 5", "cannot modify frozen array"},
+		{"const XS = [1, [2]]\nXS[1][0] = 5", "cannot modify frozen array"},
+		{"const H = {a: {b: 1}}\nH[\"a\"][\"b\"] = 2", "cannot modify frozen hash"},
+		// constants hold frozen copies, the values they are declared from
+		// stay mutable
+		{"var xs = [1]\nconst XS = xs\nxs[0] = 2\nxs[0] + XS[0]", 3},
+		{"var h = {a: 1}\nconst H = h\nh[\"a\"] = 2\nh[\"a\"] + H[\"a\"]", 3},
+		{"var inner = [1]\nconst W = [inner]\ninner[0] = 2\ninner[0] + W[0][0]", 3},
+		{"var xs = [1]\nconst XS = xs\nXS[0] = 2", "cannot modify frozen array"},
+		{"const XS = [1]\nvar ys = push(XS, 2)\nys[0] = 5\nys[0] + XS[0]", 6},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if err.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, err.Message)
+			}
+		}
+	}
+}
+
+func TestResolvedLocals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"func f(a: Int, b: Int = a * 2) { var c = a + b\nreturn c }\nf(1)", 3},
+		{"func counter() { var n = 0\nreturn func() { n += 1\nreturn n } }\nvar c = counter()\nc()\nc()", 2},
+		{"func f(n: Int) { func even(n: Int) { if n == 0 { return 1 }\nreturn odd(n - 1) }\nfunc odd(n: Int) { if n == 0 { return 0 }\nreturn even(n - 1) }\nreturn even(n) }\nf(10)", 1},
+		{"func f() { var s = 0\nfor i, x in [4, 5] { s += i * x }\nreturn s }\nf()", 5},
+		{"func f(x: Int) { return match [x, 2] { [a, b] -> a * b } }\nf(3)", 6},
+		{"func f(n: Int) { class A { var k: Int = n\nfunc get(m: Int) { return self.k + n + m } }\nreturn new(A).get(1) }\nf(2)", 5},
+		// a closure called before the function declares the local sees the
+		// variable further out
+		{"var x = 1\nfunc f() { func g() { return x }\nvar y = g()\nvar x = 10\nreturn y + g() }\nf()", 11},
+		{"func f() { const K = 1\nK = 2 }\nf()", "cannot assign to constant K"},
+		{"func f() { const K = 1\nvar K = 2 }\nf()", "cannot redeclare constant K"},
+		{"func f() { print(x)\nvar x = 1 }", "variable x used before declaration"},
+		{"func f() { var n = n + 1 }", "variable n used before declaration"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if err.Message != expected {
+				t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, err.Message)
+			}
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"func count(n: Int, acc: Int) { if n == 0 { return acc }\nreturn count(n - 1, acc + 1) }\ncount(100000, 0)", 100000},
+		{"func even(n: Int) { if n == 0 { return 1 }\nreturn odd(n - 1) }\nfunc odd(n: Int) { if n == 0 { return 0 }\nreturn even(n - 1) }\neven(100001)", 0},
+		{"func f() { return len([1, 2]) }\nf()", 2},
+		{"func g() { throw \"boom\" }\nfunc f() { try { return g() } catch e { return 1 } }\nf()", 1},
+		{"func down(n: Int) { if n == 0 { return 0 }\nreturn 1 + down(n - 1) }\ndown(20000)", "maximum call depth of 10000 exceeded"},
+		{"func down(n: Int) { if n == 0 { return 0 }\nreturn 1 + down(n - 1) }\ntry { down(20000) } catch e { e.kind }", "RecursionError"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string for %q. expected=%q, got=%q", tt.input, expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestTailCallStackTrace(t *testing.T) {
+	input := `func loop(n: Int) {
+  if n == 0 { return missing }
+  return loop(n - 1)
+}
+
+loop(3)`
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+
+	expected := `  at loop (2:22)
+  at loop (3:10)
+  ... repeated 2 more times
+  at <main> (6:1)
+`
+	if errObj.StackTrace() != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, errObj.StackTrace())
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := t.TempDir()
+	path := t.TempDir()
+	t.Setenv("EMO_PATH", path)
+
+	files := map[string]string{
+		filepath.Join(dir, "lib", "strings.emo"): `import helpers
+var loads = 0
+func slugify(s: String) -> String { return helpers.dash(s) + _suffix() }
+func _suffix() { return "!" }`,
+		filepath.Join(dir, "lib", "helpers.emo"): `func dash(s: String) -> String { return "-" + s }`,
+		filepath.Join(dir, "lib", "broken.emo"):  `var x = 1 + true`,
+		filepath.Join(dir, "a.emo"):              `import b`,
+		filepath.Join(dir, "b.emo"):              `import a`,
+		filepath.Join(path, "utils.emo"):         `func twice(n: Int) -> Int { return n * 2 }`,
+	}
+
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "lib/strings.emo"
+strings.slugify("a")`, "-a!"},
+		{`import "lib/strings" as text
+text.slugify("b")`, "-b!"},
+		{`import utils
+utils.twice(4)`, 8},
+		// modules are evaluated once and shared by their importers
+		{`import "lib/strings.emo"
+import "lib/strings.emo" as again
+strings == again`, true},
+		{`import "lib/strings.emo"
+strings.loads = 1`, "cannot assign field loads on MODULE"},
+		{`import "lib/strings.emo"
+strings._suffix()`, "cannot access private name _suffix of module strings"},
+		{`import "lib/strings.emo"
+strings.nope`, "module strings has no member nope"},
+		{`import missing`, "cannot find module missing in " + dir + ", " + path},
+		{`import "lib/broken.emo"`, "type mismatch: INTEGER + BOOLEAN"},
+		{`import a`, "import cycle: a.emo -> b.emo -> a.emo"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.NewFile(filepath.Join(dir, "main.emo"), tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors in %q: %v", tt.input, p.Errors())
+		}
+
+		evaluated := Eval(program, object.NewEnvironment())
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, result.Message)
+				}
+			default:
+				t.Errorf("unexpected result for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+
+	if _, ok := modules[filepath.Join(dir, "lib", "strings.emo")]; !ok {
+		t.Errorf("module strings is not cached")
+	}
+}
+
+func TestStdlibModules(t *testing.T) {
+	imports := "import strings\nimport math\nimport collections\n"
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`strings.join(strings.split("a,b,c", ","), "-")`, "a-b-c"},
+		{`strings.trim("  hi ")`, "hi"},
+		{`strings.replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`strings.upper("hi") + strings.lower("HI")`, "HIhi"},
+		{`strings.contains("hello", "ell")`, true},
+		{`strings.format("{} is {}", "ann", 30)`, "ann is 30"},
+		{`strings.format("{} and {}", 1)`, "strings.format: format has 2 placeholders, got 1 values"},
+		{`strings.upper(1)`, "argument 1 to strings.upper must be STRING, got INTEGER"},
+		{`strings.split("a")`, "wrong number of arguments to strings.split: got 1, want 2"},
+		{`math.abs(-3) + math.min(5, 2, 9) + math.max(1, 4)`, 9},
+		{`math.pow(2, 10)`, 1024},
+		{`math.pow(2, -1)`, "math.pow: negative exponent -1"},
+		{`math.sqrt(17)`, 4},
+		{`math.sqrt(9223372036854775807)`, 3037000499},
+		{`math.sqrt(2)`, 1},
+		{`math.sqrt(0)`, 0},
+		{`math.pow(-2, 63) == -9223372036854775807 - 1`, true},
+		{`math.pow(3, 64)`, "math.pow: 3 to the power 64 overflows"},
+		{`math.pow(2, 63)`, "math.pow: 2 to the power 63 overflows"},
+		{`math.abs(math.pow(-2, 63))`, "math.abs: the absolute value of -9223372036854775808 overflows"},
+		{`math.floor(-7, 2)`, -4},
+		{`math.floor(7, 2)`, 3},
+		{`math.seed(7)
+var a = math.random(1000)
+math.seed(7)
+a == math.random(1000)`, true},
+		{`math.random(0)`, "math.random: argument must be positive, got 0"},
+		{`len(collections.map([1, 2, 3], func(x: Int) { return x * 2 }))`, 3},
+		{`collections.reduce(collections.map([1, 2, 3], func(x: Int) { return x * 2 }), func(a: Int, b: Int) { return a + b }, 0)`, 12},
+		{`len(collections.filter([1, 2, 3, 4], func(x: Int) { return x > 2 }))`, 2},
+		{`collections.map([1], func(x: Int) { return x + "a" })`, "type mismatch: INTEGER + STRING"},
+		{`first(collections.sort([3, 1, 2]))`, 1},
+		{`first(collections.sort([1, 3, 2], func(a: Int, b: Int) { return a > b }))`, 3},
+		{`collections.sort([1, "a"])`, "collections.sort: cannot compare STRING and INTEGER without a function"},
+		{`len(collections.zip([1, 2], [3, 4, 5]))`, 2},
+		{`last(first(collections.zip([1, 2], [3, 4, 5])))`, 3},
+		{`len(collections.unique([1, 2, 1, "a", "a"]))`, 3},
+		{`first(collections.keys({b: 1, a: 2}))`, "a"},
+		{`first(collections.values({b: 1, a: 2}))`, 2},
+		{`var h = {a: 1, b: 2}
+collections.delete(h, "a") + len(collections.keys(h))`, 2},
+		{`const H = {a: 1}
+collections.delete(H, "a")`, "cannot modify frozen hash"},
+		{`strings.nope("a")`, "module strings has no member nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(imports + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, result.Message)
+				}
+			default:
+				t.Errorf("unexpected result for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestOSAndFSModules(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	if err := os.MkdirAll(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(dir, "secret.txt"):  "secret",
+		filepath.Join(dir, "secret.emo"):  "var token = 42",
+		filepath.Join(dir, "notes.emo"):   "password: hunter2",
+		filepath.Join(data, "helper.emo"): "var x = 1",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.txt"), filepath.Join(data, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "outside", "sub")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(sub, filepath.Join(data, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "outside", "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("EMO_TEST_VAR", "yes")
+
+	exitCode := -1
+	exitProcess = func(code int) { exitCode = code }
+	defer func() {
+		Caps = AllCapabilities()
+		Args = nil
+		exitProcess = os.Exit
+	}()
+
+	Args = []string{"a", "b"}
+
+	imports := "import os\nimport fs\n"
+	restricted := Capabilities{FS: true, FSRoots: []string{data}}
+
+	tests := []struct {
+		caps     Capabilities
+		input    string
+		expected interface{}
+	}{
+		{AllCapabilities(), `os.env("EMO_TEST_VAR")`, "yes"},
+		{AllCapabilities(), `os.env()["EMO_TEST_VAR"]`, "yes"},
+		{AllCapabilities(), `len(os.args())`, 2},
+		{AllCapabilities(), `fs.write_file("` + data + `/out.txt", "hi")
+fs.read_file("` + data + `/out.txt")`, "hi"},
+		{AllCapabilities(), `fs.exists?("` + data + `/out.txt")`, true},
+		{AllCapabilities(), `fs.exists?("` + data + `/nope.txt")`, false},
+		{AllCapabilities(), `len(fs.list_dir("` + data + `"))`, 4},
+		{AllCapabilities(), `fs.read_file("` + data + `/nope.txt")`, object.IO_ERROR},
+		{restricted, `fs.read_file("` + data + `/out.txt")`, "hi"},
+		{restricted, `fs.read_file("` + dir + `/secret.txt")`, object.PERMISSION_ERROR},
+		{restricted, `fs.read_file("` + data + `/../secret.txt")`, object.PERMISSION_ERROR},
+		// links cannot lead out of the allowed directories
+		{restricted, `fs.read_file("` + data + `/link.txt")`, object.PERMISSION_ERROR},
+		// `..` after a link leaves the directory it points to
+		{restricted, `fs.read_file("` + data + `/link/../secret.txt")`, object.PERMISSION_ERROR},
+		{restricted, `fs.write_file("` + data + `/link/../pwned.txt", "x")`, object.PERMISSION_ERROR},
+		{restricted, `fs.exists?("` + data + `/link/../sub")`, object.PERMISSION_ERROR},
+		{restricted, `fs.read_file("` + data + `/./out.txt")`, "hi"},
+		{restricted, `os.env("EMO_TEST_VAR")`, object.PERMISSION_ERROR},
+		{restricted, `os.exit(1)`, object.PERMISSION_ERROR},
+		{Capabilities{}, `fs.exists?("` + data + `")`, object.PERMISSION_ERROR},
+		// imports are restricted like the fs module
+		{restricted, `import "` + data + `/helper.emo"
+helper.x`, 1},
+		{restricted, `import "` + dir + `/secret.emo"
+secret.token`, object.PERMISSION_ERROR},
+		{restricted, `import "` + data + `/../secret"`, object.PERMISSION_ERROR},
+		{Capabilities{ImportRoots: []string{dir}}, `import "` + dir + `/secret.emo"
+secret.token`, 42},
+		{Capabilities{}, `import "` + data + `/helper.emo"`, object.PERMISSION_ERROR},
+	}
+
+	for _, tt := range tests {
+		Caps = tt.caps
+		evaluated := testEval(imports + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, result.Value)
+				}
+			case *object.Error:
+				if result.Kind != expected {
+					t.Errorf("wrong error for %q. expected=%s, got=%s: %s", tt.input, expected, result.Kind, result.Message)
+				}
+			default:
+				t.Errorf("unexpected result for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+
+	Caps = AllCapabilities()
+
+	// the errors of a module that does not parse give their positions
+	err, ok := testEval(`import "` + dir + `/notes.emo"`).(*object.Error)
+	if !ok || !strings.Contains(err.Message, dir+"/notes.emo:1:9: no prefix parse function for :") {
+		t.Errorf("wrong error for a file that does not parse. got=%+v", err)
+	}
+
+	testEval(imports + "os.exit(3)")
+	if exitCode != 3 {
+		t.Errorf("os.exit did not exit with 3. got=%d", exitCode)
+	}
+}
+
+func TestJSONModule(t *testing.T) {
+	classes := `import json
+class User {
+  public var name: String
+  public var tags: Array = []
+  var password: String = "secret"
+}
+`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`json.stringify({b: [1, true, "x"], a: {c: -2}})`, `{"a":{"c":-2},"b":[1,true,"x"]}`},
+		{`json.stringify(new(User, name: "ann"))`, `{"name":"ann","tags":[]}`},
+		{`json.stringify([1, [2]], true)`, "[\n  1,\n  [\n    2\n  ]\n]"},
+		{`json.parse("[1, null, false, -3]")[3]`, -3},
+		{`json.parse(json.stringify({a: [1, 2]}))["a"][1]`, 2},
+		{`json.parse(json.stringify(new(User, name: "ann")))["name"]`, "ann"},
+		{`json.parse("1.5")`, "json.parse: number 1.5 is not an integer"},
+		{`json.parse("[1, 2")`, "json.parse: unexpected EOF"},
+		{`json.parse("1 2")`, "json.parse: unexpected data after the value"},
+		{`json.stringify(len)`, "json.stringify: cannot serialize Func"},
+		{`var h = {"1": "a"}; h[1] = "b"; json.stringify(h)`, "json.stringify: cannot serialize a hash key of type Int"},
+		{`var h = {}; h[true] = 1; json.stringify(h)`, "json.stringify: cannot serialize a hash key of type Bool"},
+		{`json.stringify({a: "<b>&</b>"}, true)`, "{\n  \"a\": \"<b>&</b>\"\n}"},
+		{`var xs = [1]
+xs[0] = xs
+json.stringify(xs)`, "json.stringify: cannot serialize a value that contains itself"},
+		{`var u = new(User, name: "ann")
+json.stringify([u, u])`, `[{"name":"ann","tags":[]},{"name":"ann","tags":[]}]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(classes + tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, result.Message)
+				}
+			default:
+				t.Errorf("unexpected result for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			}
+		}
+	}
+}
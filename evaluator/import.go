@@ -0,0 +1,218 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/parser"
+)
+
+// modules caches the imported modules by the absolute path of their file,
+// so a module is evaluated once however many files import it.
+var modules = map[string]*object.Module{}
+
+// importing holds the absolute paths of the modules being evaluated, the
+// innermost last, to detect import cycles.
+var importing []string
+
+// evalImportStatement loads the module imported by node and binds it to
+// its namespace in env.
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	module, err := importModule(node)
+	if err != nil {
+		return err
+	}
+
+	if err := declare(env, &ast.Identifier{Token: node.Token, Value: node.Namespace()}, module, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// importModule returns the module of the standard library imported by
+// name, or else the module of the file imported by node.
+func importModule(node *ast.ImportStatement) (*object.Module, *object.Error) {
+	if _, isName := node.Name.(*ast.Identifier); isName {
+		if module, ok := loadStdModule(node.Path()); ok {
+			return module, nil
+		}
+	}
+
+	path, abs, err := findModule(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadModule(node, path, abs)
+}
+
+// findModule returns the path of the file imported by node and its
+// absolute path, which identifies the module.
+func findModule(node *ast.ImportStatement) (string, string, *object.Error) {
+	path, err := resolveImport(node)
+	if err != nil {
+		return "", "", err
+	}
+
+	abs, aerr := filepath.Abs(path)
+	if aerr != nil {
+		return "", "", newKindError(object.IMPORT_ERROR, "cannot import %s: %s", node.Path(), aerr)
+	}
+
+	return path, abs, nil
+}
+
+// resolveImport finds the file of an imported module, relative to the
+// directory of the importing file and then to each directory listed in
+// EMO_PATH. A path without extension refers to a .emo file. Files the
+// capabilities do not let the scripts import are skipped without looking
+// at them, and make an error if no other file is found.
+func resolveImport(node *ast.ImportStatement) (string, *object.Error) {
+	name := filepath.FromSlash(node.Path())
+	if filepath.Ext(name) == "" {
+		name += ".emo"
+	}
+
+	if filepath.IsAbs(name) {
+		if !canImport(name) {
+			return "", newImportPermissionError(node)
+		}
+		if isFile(name) {
+			return name, nil
+		}
+		return "", newKindError(object.IMPORT_ERROR, "cannot find module %s", node.Path())
+	}
+
+	dirs := append([]string{filepath.Dir(node.Pos().Filename)}, ModulePath()...)
+
+	denied := false
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if !canImport(path) {
+			denied = true
+			continue
+		}
+		if isFile(path) {
+			return path, nil
+		}
+	}
+
+	if denied {
+		return "", newImportPermissionError(node)
+	}
+
+	return "", newKindError(object.IMPORT_ERROR, "cannot find module %s in %s", node.Path(), strings.Join(dirs, ", "))
+}
+
+// ModulePath returns the directories listed in EMO_PATH, where imports look
+// for the modules that are not next to the importing file.
+func ModulePath() []string {
+	dirs := []string{}
+	for _, dir := range filepath.SplitList(os.Getenv("EMO_PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+func newImportPermissionError(node *ast.ImportStatement) *object.Error {
+	return newKindError(object.PERMISSION_ERROR, "import: access to module %s is not allowed", node.Path())
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// loadModule returns the module of the file at path, whose absolute path
+// is abs, evaluating the file in a new environment the first time it is
+// imported.
+func loadModule(node *ast.ImportStatement, path, abs string) (*object.Module, *object.Error) {
+	if module, ok := modules[abs]; ok {
+		return module, nil
+	}
+
+	if cycle := importCycle(importing, abs); cycle != nil {
+		return nil, cycle
+	}
+
+	program, err := parseModule(node, path)
+	if err != nil {
+		return nil, err
+	}
+
+	env := object.NewEnvironment()
+	module := &object.Module{Name: moduleName(path), Path: path, Env: env}
+
+	importing = append(importing, abs)
+	defer func() { importing = importing[:len(importing)-1] }()
+
+	if err, ok := Eval(program, env).(*object.Error); ok {
+		err.Stack = append(err.Stack, object.StackFrame{Function: "<module " + module.Name + ">", Call: node.Pos()})
+		return nil, err
+	}
+	modules[abs] = module
+
+	return module, nil
+}
+
+// importCycle returns the error of importing the module abs while the
+// modules importing, the innermost last, are being imported, or nil if
+// abs is not one of them.
+func importCycle(importing []string, abs string) *object.Error {
+	for i, p := range importing {
+		if p == abs {
+			cycle := []string{}
+			for _, p := range append(importing[i:], abs) {
+				cycle = append(cycle, filepath.Base(p))
+			}
+			return newKindError(object.IMPORT_ERROR, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	return nil
+}
+
+// parseModule parses the file at path imported by node.
+func parseModule(node *ast.ImportStatement, path string) (*ast.Program, *object.Error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, newKindError(object.IMPORT_ERROR, "cannot import %s: %s", node.Path(), err)
+	}
+
+	p := parser.New(lexer.NewFile(path, string(data)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newKindError(object.IMPORT_ERROR, "cannot import %s: %s", node.Path(), strings.Join(p.Errors(), "; "))
+	}
+
+	return program, nil
+}
+
+// moduleName is the name of the module of the file at path, the name of
+// the file without its extension.
+func moduleName(path string) string {
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// evalModuleMember returns the public top-level binding name of module.
+func evalModuleMember(module *object.Module, member *ast.Identifier) object.Object {
+	if object.IsPrivateName(member.Value) {
+		return newKindError(object.ACCESS_ERROR, "cannot access private name %s of module %s", member.Value, module.Name)
+	}
+
+	val, ok := module.Env.Get(member.Value)
+	if !ok {
+		return newKindError(object.NAME_ERROR, "module %s has no member %s", module.Name, member.Value)
+	}
+
+	return val
+}
@@ -0,0 +1,265 @@
+package evaluator
+
+import (
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+)
+
+func evalEnumExpression(node *ast.EnumExpression, env *object.Environment) object.Object {
+	enum := newEnum(node, env)
+	if err := declare(env, node.Name, enum, false); err != nil {
+		return err
+	}
+
+	return enum
+}
+
+// newEnum returns the enum declared by node, which looks up the types of
+// the fields of its variants in scope.
+func newEnum(node *ast.EnumExpression, scope object.Scope) *object.Enum {
+	enum := &object.Enum{Name: node.Name, Variants: map[string]*object.EnumVariant{}, Env: scope}
+
+	for _, v := range node.Variants {
+		variant := &object.EnumVariant{Enum: enum, Name: v.Name.Value, Fields: v.Fields}
+		if len(v.Fields) == 0 {
+			variant.Value = &object.EnumValue{Variant: variant}
+		}
+
+		enum.Variants[variant.Name] = variant
+		enum.Order = append(enum.Order, variant.Name)
+	}
+
+	return enum
+}
+
+// evalEnumMember returns the value of a variant without fields, or the
+// variant itself to be called with the values of its fields.
+func evalEnumMember(enum *object.Enum, member *ast.Identifier) object.Object {
+	variant, ok := enum.Variants[member.Value]
+	if !ok {
+		return newKindError(object.NAME_ERROR, "%s has no variant %s", enum.Name.Value, member.Value)
+	}
+
+	if variant.Value != nil {
+		return variant.Value
+	}
+
+	return variant
+}
+
+func evalEnumValueMember(value *object.EnumValue, member *ast.Identifier) object.Object {
+	if val := value.Field(member.Value); val != nil {
+		return val
+	}
+
+	return newKindError(object.NAME_ERROR, "%s has no field %s", value.Variant.Inspect(), member.Value)
+}
+
+// applyEnumVariant creates a value of variant from the values of its fields.
+func applyEnumVariant(variant *object.EnumVariant, args []object.Object, named []namedArgument) object.Object {
+	if len(named) > 0 {
+		return newKindError(object.ARGUMENT_ERROR, "enum variants take no named arguments, got %s", named[0].name)
+	}
+
+	if len(args) != len(variant.Fields) {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to %s: got %d, want %d",
+			variant.Inspect(), len(args), len(variant.Fields))
+	}
+
+	if Strict {
+		for i, field := range variant.Fields {
+			ok, err := hasType(args[i], field.Type, variant.Enum.Env)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return newKindError(object.TYPE_ERROR, "argument %s to %s must be %s, got %s",
+					field.Name.Value, variant.Inspect(), field.Type.Value, typeName(args[i]))
+			}
+		}
+	}
+
+	return &object.EnumValue{Variant: variant, Values: args}
+}
+
+// evalMatchExpression evaluates the body of the first arm whose pattern
+// matches the value, after binding the names of the pattern in env.
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	for _, arm := range node.Arms {
+		bindings := map[string]object.Object{}
+
+		ok, err := matchPattern(arm.Pattern, val, env, bindings)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			continue
+		}
+
+		for name, val := range bindings {
+			if env.IsConst(name) {
+				return newConstantError(name)
+			}
+			env.Set(name, val)
+		}
+
+		return Eval(arm.Body, env)
+	}
+
+	return newMatchError(val)
+}
+
+// newMatchError is the error of a match expression none of whose arms
+// matches val.
+func newMatchError(val object.Object) *object.Error {
+	if value, ok := val.(*object.EnumValue); ok {
+		return newKindError(object.MATCH_ERROR, "match on %s is not exhaustive: no arm matches %s",
+			value.Variant.Enum.Name.Value, value.Variant.Inspect())
+	}
+
+	return newKindError(object.MATCH_ERROR, "no arm matches %s", val.Inspect())
+}
+
+// matchPattern reports whether val matches pattern, collecting the values
+// of the names the pattern binds in bindings.
+func matchPattern(pattern ast.Pattern, val object.Object, env *object.Environment, bindings map[string]object.Object) (bool, *object.Error) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+	case *ast.BindingPattern:
+		bindings[pattern.Name.Value] = val
+		return true, nil
+	case *ast.LiteralPattern:
+		lit := Eval(pattern.Value, env)
+		if err, ok := lit.(*object.Error); ok {
+			return false, err
+		}
+
+		return sameValue(lit, val), nil
+	case *ast.ArrayPattern:
+		return matchArrayPattern(pattern, val, env, bindings)
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+
+		for _, pair := range pattern.Pairs {
+			key := &object.String{Value: pair.Key.Value}
+
+			found, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return false, nil
+			}
+
+			if ok, err := matchPattern(pair.Pattern, found.Value, env, bindings); !ok || err != nil {
+				return false, err
+			}
+		}
+
+		return true, nil
+	case *ast.VariantPattern:
+		return matchVariantPattern(pattern, val, env, bindings)
+	}
+
+	return false, newError("unknown pattern: %s", pattern.String())
+}
+
+func matchArrayPattern(pattern *ast.ArrayPattern, val object.Object, env *object.Environment, bindings map[string]object.Object) (bool, *object.Error) {
+	array, ok := val.(*object.Array)
+	if !ok {
+		return false, nil
+	}
+
+	n := len(pattern.Elements)
+	if len(array.Elements) < n || pattern.Rest == nil && len(array.Elements) != n {
+		return false, nil
+	}
+
+	for i, element := range pattern.Elements {
+		if ok, err := matchPattern(element, array.Elements[i], env, bindings); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	if pattern.Rest != nil {
+		rest := make([]object.Object, len(array.Elements)-n)
+		copy(rest, array.Elements[n:])
+
+		bindings[pattern.Rest.Value] = &object.Array{Elements: rest}
+	}
+
+	return true, nil
+}
+
+func matchVariantPattern(pattern *ast.VariantPattern, val object.Object, env *object.Environment, bindings map[string]object.Object) (bool, *object.Error) {
+	obj := Eval(pattern.Enum, env)
+	if err, ok := obj.(*object.Error); ok {
+		return false, err
+	}
+
+	args := -1
+	if pattern.Arguments != nil {
+		args = len(pattern.Arguments)
+	}
+
+	variant, err := patternVariant(obj, pattern.Enum.Value, pattern.Variant.Value, args)
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := val.(*object.EnumValue)
+	if !ok || value.Variant != variant {
+		return false, nil
+	}
+
+	for i, arg := range pattern.Arguments {
+		if ok, err := matchPattern(arg, value.Values[i], env, bindings); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// patternVariant returns the variant named by a pattern of the variant
+// name of the enum obj, named enumName in the pattern, with args argument
+// patterns, or -1 without parentheses.
+func patternVariant(obj object.Object, enumName, name string, args int) (*object.EnumVariant, *object.Error) {
+	enum, ok := obj.(*object.Enum)
+	if !ok {
+		return nil, newKindError(object.TYPE_ERROR, "%s is not an enum", enumName)
+	}
+
+	variant, ok := enum.Variants[name]
+	if !ok {
+		return nil, newKindError(object.NAME_ERROR, "%s has no variant %s", enum.Name.Value, name)
+	}
+
+	if args >= 0 && args != len(variant.Fields) {
+		return nil, newKindError(object.TYPE_ERROR, "%s has %d fields, pattern has %d",
+			variant.Inspect(), len(variant.Fields), args)
+	}
+
+	return variant, nil
+}
+
+// sameValue reports whether the integers, strings or booleans a and b are
+// equal.
+func sameValue(a, b object.Object) bool {
+	ha, ok := a.(object.Hashable)
+	if !ok {
+		return false
+	}
+
+	hb, ok := b.(object.Hashable)
+
+	return ok && ha.HashKey() == hb.HashKey()
+}
@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// The functions below expose the operations of the evaluator to the
+// virtual machine, so compiled programs behave exactly like evaluated ones.
+// The scopes they take are where the machine runs the operation, which has
+// access to private members within methods, and where it looks up the
+// types of annotations.
+
+// LookupBuiltin returns the builtin function called name.
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+	builtin, ok := builtins[name]
+	return builtin, ok
+}
+
+// IsTruthy reports whether obj counts as true in a condition.
+func IsTruthy(obj object.Object) bool {
+	return isTruthy(obj)
+}
+
+// Infix applies the binary operator to left and right.
+func Infix(operator string, left, right object.Object) object.Object {
+	return evalInfixExpression(operator, left, right)
+}
+
+// Prefix applies the unary operator to right.
+func Prefix(operator string, right object.Object) object.Object {
+	return evalPrefixExpression(operator, right)
+}
+
+// Index returns left[index].
+func Index(left, index object.Object) object.Object {
+	return evalIndexExpression(left, index)
+}
+
+// AssignIndex performs `left[index] operator val`, where operator is = or a
+// compound assignment operator such as +=.
+func AssignIndex(left, index object.Object, operator string, val object.Object) object.Object {
+	return assignIndex(left, index, func(current object.Object) object.Object {
+		return combine(operator, current, val)
+	})
+}
+
+// Member returns `receiver.name` in scope.
+func Member(receiver object.Object, name string, scope object.Scope) object.Object {
+	return evalMember(receiver, &ast.Identifier{Value: name}, scope)
+}
+
+// AssignMember performs `receiver.name operator val` in scope.
+func AssignMember(receiver object.Object, name string, operator string, val object.Object, scope object.Scope) object.Object {
+	return assignField(receiver, &ast.Identifier{Value: name}, scope, func(current object.Object) object.Object {
+		return combine(operator, current, val)
+	})
+}
+
+// combine returns the value assigned by an assignment with operator to a
+// target holding current.
+func combine(operator string, current, val object.Object) object.Object {
+	if operator == "=" {
+		return val
+	}
+
+	return evalInfixExpression(strings.TrimSuffix(operator, "="), current, val)
+}
+
+// Destructure returns the parts of val bound to names by a destructuring
+// of the given kind, token.LPAREN, token.LBRACKET or token.LBRACE.
+func Destructure(kind token.TokenType, names []*ast.Identifier, val object.Object) ([]object.Object, *object.Error) {
+	return destructure(kind, names, val)
+}
+
+// Throw returns the error raised by `throw val`.
+func Throw(val object.Object) *object.Error {
+	return newThrownError(val)
+}
+
+// Import returns the module imported by node.
+func Import(node *ast.ImportStatement) (*object.Module, *object.Error) {
+	return importModule(node)
+}
+
+// IsStdModule reports whether node imports a module of the standard
+// library, which takes precedence over the files of the same name.
+func IsStdModule(node *ast.ImportStatement) bool {
+	if _, isName := node.Name.(*ast.Identifier); !isName {
+		return false
+	}
+
+	_, ok := stdModules[node.Path()]
+	return ok
+}
+
+// FindModule returns the path of the file imported by node, its absolute
+// path, which identifies the module, and the name of the module.
+func FindModule(node *ast.ImportStatement) (path, abs, name string, err *object.Error) {
+	path, abs, err = findModule(node)
+	return path, abs, moduleName(path), err
+}
+
+// ParseModule parses the file at path imported by node.
+func ParseModule(node *ast.ImportStatement, path string) (*ast.Program, *object.Error) {
+	return parseModule(node, path)
+}
+
+// ImportCycle returns the error of importing the module abs while the
+// modules importing are being imported, or nil if abs is not one of them.
+func ImportCycle(importing []string, abs string) *object.Error {
+	return importCycle(importing, abs)
+}
+
+// Apply calls a function that is not compiled, such as a builtin or a
+// function of an imported module, with positional arguments and the named
+// arguments names[i]: values[i]. callPos is the location of the call.
+func Apply(fn object.Object, args []object.Object, names []string, values []object.Object, callPos token.Position) object.Object {
+	var named []namedArgument
+	for i, name := range names {
+		named = append(named, namedArgument{name: name, value: values[i]})
+	}
+
+	return applyFunction(fn, args, named, callPos)
+}
+
+// ArityError is the error of a call to the function name with params with
+// got arguments.
+func ArityError(name string, params []*ast.TypedField, got int) *object.Error {
+	return newArityError(name, params, got)
+}
+
+// CheckArgument reports an argument to the function name that does not
+// match the annotated type of its parameter, looked up in scope.
+func CheckArgument(name string, param *ast.TypedField, arg object.Object, scope object.Scope) *object.Error {
+	return checkArgument(name, param, arg, scope)
+}
+
+// CheckResult reports a result of the function name that does not match
+// its annotated return types, looked up in scope.
+func CheckResult(name string, returnTypes []*ast.Identifier, result object.Object, scope object.Scope) *object.Error {
+	return checkResult(name, returnTypes, result, scope)
+}
+
+// Extend makes klass a subclass of super, the value after extends.
+func Extend(klass *object.Class, super object.Object) *object.Error {
+	return extend(klass, super)
+}
+
+// Implement makes klass implement iface, the value of name after
+// implements.
+func Implement(klass *object.Class, iface object.Object, name *ast.Identifier) *object.Error {
+	return implement(klass, iface, name)
+}
+
+// NewInstance returns an instance of klass, created by new(name, ...), with
+// the initial values of its fields. Its init method, if the class has one,
+// is left to the caller to call.
+func NewInstance(klass *object.Class, name string) (*object.ClassInstance, *object.Error) {
+	return newInstance(klass, &ast.Identifier{Value: name})
+}
+
+// Init returns the init method of the class of instance bound to it, or nil
+// if the class has none.
+func Init(instance *object.ClassInstance) object.Object {
+	init, definedBy := instance.Klass.LookupMethod("init")
+	if init == nil {
+		return nil
+	}
+
+	return bindMethod(instance, init, definedBy)
+}
+
+// SetFields sets the fields of an instance of a class without init to the
+// arguments of new: a hash of the fields, or the named arguments names[i]:
+// values[i].
+func SetFields(instance *object.ClassInstance, args []object.Object, names []string, values []object.Object) *object.Error {
+	var named []namedArgument
+	for i, name := range names {
+		named = append(named, namedArgument{name: name, value: values[i]})
+	}
+
+	return setFields(instance, args, named)
+}
+
+// NewEnum returns the enum declared by node, which looks up the types of
+// the fields of its variants in scope.
+func NewEnum(node *ast.EnumExpression, scope object.Scope) *object.Enum {
+	return newEnum(node, scope)
+}
+
+// SameValue reports whether the integers, strings or booleans a and b are
+// equal, as for a literal pattern.
+func SameValue(a, b object.Object) bool {
+	return sameValue(a, b)
+}
+
+// PatternVariant returns the variant a variant pattern enumName.name with
+// args argument patterns, or -1 without parentheses, matches, where enum is
+// the value of enumName.
+func PatternVariant(enum object.Object, enumName, name string, args int) (*object.EnumVariant, *object.Error) {
+	return patternVariant(enum, enumName, name, args)
+}
+
+// MatchError is the error of a match expression none of whose arms
+// matches val.
+func MatchError(val object.Object) *object.Error {
+	return newMatchError(val)
+}
@@ -0,0 +1,66 @@
+package evaluator
+
+import (
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// stdModules are the modules of the standard library, implemented in Go
+// and imported by name: `import strings`. They take precedence over files
+// of the same name.
+var stdModules = map[string]map[string]*object.Builtin{}
+
+// registerModule adds a module of the standard library. The functions of
+// the module are called as `name.function(...)`.
+func registerModule(name string, functions map[string]*object.Builtin) {
+	stdModules[name] = functions
+}
+
+// loadStdModule returns the module of the standard library with the given
+// name, or false if there is none.
+func loadStdModule(name string) (*object.Module, bool) {
+	functions, ok := stdModules[name]
+	if !ok {
+		return nil, false
+	}
+
+	// std modules are cached next to the files, under a name no file has
+	key := "<std>/" + name
+	if module, ok := modules[key]; ok {
+		return module, true
+	}
+
+	env := object.NewEnvironment()
+	for fname, fn := range functions {
+		env.SetConst(fname, fn)
+	}
+	module := &object.Module{Name: name, Env: env}
+	modules[key] = module
+
+	return module, true
+}
+
+// checkArgs reports a call to the function name of the standard library
+// with the wrong number of arguments or an argument of the wrong type. An
+// empty type accepts any argument.
+func checkArgs(name string, args []object.Object, types ...object.ObjectType) *object.Error {
+	if len(args) != len(types) {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to %s: got %d, want %d",
+			name, len(args), len(types))
+	}
+
+	for i, t := range types {
+		if t != "" && args[i].Type() != t {
+			return newKindError(object.TYPE_ERROR, "argument %d to %s must be %s, got %s",
+				i+1, name, t, args[i].Type())
+		}
+	}
+
+	return nil
+}
+
+// callFunction calls fn, a function passed to the standard library, with
+// positional arguments.
+func callFunction(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args, nil, token.Position{})
+}
@@ -0,0 +1,238 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/emo-lang/emo/object"
+)
+
+func init() {
+	registerModule("collections", map[string]*object.Builtin{
+		// map(array, func(element) {...}) returns the results of the function
+		"map": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.map", args, object.ARRAY_OBJ, ""); err != nil {
+					return err
+				}
+
+				elements := args[0].(*object.Array).Elements
+				result := make([]object.Object, len(elements))
+
+				for i, el := range elements {
+					val := callFunction(args[1], el)
+					if isError(val) {
+						return val
+					}
+					result[i] = val
+				}
+
+				return &object.Array{Elements: result}
+			},
+		},
+		// filter(array, func(element) {...}) returns the elements for which
+		// the function returns a truthy value
+		"filter": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.filter", args, object.ARRAY_OBJ, ""); err != nil {
+					return err
+				}
+
+				result := []object.Object{}
+
+				for _, el := range args[0].(*object.Array).Elements {
+					keep := callFunction(args[1], el)
+					if isError(keep) {
+						return keep
+					}
+
+					if isTruthy(keep) {
+						result = append(result, el)
+					}
+				}
+
+				return &object.Array{Elements: result}
+			},
+		},
+		// reduce(array, func(acc, element) {...}, initial)
+		"reduce": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.reduce", args, object.ARRAY_OBJ, "", ""); err != nil {
+					return err
+				}
+
+				acc := args[2]
+
+				for _, el := range args[0].(*object.Array).Elements {
+					acc = callFunction(args[1], acc, el)
+					if isError(acc) {
+						return acc
+					}
+				}
+
+				return acc
+			},
+		},
+		// sort(array) sorts integers or strings, sort(array, func(a, b) {...})
+		// sorts by a function that reports whether a comes before b
+		"sort": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to collections.sort: got %d, want 1 to 2", len(args))
+				}
+
+				array, ok := args[0].(*object.Array)
+				if !ok {
+					return newKindError(object.TYPE_ERROR, "argument 1 to collections.sort must be ARRAY, got %s", args[0].Type())
+				}
+
+				result := make([]object.Object, len(array.Elements))
+				copy(result, array.Elements)
+
+				less := compareValues
+				if len(args) == 2 {
+					less = func(a, b object.Object) (bool, object.Object) {
+						val := callFunction(args[1], a, b)
+						if isError(val) {
+							return false, val
+						}
+						return isTruthy(val), nil
+					}
+				}
+
+				var err object.Object
+				sort.SliceStable(result, func(i, j int) bool {
+					if err != nil {
+						return false
+					}
+
+					var before bool
+					before, err = less(result[i], result[j])
+
+					return before
+				})
+
+				if err != nil {
+					return err
+				}
+
+				return &object.Array{Elements: result}
+			},
+		},
+		// zip(a, b) pairs the elements of two arrays, up to the shorter one
+		"zip": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.zip", args, object.ARRAY_OBJ, object.ARRAY_OBJ); err != nil {
+					return err
+				}
+
+				a, b := args[0].(*object.Array).Elements, args[1].(*object.Array).Elements
+				n := min(len(a), len(b))
+
+				result := make([]object.Object, n)
+				for i := 0; i < n; i++ {
+					result[i] = &object.Array{Elements: []object.Object{a[i], b[i]}}
+				}
+
+				return &object.Array{Elements: result}
+			},
+		},
+		// unique returns the elements without repetitions, in their order
+		"unique": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.unique", args, object.ARRAY_OBJ); err != nil {
+					return err
+				}
+
+				seen := map[object.HashKey]bool{}
+				result := []object.Object{}
+
+				for _, el := range args[0].(*object.Array).Elements {
+					hashable, ok := el.(object.Hashable)
+					if !ok {
+						return newKindError(object.TYPE_ERROR, "collections.unique: unusable as hash key: %s", el.Type())
+					}
+
+					if key := hashable.HashKey(); !seen[key] {
+						seen[key] = true
+						result = append(result, el)
+					}
+				}
+
+				return &object.Array{Elements: result}
+			},
+		},
+		"keys": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.keys", args, object.HASH_OBJ); err != nil {
+					return err
+				}
+
+				keys := []object.Object{}
+				for _, pair := range args[0].(*object.Hash).SortedPairs() {
+					keys = append(keys, pair.Key)
+				}
+
+				return &object.Array{Elements: keys}
+			},
+		},
+		"values": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.values", args, object.HASH_OBJ); err != nil {
+					return err
+				}
+
+				values := []object.Object{}
+				for _, pair := range args[0].(*object.Hash).SortedPairs() {
+					values = append(values, pair.Value)
+				}
+
+				return &object.Array{Elements: values}
+			},
+		},
+		// delete(hash, key) removes key from hash and returns its value, or
+		// nil if hash has no such key
+		"delete": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("collections.delete", args, object.HASH_OBJ, ""); err != nil {
+					return err
+				}
+
+				hash := args[0].(*object.Hash)
+				if hash.Frozen {
+					return newKindError(object.TYPE_ERROR, "cannot modify frozen hash")
+				}
+
+				key, ok := args[1].(object.Hashable)
+				if !ok {
+					return newKindError(object.TYPE_ERROR, "unusable as hash key: %s", args[1].Type())
+				}
+
+				pair, ok := hash.Pairs[key.HashKey()]
+				if !ok {
+					return NIL
+				}
+
+				delete(hash.Pairs, key.HashKey())
+
+				return pair.Value
+			},
+		},
+	})
+}
+
+// compareValues orders integers and strings, the values sort can compare
+// without a function.
+func compareValues(a, b object.Object) (bool, object.Object) {
+	switch a := a.(type) {
+	case *object.Integer:
+		if b, ok := b.(*object.Integer); ok {
+			return a.Value < b.Value, nil
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			return a.Value < b.Value, nil
+		}
+	}
+
+	return false, newKindError(object.TYPE_ERROR, "collections.sort: cannot compare %s and %s without a function", a.Type(), b.Type())
+}
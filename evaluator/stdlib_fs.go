@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"os"
+
+	"github.com/emo-lang/emo/object"
+)
+
+func init() {
+	registerModule("fs", map[string]*object.Builtin{
+		"read_file": {
+			Fn: func(args ...object.Object) object.Object {
+				path, err := pathArg("fs.read_file", args, object.STRING_OBJ)
+				if err != nil {
+					return err
+				}
+
+				data, ioErr := os.ReadFile(path)
+				if ioErr != nil {
+					return newIOError("fs.read_file", ioErr)
+				}
+
+				return &object.String{Value: string(data)}
+			},
+		},
+		// write_file(path, content) creates or replaces the file at path
+		"write_file": {
+			Fn: func(args ...object.Object) object.Object {
+				path, err := pathArg("fs.write_file", args, object.STRING_OBJ, object.STRING_OBJ)
+				if err != nil {
+					return err
+				}
+
+				if ioErr := os.WriteFile(path, []byte(args[1].(*object.String).Value), 0o644); ioErr != nil {
+					return newIOError("fs.write_file", ioErr)
+				}
+
+				return NIL
+			},
+		},
+		// list_dir returns the names of the entries of a directory, sorted
+		"list_dir": {
+			Fn: func(args ...object.Object) object.Object {
+				path, err := pathArg("fs.list_dir", args, object.STRING_OBJ)
+				if err != nil {
+					return err
+				}
+
+				entries, ioErr := os.ReadDir(path)
+				if ioErr != nil {
+					return newIOError("fs.list_dir", ioErr)
+				}
+
+				names := make([]object.Object, len(entries))
+				for i, entry := range entries {
+					names[i] = &object.String{Value: entry.Name()}
+				}
+
+				return &object.Array{Elements: names}
+			},
+		},
+		"exists?": {
+			Fn: func(args ...object.Object) object.Object {
+				path, err := pathArg("fs.exists?", args, object.STRING_OBJ)
+				if err != nil {
+					return err
+				}
+
+				_, statErr := os.Stat(path)
+
+				return nativeBoolToBooleanObject(statErr == nil)
+			},
+		},
+	})
+}
+
+// pathArg checks the arguments of the function name of the fs module, the
+// first of which is a path, and that the scripts may touch that path. It
+// returns the path to open, which checkPath checked.
+func pathArg(name string, args []object.Object, types ...object.ObjectType) (string, *object.Error) {
+	if err := checkArgs(name, args, types...); err != nil {
+		return "", err
+	}
+
+	return checkPath(name, args[0].(*object.String).Value)
+}
+
+func newIOError(name string, err error) *object.Error {
+	return newKindError(object.IO_ERROR, "%s: %s", name, err)
+}
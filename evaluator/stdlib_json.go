@@ -0,0 +1,212 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/emo-lang/emo/object"
+)
+
+func init() {
+	registerModule("json", map[string]*object.Builtin{
+		// parse turns JSON into hashes, arrays, integers, strings, booleans
+		// and nil
+		"parse": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("json.parse", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				dec := json.NewDecoder(strings.NewReader(args[0].(*object.String).Value))
+				dec.UseNumber()
+
+				var value any
+				if err := dec.Decode(&value); err != nil {
+					return newKindError(object.ARGUMENT_ERROR, "json.parse: %s", err)
+				}
+
+				if _, err := dec.Token(); err != io.EOF {
+					return newKindError(object.ARGUMENT_ERROR, "json.parse: unexpected data after the value")
+				}
+
+				return fromJSON(value)
+			},
+		},
+		// stringify(value) turns a value into JSON, stringify(value, true)
+		// indents it
+		"stringify": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to json.stringify: got %d, want 1 to 2", len(args))
+				}
+
+				pretty := false
+				if len(args) == 2 {
+					b, ok := args[1].(*object.Boolean)
+					if !ok {
+						return newKindError(object.TYPE_ERROR, "argument 2 to json.stringify must be BOOLEAN, got %s", args[1].Type())
+					}
+					pretty = b.Value
+				}
+
+				var out bytes.Buffer
+				if err := writeJSON(&out, args[0], map[object.Object]bool{}); err != nil {
+					return err
+				}
+
+				if pretty {
+					var indented bytes.Buffer
+					json.Indent(&indented, out.Bytes(), "", "  ")
+					out = indented
+				}
+
+				return &object.String{Value: out.String()}
+			},
+		},
+	})
+}
+
+// fromJSON converts a value decoded by encoding/json into an object.
+func fromJSON(value any) object.Object {
+	switch value := value.(type) {
+	case nil:
+		return NIL
+	case bool:
+		return nativeBoolToBooleanObject(value)
+	case string:
+		return &object.String{Value: value}
+	case json.Number:
+		n, err := value.Int64()
+		if err != nil {
+			return newKindError(object.ARGUMENT_ERROR, "json.parse: number %s is not an integer", value)
+		}
+		return &object.Integer{Value: n}
+	case []any:
+		elements := make([]object.Object, len(value))
+		for i, v := range value {
+			elements[i] = fromJSON(v)
+			if isError(elements[i]) {
+				return elements[i]
+			}
+		}
+		return &object.Array{Elements: elements}
+	case map[string]any:
+		hash := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+		for k, v := range value {
+			val := fromJSON(v)
+			if isError(val) {
+				return val
+			}
+
+			key := &object.String{Value: k}
+			hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: val}
+		}
+		return hash
+	}
+
+	return newError("json.parse: unexpected value %v", value)
+}
+
+// writeJSON writes obj to out as compact JSON. Hashes, whose keys must be
+// strings, are written with their keys sorted and class instances with their
+// public fields. visiting
+// holds the arrays, hashes and instances being written, to detect cycles.
+func writeJSON(out *bytes.Buffer, obj object.Object, visiting map[object.Object]bool) *object.Error {
+	switch obj.(type) {
+	case *object.Array, *object.Hash, *object.ClassInstance:
+		if visiting[obj] {
+			return newKindError(object.ARGUMENT_ERROR, "json.stringify: cannot serialize a value that contains itself")
+		}
+
+		visiting[obj] = true
+		defer delete(visiting, obj)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Nil:
+		out.WriteString("null")
+	case *object.Boolean, *object.Integer:
+		out.WriteString(obj.Inspect())
+	case *object.String:
+		writeJSONString(out, obj.Value)
+	case *object.Array:
+		return writeJSONArray(out, obj.Elements, visiting)
+	case *object.Tuple:
+		return writeJSONArray(out, obj.Elements, visiting)
+	case *object.Hash:
+		out.WriteString("{")
+		for i, pair := range obj.SortedPairs() {
+			if i > 0 {
+				out.WriteString(",")
+			}
+
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newKindError(object.TYPE_ERROR, "json.stringify: cannot serialize a hash key of type %s", typeName(pair.Key))
+			}
+
+			writeJSONString(out, key.Value)
+			out.WriteString(":")
+
+			if err := writeJSON(out, pair.Value, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	case *object.ClassInstance:
+		names := []string{}
+		for name := range obj.Fields {
+			if field, _ := obj.Klass.LookupField(name); field != nil && field.Public {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		out.WriteString("{")
+		for i, name := range names {
+			if i > 0 {
+				out.WriteString(",")
+			}
+
+			writeJSONString(out, name)
+			out.WriteString(":")
+
+			if err := writeJSON(out, obj.Fields[name], visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	default:
+		return newKindError(object.TYPE_ERROR, "json.stringify: cannot serialize %s", typeName(obj))
+	}
+
+	return nil
+}
+
+func writeJSONArray(out *bytes.Buffer, elements []object.Object, visiting map[object.Object]bool) *object.Error {
+	out.WriteString("[")
+	for i, el := range elements {
+		if i > 0 {
+			out.WriteString(",")
+		}
+
+		if err := writeJSON(out, el, visiting); err != nil {
+			return err
+		}
+	}
+	out.WriteString("]")
+
+	return nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	// encoding a string cannot fail; unlike json.Marshal, the encoder can
+	// leave <, > and & as they are
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	out.Truncate(out.Len() - 1) // the newline after the value
+}
@@ -0,0 +1,196 @@
+package evaluator
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/emo-lang/emo/object"
+)
+
+// random is the source of math.random, reseeded by math.seed.
+var random = rand.New(rand.NewSource(rand.Int63()))
+
+func init() {
+	registerModule("math", map[string]*object.Builtin{
+		"abs": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("math.abs", args, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				n := args[0].(*object.Integer).Value
+				if n == math.MinInt64 {
+					return newKindError(object.ARGUMENT_ERROR, "math.abs: the absolute value of %d overflows", n)
+				}
+				if n < 0 {
+					n = -n
+				}
+
+				return &object.Integer{Value: n}
+			},
+		},
+		"min": {
+			Fn: func(args ...object.Object) object.Object {
+				return extremum("math.min", args, func(a, b int64) bool { return a < b })
+			},
+		},
+		"max": {
+			Fn: func(args ...object.Object) object.Object {
+				return extremum("math.max", args, func(a, b int64) bool { return a > b })
+			},
+		},
+		"pow": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("math.pow", args, object.INTEGER_OBJ, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				base, exp := args[0].(*object.Integer).Value, args[1].(*object.Integer).Value
+				if exp < 0 {
+					return newKindError(object.ARGUMENT_ERROR, "math.pow: negative exponent %d", exp)
+				}
+
+				result, ok := power(base, exp)
+				if !ok {
+					return newKindError(object.ARGUMENT_ERROR, "math.pow: %d to the power %d overflows", base, exp)
+				}
+
+				return &object.Integer{Value: result}
+			},
+		},
+		// sqrt is the integer square root, rounded down
+		"sqrt": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("math.sqrt", args, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				n := args[0].(*object.Integer).Value
+				if n == math.MinInt64 {
+					return newKindError(object.ARGUMENT_ERROR, "math.abs: the absolute value of %d overflows", n)
+				}
+				if n < 0 {
+					return newKindError(object.ARGUMENT_ERROR, "math.sqrt: negative argument %d", n)
+				}
+
+				// the float root, corrected where it is rounded
+				x := int64(math.Sqrt(float64(n)))
+				for x > 0 && x > n/x {
+					x--
+				}
+				for x+1 <= n/(x+1) {
+					x++
+				}
+
+				return &object.Integer{Value: x}
+			},
+		},
+		// floor divides rounding towards negative infinity, where `/` rounds
+		// towards zero
+		"floor": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("math.floor", args, object.INTEGER_OBJ, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				a, b := args[0].(*object.Integer).Value, args[1].(*object.Integer).Value
+				if b == 0 {
+					return newError("division by zero")
+				}
+
+				q := a / b
+				if (a%b != 0) && ((a < 0) != (b < 0)) {
+					q--
+				}
+
+				return &object.Integer{Value: q}
+			},
+		},
+		// random(n) returns an integer from 0 to n-1
+		"random": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("math.random", args, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				n := args[0].(*object.Integer).Value
+				if n <= 0 {
+					return newKindError(object.ARGUMENT_ERROR, "math.random: argument must be positive, got %d", n)
+				}
+
+				return &object.Integer{Value: random.Int63n(n)}
+			},
+		},
+		// seed makes the numbers of random repeat for the same seed
+		"seed": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("math.seed", args, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				random.Seed(args[0].(*object.Integer).Value)
+
+				return NIL
+			},
+		},
+	})
+}
+
+// power returns base to the power exp by squaring, and false if it does not
+// fit in an int64.
+func power(base, exp int64) (int64, bool) {
+	result := int64(1)
+	ok := true
+
+	for {
+		if exp&1 == 1 {
+			if result, ok = multiply(result, base); !ok {
+				return 0, false
+			}
+		}
+
+		if exp >>= 1; exp == 0 {
+			return result, true
+		}
+
+		// the square is a factor of the result from here on
+		if base, ok = multiply(base, base); !ok {
+			return 0, false
+		}
+	}
+}
+
+// multiply returns a * b, and false if it does not fit in an int64.
+func multiply(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	c := a * b
+	if c/b != a || (c < 0) != ((a < 0) != (b < 0)) {
+		return 0, false
+	}
+
+	return c, true
+}
+
+// extremum returns the argument that comes first by better.
+func extremum(name string, args []object.Object, better func(a, b int64) bool) object.Object {
+	if len(args) == 0 {
+		return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to %s: got 0, want at least 1", name)
+	}
+
+	var result *object.Integer
+	for i, arg := range args {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "argument %d to %s must be INTEGER, got %s", i+1, name, arg.Type())
+		}
+
+		if result == nil || better(n.Value, result.Value) {
+			result = n
+		}
+	}
+
+	return result
+}
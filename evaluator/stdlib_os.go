@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"os"
+	"strings"
+
+	"github.com/emo-lang/emo/object"
+)
+
+func init() {
+	registerModule("os", map[string]*object.Builtin{
+		// env() returns all environment variables, env(name) the value of
+		// one or nil if it is not set
+		"env": {
+			Fn: func(args ...object.Object) object.Object {
+				if !Caps.Env {
+					return newKindError(object.PERMISSION_ERROR, "os.env: reading environment variables is not allowed")
+				}
+
+				if len(args) == 0 {
+					env := &object.Hash{Pairs: map[object.HashKey]object.HashPair{}}
+					for _, kv := range os.Environ() {
+						name, value, _ := strings.Cut(kv, "=")
+						key := &object.String{Value: name}
+						env.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: &object.String{Value: value}}
+					}
+					return env
+				}
+
+				if err := checkArgs("os.env", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				value, ok := os.LookupEnv(args[0].(*object.String).Value)
+				if !ok {
+					return NIL
+				}
+
+				return &object.String{Value: value}
+			},
+		},
+		"args": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("os.args", args); err != nil {
+					return err
+				}
+
+				elements := make([]object.Object, len(Args))
+				for i, arg := range Args {
+					elements[i] = &object.String{Value: arg}
+				}
+
+				return &object.Array{Elements: elements}
+			},
+		},
+		"exit": {
+			Fn: func(args ...object.Object) object.Object {
+				if !Caps.Exit {
+					return newKindError(object.PERMISSION_ERROR, "os.exit: exiting is not allowed")
+				}
+
+				if err := checkArgs("os.exit", args, object.INTEGER_OBJ); err != nil {
+					return err
+				}
+
+				exitProcess(int(args[0].(*object.Integer).Value))
+
+				return NIL
+			},
+		},
+	})
+}
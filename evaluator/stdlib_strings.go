@@ -0,0 +1,121 @@
+package evaluator
+
+import (
+	"strings"
+
+	"github.com/emo-lang/emo/object"
+)
+
+func init() {
+	registerModule("strings", map[string]*object.Builtin{
+		"split": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.split", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				parts := strings.Split(args[0].(*object.String).Value, args[1].(*object.String).Value)
+
+				elements := make([]object.Object, len(parts))
+				for i, part := range parts {
+					elements[i] = &object.String{Value: part}
+				}
+
+				return &object.Array{Elements: elements}
+			},
+		},
+		"join": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.join", args, object.ARRAY_OBJ, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				parts := []string{}
+				for _, el := range args[0].(*object.Array).Elements {
+					parts = append(parts, el.Inspect())
+				}
+
+				return &object.String{Value: strings.Join(parts, args[1].(*object.String).Value)}
+			},
+		},
+		"trim": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.trim", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				return &object.String{Value: strings.TrimSpace(args[0].(*object.String).Value)}
+			},
+		},
+		"replace": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.replace", args, object.STRING_OBJ, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				s, old, new := args[0].(*object.String), args[1].(*object.String), args[2].(*object.String)
+
+				return &object.String{Value: strings.ReplaceAll(s.Value, old.Value, new.Value)}
+			},
+		},
+		"upper": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.upper", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				return &object.String{Value: strings.ToUpper(args[0].(*object.String).Value)}
+			},
+		},
+		"lower": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.lower", args, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				return &object.String{Value: strings.ToLower(args[0].(*object.String).Value)}
+			},
+		},
+		"contains": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArgs("strings.contains", args, object.STRING_OBJ, object.STRING_OBJ); err != nil {
+					return err
+				}
+
+				return nativeBoolToBooleanObject(strings.Contains(args[0].(*object.String).Value, args[1].(*object.String).Value))
+			},
+		},
+		// format("{} is {}", name, age) replaces each {} with the next
+		// argument
+		"format": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) == 0 {
+					return newKindError(object.ARGUMENT_ERROR, "wrong number of arguments to strings.format: got 0, want at least 1")
+				}
+
+				format, ok := args[0].(*object.String)
+				if !ok {
+					return newKindError(object.TYPE_ERROR, "argument 1 to strings.format must be STRING, got %s", args[0].Type())
+				}
+
+				values := args[1:]
+				if n := strings.Count(format.Value, "{}"); n != len(values) {
+					return newKindError(object.ARGUMENT_ERROR, "strings.format: format has %d placeholders, got %d values", n, len(values))
+				}
+
+				var out strings.Builder
+
+				rest := format.Value
+				for _, v := range values {
+					i := strings.Index(rest, "{}")
+					out.WriteString(rest[:i])
+					out.WriteString(v.Inspect())
+					rest = rest[i+2:]
+				}
+				out.WriteString(rest)
+
+				return &object.String{Value: out.String()}
+			},
+		},
+	})
+}
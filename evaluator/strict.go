@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+)
+
+// Strict makes function calls check their arguments and return values
+// against the type annotations of the function, as `emo run --strict` does.
+var Strict bool
+
+// annotationTypes maps the built-in type names of annotations to the types
+// of the objects they accept.
+var annotationTypes = map[string][]object.ObjectType{
+	"Int":       {object.INTEGER_OBJ},
+	"String":    {object.STRING_OBJ},
+	"Bool":      {object.BOOLEAN_OBJ},
+	"Nil":       {object.NIL_OBJ},
+	"Array":     {object.ARRAY_OBJ},
+	"Hash":      {object.HASH_OBJ},
+	"Range":     {object.RANGE_OBJ},
+	"Exception": {object.EXCEPTION_OBJ},
+	"Func":      {object.FUNCTION_OBJ, object.BUILTIN_OBJ},
+	"Tuple":     {object.TUPLE_OBJ},
+}
+
+// checkArgument reports an argument to the function name that does not
+// match the annotated type of its parameter, looking up types in scope.
+func checkArgument(name string, param *ast.TypedField, arg object.Object, scope object.Scope) *object.Error {
+	ok, err := hasType(arg, param.Type, scope)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return newKindError(object.TYPE_ERROR, "argument %s to %s must be %s, got %s",
+			param.Name.Value, name, param.Type.Value, typeName(arg))
+	}
+
+	return nil
+}
+
+// checkResult reports a result of the function name that does not match its
+// annotated return types. A function with several return types must return
+// a tuple of values of those types. Functions without return types are not
+// checked.
+func checkResult(name string, returnTypes []*ast.Identifier, result object.Object, scope object.Scope) *object.Error {
+	if len(returnTypes) == 0 {
+		return nil
+	}
+
+	if result == nil {
+		result = NIL
+	}
+
+	values := []object.Object{result}
+
+	if len(returnTypes) > 1 {
+		tuple, ok := result.(*object.Tuple)
+		if !ok || len(tuple.Elements) != len(returnTypes) {
+			return newKindError(object.TYPE_ERROR, "%s must return %d values, got %s",
+				name, len(returnTypes), typeName(result))
+		}
+
+		values = tuple.Elements
+	}
+
+	for i, want := range returnTypes {
+		ok, err := hasType(values[i], want, scope)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return newKindError(object.TYPE_ERROR, "%s must return %s, got %s",
+				name, want.Value, typeName(values[i]))
+		}
+	}
+
+	return nil
+}
+
+// hasType reports whether obj is a value of the annotated type, looking up
+// class, interface and enum names in scope. Nil is a value of every class
+// type, as fields of class types start as nil.
+func hasType(obj object.Object, typ *ast.Identifier, scope object.Scope) (bool, *object.Error) {
+	if typ.Value == "Any" {
+		return true, nil
+	}
+
+	if types, ok := annotationTypes[typ.Value]; ok {
+		for _, t := range types {
+			if obj.Type() == t {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	val, _ := scope.Get(typ.Value)
+	instance, isInstance := obj.(*object.ClassInstance)
+	value, isEnumValue := obj.(*object.EnumValue)
+
+	switch val := val.(type) {
+	case *object.Class:
+		return obj == NIL || isInstance && instance.Klass.IsA(val), nil
+	case *object.Interface:
+		return obj == NIL || isInstance && instance.Klass.Implements(val), nil
+	case *object.Enum:
+		return isEnumValue && value.Variant.Enum == val, nil
+	default:
+		return false, newKindError(object.TYPE_ERROR, "unknown type %s", typ.Value)
+	}
+}
+
+// typeName is the name of the type of obj as written in annotations.
+func typeName(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.ClassInstance:
+		return obj.Klass.Name.Value
+	case *object.EnumValue:
+		return obj.Variant.Enum.Name.Value
+	}
+
+	for name, types := range annotationTypes {
+		for _, t := range types {
+			if t == obj.Type() {
+				return name
+			}
+		}
+	}
+
+	return string(obj.Type())
+}
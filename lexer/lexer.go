@@ -5,22 +5,36 @@ import (
 )
 
 type Lexer struct {
+	filename     string
 	input        string
 	position     int
 	readPosition int
 	ch           byte
+
+	// line and column of the current character ch
+	line   int
+	column int
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile returns a lexer whose token positions refer to filename.
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{filename: filename, input: input, line: 1}
 	l.readChar()
 
 	return l
 }
 
 func (l *Lexer) readChar() {
-	// fmt.Println("l.readPosition", l.readPosition)
-	// fmt.Println("len(l.input)", len(l.input))
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -32,11 +46,30 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
-func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+// pos returns the position of the current character.
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
 
+func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
 
+	start := l.pos()
+	tok := l.nextToken()
+	tok.Pos = start
+	tok.End = l.pos()
+
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -47,11 +80,19 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.PLUS_ASSIGN, Literal: "+="}
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
 		if l.peekChar() == '>' {
 			l.readChar()
 			tok = token.Token{Type: token.ARROW, Literal: "->"}
+		} else if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.MINUS_ASSIGN, Literal: "-="}
 		} else {
 			tok = newToken(token.MINUS, l.ch)
 		}
@@ -63,9 +104,19 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: "/="}
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.ASTERISK_ASSIGN, Literal: "*="}
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -75,7 +126,17 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '.':
-		tok = newToken(token.DOT, l.ch)
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '.' {
+				l.readChar()
+				tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			} else {
+				tok = token.Token{Type: token.DOTDOT, Literal: ".."}
+			}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
@@ -199,3 +199,76 @@ func TestNextToken3(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `var x = 5
+  println("hi")`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedPos     string
+		expectedEnd     string
+	}{
+		{"var", "main.emo:1:1", "main.emo:1:4"},
+		{"x", "main.emo:1:5", "main.emo:1:6"},
+		{"=", "main.emo:1:7", "main.emo:1:8"},
+		{"5", "main.emo:1:9", "main.emo:1:10"},
+		{"\n", "main.emo:1:10", "main.emo:2:1"},
+		{"println", "main.emo:2:3", "main.emo:2:10"},
+		{"(", "main.emo:2:10", "main.emo:2:11"},
+		{"hi", "main.emo:2:11", "main.emo:2:15"},
+		{")", "main.emo:2:15", "main.emo:2:16"},
+		{"", "main.emo:2:16", "main.emo:2:17"},
+	}
+
+	l := NewFile("main.emo", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.String() != tt.expectedPos {
+			t.Fatalf("tests[%d] - pos wrong. expected=%q, got=%q",
+				i, tt.expectedPos, tok.Pos.String())
+		}
+
+		if tok.End.String() != tt.expectedEnd {
+			t.Fatalf("tests[%d] - end wrong. expected=%q, got=%q",
+				i, tt.expectedEnd, tok.End.String())
+		}
+	}
+}
+
+func TestDots(t *testing.T) {
+	input := `a.b 0..3 ...rest`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.DOT, "."},
+		{token.IDENT, "b"},
+		{token.INT, "0"},
+		{token.DOTDOT, ".."},
+		{token.INT, "3"},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
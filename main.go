@@ -1,14 +1,25 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/compiler"
 	"github.com/emo-lang/emo/evaluator"
 	"github.com/emo-lang/emo/lexer"
 	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/optimizer"
 	"github.com/emo-lang/emo/parser"
 	"github.com/emo-lang/emo/repl"
+	"github.com/emo-lang/emo/resolver"
+	"github.com/emo-lang/emo/typecheck"
+	"github.com/emo-lang/emo/vm"
 )
 
 func main() {
@@ -21,6 +32,10 @@ func main() {
 	switch action {
 	case "run":
 		run(os.Args[2:])
+	case "build":
+		build(os.Args[2:])
+	case "check":
+		check(os.Args[2:])
 	case "repl":
 		repl.Start(os.Stdin, os.Stdout)
 	default:
@@ -30,19 +45,133 @@ func main() {
 }
 
 func run(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "check arguments and return values against type annotations")
+	allowFS := flags.String("allow-fs", "", "comma-separated `paths` the script may read and write, any path when empty")
+	allowEnv := flags.Bool("allow-env", true, "let the script read environment variables")
+	allowExit := flags.Bool("allow-exit", true, "let the script exit the process")
+	useVM := flags.Bool("vm", false, "compile the script to bytecode and run it on the virtual machine")
+	optimize := flags.Bool("O", false, "optimize the script before running it")
+	dumpAST := flags.Bool("dump-ast", false, "print the statements of the script, optimized with -O, instead of running it")
+	maxDepth := flags.Int("max-depth", evaluator.MaxCallDepth, "the number of function calls that can be in progress at once")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Println("emo run [--strict] [--vm] [-O] [--dump-ast] [--max-depth=n] [--allow-fs=paths] [--allow-env=bool] [--allow-exit=bool] [filename] [args...]")
+		os.Exit(1)
+	}
+
+	evaluator.Strict = *strict
+	evaluator.MaxCallDepth = *maxDepth
+	evaluator.Args = flags.Args()[1:]
+	filename := flags.Arg(0)
+
+	evaluator.Caps = evaluator.Capabilities{FS: true, Env: *allowEnv, Exit: *allowExit}
+	if *allowFS != "" {
+		evaluator.Caps.FSRoots = strings.Split(*allowFS, ",")
+		// the script still imports the modules next to it and on EMO_PATH
+		evaluator.Caps.ImportRoots = append([]string{filepath.Dir(filename)}, evaluator.ModulePath()...)
+	}
+
+	data := readFile(filename)
+
+	var result object.Object
+	switch {
+	case compiler.IsBytecode(data):
+		bytecode, err := compiler.Decode(data)
+		if err != nil {
+			fmt.Printf("Err: %s: %s\n", filename, err)
+			os.Exit(1)
+		}
+
+		result = vm.New(bytecode).Run()
+	case *dumpAST:
+		dumpProgram(sourceProgram(filename, data, *optimize))
+		return
+	case *useVM:
+		result = vm.New(compileProgram(sourceProgram(filename, data, *optimize))).Run()
+	default:
+		result = evaluator.Eval(sourceProgram(filename, data, *optimize), object.NewEnvironment())
+	}
+
+	if err, ok := result.(*object.Error); ok {
+		fmt.Printf("Err: %s: %s: %s\n", err.Pos, err.Kind, err.Message)
+		fmt.Print(err.StackTrace())
+		os.Exit(1)
+	}
+}
+
+// build compiles a program and the files it imports to a bytecode file,
+// which emo run runs without reading or parsing them again.
+func build(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	output := flags.String("o", "", "write the bytecode to `file`, the source file with the extension .emoc by default")
+	optimize := flags.Bool("O", false, "optimize the program before compiling it")
+	flags.Parse(args)
+
+	// the output flag may follow the source file
+	var filename string
+	if flags.NArg() > 0 {
+		filename = flags.Arg(0)
+		flags.Parse(flags.Args()[1:])
+	}
+
+	if filename == "" || flags.NArg() != 0 {
+		fmt.Println("emo build [-O] [filename] [-o output]")
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".emoc"
+	}
+
+	program := sourceProgram(filename, readFile(filename), *optimize)
+
+	data, err := compiler.Encode(compileProgram(program))
+	if err != nil {
+		fmt.Printf("Err: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// check reports the type errors of a program without running it.
+func check(args []string) {
 	if len(args) == 0 {
-		fmt.Println("emo run [filename]")
+		fmt.Println("emo check [filename]")
 		os.Exit(1)
 	}
 
-	filename := args[0]
+	program := parseFile(args[0], readFile(args[0]))
+	resolve(program)
+
+	errors := typecheck.Check(program)
+	if len(errors) != 0 {
+		for _, err := range errors {
+			fmt.Printf("Err: %s\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// readFile reads a source or bytecode file, exiting on errors.
+func readFile(filename string) []byte {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	l := lexer.New(string(data))
+	return data
+}
+
+// parseFile parses the source of a file, exiting on parse errors.
+func parseFile(filename string, data []byte) *ast.Program {
+	l := lexer.NewFile(filename, string(data))
 	p := parser.New(l)
 
 	program := p.ParseProgram()
@@ -54,7 +183,63 @@ func run(args []string) {
 		os.Exit(1)
 	}
 
-	env := object.NewEnvironment()
+	return program
+}
+
+// dumpProgram prints the statements of a program one per line, and the
+// fields and methods of its classes, which classes do not render.
+func dumpProgram(program *ast.Program) {
+	for _, stmt := range program.Statements {
+		fmt.Println(stmt.String())
+
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+		class, ok := es.Expression.(*ast.ClassExpression)
+		if !ok {
+			continue
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(class.Fields)) {
+			fmt.Println("  " + class.Fields[name].String())
+		}
+		for _, name := range slices.Sorted(maps.Keys(class.Methods)) {
+			fmt.Println("  " + class.Methods[name].String())
+		}
+	}
+}
+
+// sourceProgram parses the source of a file and optimizes it if optimize
+// is set, exiting on parse errors.
+func sourceProgram(filename string, data []byte, optimize bool) *ast.Program {
+	program := parseFile(filename, data)
+	if optimize {
+		optimizer.Optimize(program)
+	}
+	resolve(program)
+
+	return program
+}
+
+// resolve resolves the names of a program, exiting on the names used before
+// their declaration.
+func resolve(program *ast.Program) {
+	if errors := resolver.Resolve(program); len(errors) != 0 {
+		for _, err := range errors {
+			fmt.Printf("Err: %s\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// compileProgram compiles a program, exiting on errors.
+func compileProgram(program *ast.Program) *compiler.Bytecode {
+	bytecode, err := compiler.New().Compile(program)
+	if err != nil {
+		fmt.Printf("Err: %s\n", err)
+		os.Exit(1)
+	}
 
-	evaluator.Eval(program, env)
+	return bytecode
 }
@@ -7,6 +7,7 @@ import (
 
 type Array struct {
 	Elements []Object
+	Frozen   bool // cannot be modified, see Freeze
 }
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
@@ -7,10 +7,73 @@ import (
 )
 
 type Class struct {
-	Name    *ast.Identifier
-	Fields  map[string]*ast.ClassField
-	Methods map[string]*ast.ClassMethod
-	Env     *Environment
+	Name  *ast.Identifier
+	Super *Class // nil when the class does not extend another
+	// the interfaces the class declares to implement
+	Interfaces []*Interface
+	Fields     map[string]*ast.ClassField
+	Methods    map[string]*ast.ClassMethod
+	Env        *Environment
+
+	// the code of a class compiled for the virtual machine, nil for a class
+	// the evaluator created
+	Compiled CompiledClass
+}
+
+// CompiledClass runs the methods and the field initializers of a class
+// compiled for the virtual machine, in place of the bodies the evaluator
+// evaluates in the environment of the class.
+type CompiledClass interface {
+	// Method returns the method name of the class bound to instance.
+	Method(instance *ClassInstance, name string) Object
+	// InitialValue returns the value of the initializer of the field
+	// name, or the error it raised.
+	InitialValue(name string) Object
+}
+
+// LookupField finds a field declared by the class or its superclasses and
+// returns it together with the class that declares it.
+func (klass *Class) LookupField(name string) (*ast.ClassField, *Class) {
+	for c := klass; c != nil; c = c.Super {
+		if field, ok := c.Fields[name]; ok {
+			return field, c
+		}
+	}
+	return nil, nil
+}
+
+// LookupMethod finds a method defined by the class or its superclasses and
+// returns it together with the class that defines it.
+func (klass *Class) LookupMethod(name string) (*ast.ClassMethod, *Class) {
+	for c := klass; c != nil; c = c.Super {
+		if method, ok := c.Methods[name]; ok {
+			return method, c
+		}
+	}
+	return nil, nil
+}
+
+// IsA reports whether klass is other or inherits from it.
+func (klass *Class) IsA(other *Class) bool {
+	for c := klass; c != nil; c = c.Super {
+		if c == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Implements reports whether klass or one of its superclasses declares to
+// implement iface.
+func (klass *Class) Implements(iface *Interface) bool {
+	for c := klass; c != nil; c = c.Super {
+		for _, i := range c.Interfaces {
+			if i == iface {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (klass *Class) Type() ObjectType { return CLASS_OBJ }
@@ -19,6 +82,10 @@ func (klass *Class) Inspect() string {
 
 	out.WriteString("class ")
 	out.WriteString(klass.Name.Value)
+	if klass.Super != nil {
+		out.WriteString(" extends ")
+		out.WriteString(klass.Super.Name.Value)
+	}
 	out.WriteString(" {}")
 
 	return out.String()
@@ -28,7 +95,6 @@ type ClassInstance struct {
 	Klass  *Class
 	Name   *ast.Identifier
 	Fields map[string]Object
-	Env    *Environment
 }
 
 func (ci *ClassInstance) Type() ObjectType { return CLASS_INSTANCE_OBJ }
@@ -41,3 +107,15 @@ func (ci *ClassInstance) Inspect() string {
 
 	return out.String()
 }
+
+// Super is the value of `super` in a method: the instance the method was
+// called on, seen as an instance of the superclass of the method's class.
+type Super struct {
+	Instance *ClassInstance
+	Klass    *Class
+}
+
+func (s *Super) Type() ObjectType { return SUPER_OBJ }
+func (s *Super) Inspect() string {
+	return "<super:" + s.Klass.Name.Value + ">"
+}
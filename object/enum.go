@@ -0,0 +1,75 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/emo-lang/emo/ast"
+)
+
+// Enum is the object created by `enum Name { ... }`. Its variants are
+// reached with `Name.Variant`.
+type Enum struct {
+	Name     *ast.Identifier
+	Variants map[string]*EnumVariant
+	Order    []string // variant names in declaration order
+	Env      Scope    // where the types of the fields are looked up
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("enum ")
+	out.WriteString(e.Name.Value)
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(e.Order, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// EnumVariant is a variant of an enum. A variant without fields has a
+// single value, a variant with fields is called to create its values.
+type EnumVariant struct {
+	Enum   *Enum
+	Name   string
+	Fields []*ast.TypedField
+	Value  *EnumValue // the only value of a variant without fields
+}
+
+func (ev *EnumVariant) Type() ObjectType { return ENUM_VARIANT_OBJ }
+func (ev *EnumVariant) Inspect() string {
+	return ev.Enum.Name.Value + "." + ev.Name
+}
+
+// EnumValue is a value of an enum variant with the values of its fields.
+type EnumValue struct {
+	Variant *EnumVariant
+	Values  []Object
+}
+
+func (ev *EnumValue) Type() ObjectType { return ENUM_VALUE_OBJ }
+func (ev *EnumValue) Inspect() string {
+	if len(ev.Variant.Fields) == 0 {
+		return ev.Variant.Inspect()
+	}
+
+	values := []string{}
+	for _, v := range ev.Values {
+		values = append(values, v.Inspect())
+	}
+
+	return ev.Variant.Inspect() + "(" + strings.Join(values, ", ") + ")"
+}
+
+// Field returns the value of the field with the given name, or nil if the
+// variant has no such field.
+func (ev *EnumValue) Field(name string) Object {
+	for i, f := range ev.Variant.Fields {
+		if f.Name.Value == name {
+			return ev.Values[i]
+		}
+	}
+	return nil
+}
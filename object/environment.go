@@ -1,8 +1,27 @@
 package object
 
+// Scope looks up the values of names where code runs. Environments are
+// scopes, and so are the calls the virtual machine runs. The type names of
+// annotations and the self of methods are looked up in scopes.
+type Scope interface {
+	Get(name string) (Object, bool)
+}
+
+// Environment binds names to values. The environment of a call to a function
+// the resolver has seen keeps the locals of the function in slots, indexed
+// by the positions the resolver computed for them, and everything else,
+// such as globals and the REPL, in a map.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store     map[string]Object
+	constants map[string]bool // names bound by const or define
+	outer     *Environment
+	global    *Environment // the outermost environment, nil in it
+
+	// the locals of a function, by slot; a nil slot is not bound yet
+	slots      []Object
+	names      []string
+	index      map[string]int // the slots of names
+	constSlots []bool
 }
 
 func NewEnvironment() *Environment {
@@ -13,11 +32,51 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.global = outer.Global()
 	return env
 }
 
-func (e *Environment) Get(name string) (Object, bool) {
+// NewFunctionEnvironment returns an environment for a call to a function
+// with the given locals, one slot for each, and index, the slot of each
+// local by name.
+func NewFunctionEnvironment(outer *Environment, names []string, index map[string]int) *Environment {
+	return &Environment{
+		outer:      outer,
+		global:     outer.Global(),
+		slots:      make([]Object, len(names)),
+		names:      names,
+		index:      index,
+		constSlots: make([]bool, len(names)),
+	}
+}
+
+// Global returns the outermost environment, which binds the globals.
+func (e *Environment) Global() *Environment {
+	if e.global != nil {
+		return e.global
+	}
+	return e
+}
+
+// slot returns the slot of name, or -1 if the environment has none for it.
+func (e *Environment) slot(name string) int {
+	if i, ok := e.index[name]; ok {
+		return i
+	}
+	return -1
+}
+
+// lookup returns the value bound to name in this environment only.
+func (e *Environment) lookup(name string) (Object, bool) {
+	if i := e.slot(name); i >= 0 {
+		return e.slots[i], e.slots[i] != nil
+	}
 	obj, ok := e.store[name]
+	return obj, ok
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.lookup(name)
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
@@ -25,6 +84,144 @@ func (e *Environment) Get(name string) (Object, bool) {
 }
 
 func (e *Environment) Set(name string, val Object) Object {
+	if i := e.slot(name); i >= 0 {
+		return e.SetLocal(i, name, val)
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// SetConst binds name to val and marks the binding as constant, so it
+// cannot be assigned or declared again.
+func (e *Environment) SetConst(name string, val Object) Object {
+	if i := e.slot(name); i >= 0 {
+		return e.SetLocalConst(i, name, val)
+	}
+
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+
+	return e.Set(name, val)
+}
+
+// IsConst reports whether the innermost binding of name is a constant.
+func (e *Environment) IsConst(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if i := env.slot(name); i >= 0 && env.slots[i] != nil {
+			return env.constSlots[i]
+		}
+		if _, ok := env.store[name]; ok {
+			return env.constants[name]
+		}
+	}
+	return false
+}
+
+// Assign rebinds name in the innermost environment that defines it. It
+// reports false when name is not defined in any enclosing environment.
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if i := env.slot(name); i >= 0 && env.slots[i] != nil {
+			env.slots[i] = val
+			return true
+		}
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetLocal binds the local name, which the resolver found in slot, to val.
+func (e *Environment) SetLocal(slot int, name string, val Object) Object {
+	if slot >= len(e.slots) || e.names[slot] != name {
+		return e.Set(name, val)
+	}
+
+	e.slots[slot] = val
+	e.constSlots[slot] = false
+	return val
+}
+
+// SetLocalConst binds the local name, which the resolver found in slot, to
+// val and marks the binding as constant.
+func (e *Environment) SetLocalConst(slot int, name string, val Object) Object {
+	if slot >= len(e.slots) || e.names[slot] != name {
+		return e.SetConst(name, val)
+	}
+
+	e.slots[slot] = val
+	e.constSlots[slot] = true
+	return val
+}
+
+// local returns the environment that binds the local name, which the
+// resolver found in slot of the environment depth levels out, and whether
+// the local is in the slot. A local that is not bound yet, as in a closure
+// called before the function declares it, is looked up by name further out.
+func (e *Environment) local(depth, slot int, name string) (*Environment, bool) {
+	env := e
+	for ; depth > 0 && env.outer != nil; depth-- {
+		env = env.outer
+	}
+
+	if slot >= len(env.slots) || env.names[slot] != name {
+		return env, false
+	}
+	if env.slots[slot] == nil {
+		return env.outer, false
+	}
+	return env, true
+}
+
+// GetLocal returns the value of the local name, which the resolver found in
+// slot of the environment depth levels out.
+func (e *Environment) GetLocal(depth, slot int, name string) (Object, bool) {
+	env, ok := e.local(depth, slot, name)
+	switch {
+	case ok:
+		return env.slots[slot], true
+	case env == nil:
+		return nil, false
+	default:
+		return env.Get(name)
+	}
+}
+
+// AssignLocal rebinds the local name, which the resolver found in slot of
+// the environment depth levels out, like Assign.
+func (e *Environment) AssignLocal(depth, slot int, name string, val Object) bool {
+	env, ok := e.local(depth, slot, name)
+	switch {
+	case ok:
+		env.slots[slot] = val
+		return true
+	case env == nil:
+		return false
+	default:
+		return env.Assign(name, val)
+	}
+}
+
+// IsConstLocal reports whether the innermost binding of the local name,
+// which the resolver found in slot of the environment depth levels out, is
+// a constant.
+func (e *Environment) IsConstLocal(depth, slot int, name string) bool {
+	env, ok := e.local(depth, slot, name)
+	switch {
+	case ok:
+		return env.constSlots[slot]
+	case env == nil:
+		return false
+	default:
+		return env.IsConst(name)
+	}
+}
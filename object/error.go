@@ -0,0 +1,100 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/emo-lang/emo/token"
+)
+
+// Kinds of errors. Errors raised by the interpreter and builtins use one of
+// the specific kinds; `throw` with a plain value raises an ERROR.
+const (
+	ERROR            = "Error"
+	RUNTIME_ERROR    = "RuntimeError"
+	TYPE_ERROR       = "TypeError"
+	NAME_ERROR       = "NameError"
+	ARGUMENT_ERROR   = "ArgumentError"
+	INDEX_ERROR      = "IndexError"
+	KEY_ERROR        = "KeyError"
+	ACCESS_ERROR     = "AccessError"
+	MATCH_ERROR      = "MatchError"
+	IMPORT_ERROR     = "ImportError"
+	IO_ERROR         = "IOError"
+	PERMISSION_ERROR = "PermissionError"
+	RECURSION_ERROR  = "RecursionError"
+)
+
+// StackFrame is a function call that was active when an Error was raised.
+type StackFrame struct {
+	Function string         // name of the called function
+	Call     token.Position // where the function was called from
+}
+
+// Error is an error in flight: it propagates up through the evaluator until
+// it is caught by a try expression or reaches the top of the program.
+type Error struct {
+	Kind    string
+	Message string
+	Value   Object         // the thrown value, nil for interpreter errors
+	Pos     token.Position // where the error was raised
+	Stack   []StackFrame   // innermost call first
+}
+
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+	return "ERROR: " + e.Message
+}
+
+// Frames describes each active call, innermost first, with the location that
+// was being executed in that frame.
+func (e *Error) Frames() []string {
+	frames := []string{}
+
+	pos := e.Pos
+	for _, frame := range e.Stack {
+		frames = append(frames, fmt.Sprintf("at %s (%s)", frame.Function, pos))
+		pos = frame.Call
+	}
+	frames = append(frames, fmt.Sprintf("at <main> (%s)", pos))
+
+	return frames
+}
+
+// StackTrace renders Frames one per line. A frame repeated by recursion is
+// rendered once, with the number of repetitions.
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+
+	frames := e.Frames()
+	for i := 0; i < len(frames); {
+		n := 1
+		for i+n < len(frames) && frames[i+n] == frames[i] {
+			n++
+		}
+
+		out.WriteString("  " + frames[i] + "\n")
+		if n > 1 {
+			fmt.Fprintf(&out, "  ... repeated %d more times\n", n-1)
+		}
+
+		i += n
+	}
+
+	return out.String()
+}
+
+// Exception is a caught Error as seen by the script, e.g. the `e` in
+// `catch e { }`. Unlike Error it is an ordinary value that can be stored,
+// passed around and thrown again.
+type Exception struct {
+	Error *Error
+}
+
+func (ex *Exception) Type() ObjectType { return EXCEPTION_OBJ }
+func (ex *Exception) Inspect() string {
+	return ex.Error.Kind + ": " + ex.Error.Message
+}
@@ -0,0 +1,69 @@
+package object
+
+// Freeze returns obj with its arrays and hashes, and those nested in them,
+// replaced by immutable copies. Constants hold frozen values, while the
+// values they are declared from stay mutable.
+func Freeze(obj Object) Object {
+	return freeze(obj, map[Object]Object{})
+}
+
+// freeze is Freeze with the copies made so far, for values that contain
+// themselves.
+func freeze(obj Object, copies map[Object]Object) Object {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.Frozen {
+			return obj
+		}
+		if c, ok := copies[obj]; ok {
+			return c
+		}
+
+		c := &Array{Elements: make([]Object, len(obj.Elements)), Frozen: true}
+		copies[obj] = c
+		for i, el := range obj.Elements {
+			c.Elements[i] = freeze(el, copies)
+		}
+
+		return c
+	case *Hash:
+		if obj.Frozen {
+			return obj
+		}
+		if c, ok := copies[obj]; ok {
+			return c
+		}
+
+		c := &Hash{Pairs: make(map[HashKey]HashPair, len(obj.Pairs)), Frozen: true}
+		copies[obj] = c
+		for key, pair := range obj.Pairs {
+			c.Pairs[key] = HashPair{Key: pair.Key, Value: freeze(pair.Value, copies)}
+		}
+
+		return c
+	case *Tuple:
+		if elements, ok := freezeAll(obj.Elements, copies); ok {
+			return &Tuple{Elements: elements}
+		}
+	case *EnumValue:
+		if values, ok := freezeAll(obj.Values, copies); ok {
+			return &EnumValue{Variant: obj.Variant, Values: values}
+		}
+	}
+
+	return obj
+}
+
+// freezeAll freezes the elements of an immutable value, and reports whether
+// any of them changed.
+func freezeAll(elements []Object, copies map[Object]Object) ([]Object, bool) {
+	frozen := make([]Object, len(elements))
+	changed := false
+
+	for i, el := range elements {
+		frozen[i] = freeze(el, copies)
+		changed = changed || frozen[i] != el
+	}
+
+	return frozen, changed
+}
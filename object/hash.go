@@ -3,6 +3,7 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -16,7 +17,8 @@ type HashPair struct {
 }
 
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	Pairs  map[HashKey]HashPair
+	Frozen bool // cannot be modified, see Freeze
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -25,7 +27,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.SortedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
@@ -37,3 +39,27 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// SortedPairs returns the pairs ordered by key type and then by key, giving
+// hashes a stable iteration and printing order.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i].Key, pairs[j].Key
+		if a.Type() != b.Type() {
+			return a.Type() < b.Type()
+		}
+
+		if a, ok := a.(*Integer); ok {
+			return a.Value < b.(*Integer).Value
+		}
+
+		return a.Inspect() < b.Inspect()
+	})
+
+	return pairs
+}
@@ -0,0 +1,25 @@
+package object
+
+import (
+	"bytes"
+
+	"github.com/emo-lang/emo/ast"
+)
+
+// Interface is a set of method signatures that classes declare to
+// implement with `class X implements Y`.
+type Interface struct {
+	Name    *ast.Identifier
+	Methods map[string]*ast.InterfaceMethod
+}
+
+func (iface *Interface) Type() ObjectType { return INTERFACE_OBJ }
+func (iface *Interface) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("interface ")
+	out.WriteString(iface.Name.Value)
+	out.WriteString(" {}")
+
+	return out.String()
+}
@@ -0,0 +1,21 @@
+package object
+
+import "strings"
+
+// Module is a file imported with `import`. Its top-level bindings are
+// reached with `module.name`, except the private ones, whose names start
+// with an underscore.
+type Module struct {
+	Name string // the file name without its extension
+	Path string
+	Env  Scope // where its top-level bindings are looked up
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module " + m.Name }
+
+// IsPrivateName reports whether the top-level binding name is private to
+// its module.
+func IsPrivateName(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
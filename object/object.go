@@ -24,6 +24,22 @@ const (
 	ERROR_OBJ          = "ERROR"
 	CLASS_OBJ          = "CLASS"
 	CLASS_INSTANCE_OBJ = "CLASS_INSTANCE"
+	EXCEPTION_OBJ      = "EXCEPTION"
+	RANGE_OBJ          = "RANGE"
+	BREAK_OBJ          = "BREAK"
+	CONTINUE_OBJ       = "CONTINUE"
+	SUPER_OBJ          = "SUPER"
+	INTERFACE_OBJ      = "INTERFACE"
+	TUPLE_OBJ          = "TUPLE"
+	ENUM_OBJ           = "ENUM"
+	ENUM_VARIANT_OBJ   = "ENUM_VARIANT"
+	ENUM_VALUE_OBJ     = "ENUM_VALUE"
+	MODULE_OBJ         = "MODULE"
+
+	COMPILED_FUNCTION_OBJ  = "COMPILED_FUNCTION"
+	COMPILED_CLASS_OBJ     = "COMPILED_CLASS"
+	COMPILED_INTERFACE_OBJ = "COMPILED_INTERFACE"
+	COMPILED_ENUM_OBJ      = "COMPILED_ENUM"
 )
 
 type Object interface {
@@ -57,20 +73,47 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
-type Error struct {
-	Message string
+// Break and Continue signal a break or continue statement to the enclosing
+// loop, the way ReturnValue signals a return to the enclosing function.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Range is the half-open integer interval [Start, End) created by `a..b`.
+type Range struct {
+	Start int64
+	End   int64
 }
 
-func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string  { return fmt.Sprintf("%d..%d", r.Start, r.End) }
 
 type Function struct {
-	Parameters []*ast.TypedField
-	Body       *ast.BlockStatement
-	Env        *Environment
+	Name        string // empty for function literals
+	Parameters  []*ast.TypedField
+	ReturnTypes []*ast.Identifier
+	Body        *ast.BlockStatement
+	Locals      []string       // the slots of the locals, nil if not resolved
+	Slots       map[string]int // the slot of each local
+	Env         *Environment
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// DisplayName is the name used for the function in stack traces.
+func (f *Function) DisplayName() string {
+	if f.Name == "" {
+		return "<anonymous>"
+	}
+	return f.Name
+}
+
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
@@ -105,6 +148,14 @@ type Builtin struct {
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "<builtin function>" }
 
+// Callable is a function value that is not run by the evaluator, such as a
+// closure of a compiled program, called with positional arguments when it
+// is passed to a builtin.
+type Callable interface {
+	Object
+	Call(args ...Object) Object
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/emo-lang/emo/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -21,3 +25,58 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFunctionEnvironment(t *testing.T) {
+	globals := NewEnvironment()
+	globals.Set("x", &Integer{Value: 1})
+
+	env := NewFunctionEnvironment(globals, []string{"a", "x"}, map[string]int{"a": 0, "x": 1})
+	env.SetLocal(0, "a", &Integer{Value: 2})
+
+	// x has a slot but is not bound yet, so the global is seen
+	if val, ok := env.GetLocal(0, 1, "x"); !ok || val.(*Integer).Value != 1 {
+		t.Errorf("wrong value for unbound local x. got=%v", val)
+	}
+
+	env.SetLocalConst(1, "x", &Integer{Value: 3})
+
+	if val, ok := env.Get("x"); !ok || val.(*Integer).Value != 3 {
+		t.Errorf("wrong value for x. got=%v", val)
+	}
+
+	if !env.IsConstLocal(0, 1, "x") || !env.IsConst("x") {
+		t.Errorf("local x is not constant")
+	}
+
+	inner := NewEnclosedEnvironment(env)
+	if !inner.AssignLocal(1, 0, "a", &Integer{Value: 4}) {
+		t.Fatalf("AssignLocal failed")
+	}
+
+	if val, _ := env.GetLocal(0, 0, "a"); val.(*Integer).Value != 4 {
+		t.Errorf("wrong value for a. got=%v", val)
+	}
+
+	if val, _ := globals.Get("x"); val.(*Integer).Value != 1 {
+		t.Errorf("global x was changed")
+	}
+
+	if inner.Global() != globals || globals.Global() != globals {
+		t.Errorf("wrong global environment")
+	}
+}
+
+func TestStackTraceRepeatedFrames(t *testing.T) {
+	// f calling itself twice from 2:3 and failing there
+	pos := token.Position{Line: 2, Column: 3}
+	err := &Error{Pos: pos, Stack: []StackFrame{
+		{Function: "f", Call: pos},
+		{Function: "f", Call: pos},
+		{Function: "f", Call: token.Position{Line: 5, Column: 1}},
+	}}
+
+	expected := "  at f (2:3)\n  ... repeated 2 more times\n  at <main> (5:1)\n"
+	if got := err.StackTrace(); got != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, got)
+	}
+}
@@ -0,0 +1,27 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Tuple is the fixed group of values returned by `return a, b`.
+type Tuple struct {
+	Elements []Object
+}
+
+func (t *Tuple) Type() ObjectType { return TUPLE_OBJ }
+func (t *Tuple) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range t.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
@@ -0,0 +1,381 @@
+// Package optimizer rewrites programs before they run, without changing
+// what they compute. It folds operators over literals, replaces the
+// constants defined at the top level of a program with their literal
+// values, and removes the branches of if expressions whose condition is a
+// literal boolean.
+//
+// Operators are folded with the operations of the evaluator, and only when
+// they succeed: an expression such as 1 / 0 is kept and fails when the
+// program runs, at the same position. A constant is replaced after its
+// definition, in the top-level statements and in the functions defined
+// there.
+package optimizer
+
+import (
+	"maps"
+	"strconv"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/evaluator"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// Optimize rewrites program in place and returns it.
+func Optimize(program *ast.Program) *ast.Program {
+	o := &optimizer{visible: map[string]ast.Expression{}}
+	program.Statements = o.statements(program.Statements, true)
+
+	o.constants = constants(program)
+	if len(o.constants) > 0 {
+		o.visible = map[string]ast.Expression{}
+		program.Statements = o.statements(program.Statements, true)
+	}
+
+	return program
+}
+
+type optimizer struct {
+	// the constants of the program, by name
+	constants map[string]ast.Expression
+	// the constants that can be replaced where the optimizer is
+	visible map[string]ast.Expression
+}
+
+// constants returns the constants defined once by the top-level statements
+// of program with a literal value.
+func constants(program *ast.Program) map[string]ast.Expression {
+	consts := map[string]ast.Expression{}
+	defined := map[string]int{}
+
+	for _, stmt := range program.Statements {
+		name, value := constant(stmt)
+		if name == "" {
+			continue
+		}
+
+		defined[name]++
+		if isLiteral(value) {
+			consts[name] = value
+		}
+	}
+
+	for name, n := range defined {
+		if n > 1 {
+			delete(consts, name)
+		}
+	}
+
+	return consts
+}
+
+// constant returns the name and the value of a statement defining a
+// constant, or an empty name. The name of a function, class, interface or
+// enum declared by the statement is returned without a value, as it is
+// defined again.
+func constant(stmt ast.Statement) (string, ast.Expression) {
+	switch stmt := stmt.(type) {
+	case *ast.DefineStatement:
+		return stmt.Name.Value, stmt.Value
+	case *ast.VarStatement:
+		if stmt.Token.Type == token.CONST {
+			return stmt.Name.Value, stmt.Value
+		}
+	case *ast.ExpressionStatement:
+		switch expr := stmt.Expression.(type) {
+		case *ast.FunctionDefinition:
+			return expr.Name.Value, nil
+		case *ast.ClassExpression:
+			return expr.Name.Value, nil
+		case *ast.InterfaceExpression:
+			return expr.Name.Value, nil
+		case *ast.EnumExpression:
+			return expr.Name.Value, nil
+		}
+	}
+
+	return "", nil
+}
+
+func isLiteral(node ast.Expression) bool {
+	switch node.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	default:
+		return false
+	}
+}
+
+// statements optimizes a list of statements, the program when top is set.
+// The statements of the branch an if statement always takes replace it.
+func (o *optimizer) statements(stmts []ast.Statement, top bool) []ast.Statement {
+	out := make([]ast.Statement, 0, len(stmts))
+
+	for i, stmt := range stmts {
+		stmt = o.statement(stmt)
+
+		if branch := taken(stmt); branch != nil {
+			// the value of a trailing if with an empty branch is nil,
+			// not the value of the statement before it
+			if len(branch.Statements) > 0 || i < len(stmts)-1 {
+				out = append(out, branch.Statements...)
+				continue
+			}
+		}
+
+		out = append(out, stmt)
+
+		if top {
+			if name, _ := constant(stmt); name != "" {
+				if value, ok := o.constants[name]; ok {
+					o.visible[name] = value
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// taken returns the branch an if statement always takes, an empty block if
+// it takes none, or nil if stmt is not such a statement.
+func taken(stmt ast.Statement) *ast.BlockStatement {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+
+	ie, ok := es.Expression.(*ast.IfExpression)
+	if !ok {
+		return nil
+	}
+
+	cond, ok := ie.Condition.(*ast.Boolean)
+	switch {
+	case !ok:
+		return nil
+	case cond.Value:
+		return ie.Consequence
+	case ie.Alternative != nil:
+		return ie.Alternative
+	default:
+		return &ast.BlockStatement{}
+	}
+}
+
+func (o *optimizer) statement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expression(stmt.Expression)
+	case *ast.VarStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.DefineStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expression(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.DestructuringStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.BlockStatement:
+		o.block(stmt)
+	case *ast.WhileStatement:
+		stmt.Condition = o.expression(stmt.Condition)
+		o.block(stmt.Body)
+	case *ast.ForStatement:
+		stmt.Iterable = o.expression(stmt.Iterable)
+		o.block(stmt.Body)
+	}
+
+	return stmt
+}
+
+func (o *optimizer) block(block *ast.BlockStatement) {
+	if block != nil {
+		block.Statements = o.statements(block.Statements, false)
+	}
+}
+
+func (o *optimizer) expressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		exps[i] = o.expression(exp)
+	}
+}
+
+func (o *optimizer) expression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if lit, ok := o.visible[exp.Value]; ok {
+			// a copy at the position of the name, for errors
+			return fold(exp, value(lit))
+		}
+	case *ast.ParenExpression:
+		exp.Expression = o.expression(exp.Expression)
+		if val := value(exp.Expression); val != nil {
+			return fold(exp, val)
+		}
+	case *ast.PrefixExpression:
+		exp.Right = o.expression(exp.Right)
+		if right := value(exp.Right); right != nil {
+			return fold(exp, evaluator.Prefix(exp.Operator, right))
+		}
+	case *ast.InfixExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Right = o.expression(exp.Right)
+		left, right := value(exp.Left), value(exp.Right)
+		if left != nil && right != nil {
+			return fold(exp, evaluator.Infix(exp.Operator, left, right))
+		}
+	case *ast.IfExpression:
+		exp.Condition = o.expression(exp.Condition)
+		o.block(exp.Consequence)
+		o.block(exp.Alternative)
+
+		// an if with a single expression in the branch it takes is that
+		// expression
+		if branch := taken(&ast.ExpressionStatement{Expression: exp}); branch != nil && len(branch.Statements) == 1 {
+			if es, ok := branch.Statements[0].(*ast.ExpressionStatement); ok {
+				return es.Expression
+			}
+		}
+	case *ast.AssignExpression:
+		// the target is assigned, not read
+		switch target := exp.Target.(type) {
+		case *ast.IndexExpression:
+			target.Left = o.expression(target.Left)
+			target.Index = o.expression(target.Index)
+		case *ast.DotExpression:
+			target.Left = o.expression(target.Left)
+		}
+		exp.Value = o.expression(exp.Value)
+	case *ast.ArrayLiteral:
+		o.expressions(exp.Elements)
+	case *ast.TupleLiteral:
+		o.expressions(exp.Elements)
+	case *ast.HashLiteral:
+		for key, value := range exp.Pairs {
+			exp.Pairs[key] = o.expression(value)
+		}
+	case *ast.IndexExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Index = o.expression(exp.Index)
+	case *ast.DotExpression:
+		exp.Left = o.expression(exp.Left)
+	case *ast.CallExpression:
+		exp.Function = o.expression(exp.Function)
+		o.expressions(exp.Arguments)
+	case *ast.NamedArgument:
+		exp.Value = o.expression(exp.Value)
+	case *ast.NewExpression:
+		o.expressions(exp.Arguments)
+	case *ast.TryExpression:
+		o.block(exp.Block)
+		o.block(exp.Catch)
+		o.block(exp.Finally)
+	case *ast.MatchExpression:
+		exp.Value = o.expression(exp.Value)
+		for _, arm := range exp.Arms {
+			switch body := arm.Body.(type) {
+			case *ast.BlockStatement:
+				o.block(body)
+			case ast.Expression:
+				arm.Body = o.expression(body)
+			}
+		}
+	case *ast.FunctionLiteral:
+		o.function(exp.Parameters, exp.Body)
+	case *ast.FunctionDefinition:
+		o.function(exp.Parameters, exp.Body, exp.Name.Value)
+	case *ast.ClassExpression:
+		o.class(exp)
+	}
+
+	return exp
+}
+
+// function optimizes the parameter defaults and the body of a function,
+// where the parameters and the names hide the constants they are named
+// after.
+func (o *optimizer) function(params []*ast.TypedField, body *ast.BlockStatement, names ...string) {
+	for _, param := range params {
+		names = append(names, param.Name.Value)
+	}
+	defer o.hide(names)()
+
+	for _, param := range params {
+		if param.Default != nil {
+			param.Default = o.expression(param.Default)
+		}
+	}
+	o.block(body)
+}
+
+// hide makes the visible constants but names visible in a function, and
+// returns a function restoring the visible constants.
+func (o *optimizer) hide(names []string) func() {
+	outer := o.visible
+
+	o.visible = maps.Clone(outer)
+	for _, name := range names {
+		delete(o.visible, name)
+	}
+
+	return func() { o.visible = outer }
+}
+
+// class optimizes the field initializers and the methods of a class, which
+// run when the class is instantiated and when its methods are called.
+func (o *optimizer) class(class *ast.ClassExpression) {
+	restore := o.hide(nil)
+	for _, field := range class.Fields {
+		if field.Value != nil {
+			field.Value = o.expression(field.Value)
+		}
+	}
+	restore()
+
+	for _, method := range class.Methods {
+		def := method.Function
+		o.function(def.Parameters, def.Body, def.Name.Value, "self", "super")
+	}
+}
+
+// value returns the value of a literal, or nil.
+func value(exp ast.Expression) object.Object {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: exp.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: exp.Value}
+	case *ast.Boolean:
+		if exp.Value {
+			return evaluator.TRUE
+		}
+		return evaluator.FALSE
+	default:
+		return nil
+	}
+}
+
+// fold returns the literal of the value of exp, or exp if its operands are
+// not all literals or its value has no literal.
+func fold(exp ast.Expression, val object.Object) ast.Expression {
+	tok := token.Token{Pos: exp.Pos(), End: exp.End()}
+
+	switch val := val.(type) {
+	case *object.Integer:
+		tok.Type, tok.Literal = token.INT, strconv.FormatInt(val.Value, 10)
+		return &ast.IntegerLiteral{Token: tok, Value: val.Value}
+	case *object.String:
+		tok.Type, tok.Literal = token.STRING, val.Value
+		return &ast.StringLiteral{Token: tok, Value: val.Value}
+	case *object.Boolean:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if val.Value {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		return &ast.Boolean{Token: tok, Value: val.Value}
+	default:
+		return exp
+	}
+}
@@ -0,0 +1,98 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/evaluator"
+	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors in %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"60 * 60 * 24", "86400"},
+		{"-(2 + 3)", "-5"},
+		{"!true", "false"},
+		{"\"a\" + \"b\" == \"ab\"", "true"},
+		{"1 < 2 == true", "true"},
+		{"x * (2 + 3)", "(x * 5)"},
+		// operations that fail are left to fail when the program runs
+		{"1 / 0", "(1 / 0)"},
+		{"1 + true", "(1 + true)"},
+		{"1..3", "(1 .. 3)"},
+		{"define(DAY, 60 * 60 * 24)\nDAY * 7", "define DAY = 86400;604800"},
+		{"const N = 2\nN + N", "const N = 2;4"},
+		// constants are replaced after their definition, in functions too
+		{"N\nconst N = 2", "Nconst N = 2;"},
+		{"const N = 2\nfunc f() { return N }", "const N = 2;func f() return 2;"},
+		{"func f() { return N }\nconst N = 2", "func f() return N;const N = 2;"},
+		{"const N = 2\nfunc f(N: Int) { return N }", "const N = 2;func f(<N:Int>) return N;"},
+		{"const N = 2\nN = 3", "const N = 2;N = 3"},
+		{"const N = 2\nvar h = {N: N}", "const N = 2;var h = {N:2};"},
+		{"const N = [1]\nN", "const N = [1];N"},
+		// names declared again are not replaced
+		{"const P = 1\nclass P {}\nP", "const P = 1;<class P>P"},
+		{"const F = 1\nfunc F() {}\nF", "const F = 1;func F() F"},
+		{"if true { 1 } else { 2 }", "1"},
+		{"if false { 1 } else { 2 }", "2"},
+		{"if 1 > 2 { 1 }\n3", "3"},
+		{"var x = if 2 > 1 { 1 } else { 2 }", "var x = 1;"},
+		{"if false { 1 }", "iffalse 1"},
+		{"if true { var a = 1\nvar b = 2 }\na + b", "var a = 1;var b = 2;(a + b)"},
+	}
+
+	for _, tt := range tests {
+		program := Optimize(parse(t, tt.input))
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("wrong program for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestOptimizedProgramsRunTheSame(t *testing.T) {
+	inputs := []string{
+		"60 * 60 * 24",
+		"var x = 2\nx * (3 + 4)",
+		"1 / 0",
+		"define(MAX, 3)\nvar a = 1 + true",
+		"if false { 1 }",
+		"var n = 0\nif true { var n = 5 }\nn",
+		"const N = 2\nfunc f(x: Int, by: Int = N * 2) { return x + by }\nf(1)",
+		"const N = 2\nN += 1",
+		"class P { var n: Int = 1 + 1\nfunc get() { return self.n * LIMIT } }\ndefine(LIMIT, 10)\nnew(P).get()",
+		"func f() { return MAX }\nf()\ndefine(MAX, 3)",
+		"define(MAX, 3)\nfunc f() { return MAX }\nf()",
+		"const P = 1\nclass P {}\nP",
+		"define(G, 2)\ninterface G { func f() }\nG",
+		"const E = 3\nenum E { A }\nE",
+	}
+
+	for _, input := range inputs {
+		expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+		got := evaluator.Eval(Optimize(parse(t, input)), object.NewEnvironment())
+
+		if expected.Inspect() != got.Inspect() {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", input, expected.Inspect(), got.Inspect())
+		}
+
+		if err, ok := expected.(*object.Error); ok && err.Pos != got.(*object.Error).Pos {
+			t.Errorf("wrong error position for %q. expected=%s, got=%s", input, err.Pos, got.(*object.Error).Pos)
+		}
+	}
+}
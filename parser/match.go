@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/token"
+)
+
+// parse the following code:
+//
+//	enum Status {
+//	  Active
+//	  Suspended(reason: String)
+//	}
+func (p *Parser) parseEnumExpression() ast.Expression {
+	enum := &ast.EnumExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	enum.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+
+	for {
+		p.nextToken()
+		p.skipSeparators()
+
+		if p.curTokenIs(token.RBRACE) {
+			break
+		}
+
+		if !p.curTokenIs(token.IDENT) {
+			p.errorf(p.curToken.Pos, "expected variant of enum %s, got %s instead", enum.Name.Value, p.curToken.Type)
+			return nil
+		}
+
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if seen[variant.Name.Value] {
+			p.errorf(variant.Name.Pos(), "variant %s is declared more than once in enum %s", variant.Name.Value, enum.Name.Value)
+		}
+		seen[variant.Name.Value] = true
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			variant.Fields = p.parseVariantFields()
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+
+		enum.Variants = append(enum.Variants, variant)
+	}
+
+	enum.Rbrace = p.curToken
+
+	return enum
+}
+
+// parseVariantFields parses `(name: Type, ...)` after an enum variant.
+func (p *Parser) parseVariantFields() []*ast.TypedField {
+	fields := []*ast.TypedField{}
+
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		field := p.parseTypedField()
+		if field == nil {
+			return nil
+		}
+
+		fields = append(fields, field)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return fields
+}
+
+// skipSeparators skips the newlines and commas between enum variants and
+// match arms.
+func (p *Parser) skipSeparators() {
+	for p.curTokenIs(token.NEWLINE) || p.curTokenIs(token.COMMA) {
+		p.nextToken()
+	}
+}
+
+// parse the following code:
+//
+//	match status {
+//	  Status.Active -> "active"
+//	  Status.Suspended(reason) -> { println(reason) }
+//	  _ -> "unknown"
+//	}
+func (p *Parser) parseMatchExpression() ast.Expression {
+	match := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	match.Value = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for {
+		p.nextToken()
+		p.skipSeparators()
+
+		if p.curTokenIs(token.RBRACE) {
+			break
+		}
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+
+		match.Arms = append(match.Arms, arm)
+	}
+
+	match.Rbrace = p.curToken
+
+	if len(match.Arms) == 0 {
+		p.errorf(match.Pos(), "match has no arms")
+		return nil
+	}
+
+	return match
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		arm.Body = p.parseBlockStatement()
+	} else {
+		p.nextToken()
+
+		body := p.parseExpression(LOWEST)
+		if body == nil {
+			return nil
+		}
+		arm.Body = body
+	}
+
+	return arm
+}
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.INT, token.STRING, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Value: p.prefixParseFns[p.curToken.Type]()}
+	case token.MINUS:
+		minus := p.curToken
+		if !p.expectPeek(token.INT) {
+			return nil
+		}
+
+		return &ast.LiteralPattern{Value: &ast.PrefixExpression{Token: minus, Operator: "-", Right: p.parseIntegerLiteral()}}
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTokenIs(token.DOT) {
+			return p.parseVariantPattern(ident)
+		}
+
+		return &ast.BindingPattern{Name: ident}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	default:
+		p.errorf(p.curToken.Pos, "unexpected %s in pattern", p.curToken.Type)
+		return nil
+	}
+}
+
+// parsePatternList parses patterns separated by commas up to end.
+func (p *Parser) parsePatternList(end token.TokenType) []ast.Pattern {
+	patterns := []ast.Pattern{}
+
+	for !p.peekTokenIs(end) {
+		p.nextToken()
+
+		pattern := p.parsePattern()
+		if pattern == nil {
+			return nil
+		}
+
+		patterns = append(patterns, pattern)
+
+		if !p.peekTokenIs(end) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return patterns
+}
+
+func (p *Parser) parseVariantPattern(enum *ast.Identifier) ast.Pattern {
+	pattern := &ast.VariantPattern{Enum: enum}
+
+	p.nextToken()
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	pattern.Variant = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+
+		pattern.Arguments = p.parsePatternList(token.RPAREN)
+		if pattern.Arguments == nil {
+			return nil
+		}
+
+		pattern.Rparen = p.curToken
+	}
+
+	return pattern
+}
+
+// [first, second]
+// [first, ...rest]
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		// `...rest` must be the last element
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	pattern.Rbracket = p.curToken
+
+	return pattern
+}
+
+// {name: "ann", age}
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		if !p.curTokenIs(token.IDENT) && !p.curTokenIs(token.STRING) {
+			p.errorf(p.curToken.Pos, "expected key in hash pattern, got %s instead", p.curToken.Type)
+			return nil
+		}
+
+		pair := &ast.HashPatternPair{Key: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if p.peekTokenIs(token.COLON) || p.curTokenIs(token.STRING) {
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+
+			pair.Pattern = p.parsePattern()
+			if pair.Pattern == nil {
+				return nil
+			}
+		} else {
+			// {age} binds the value of the key age to age
+			pair.Pattern = &ast.BindingPattern{Name: pair.Key}
+		}
+
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	pattern.Rbrace = p.curToken
+
+	return pattern
+}
@@ -12,8 +12,10 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN      // = or +=
 	EQAULS      // ==
 	LESSGREATER // > or <
+	RANGE       // 0..10
 	SUM         // +
 	PRODUCT     // *
 	PRIFIX      // -X or !X
@@ -28,17 +30,23 @@ type (
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQAULS,
-	token.NOT_EQ:   EQAULS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
-	token.DOT:      DOT,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.EQ:              EQAULS,
+	token.NOT_EQ:          EQAULS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.DOTDOT:          RANGE,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
+	token.DOT:             DOT,
 }
 
 type Parser struct {
@@ -49,6 +57,10 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
+	// number of loops enclosing the current token within the current
+	// function, used to reject stray break and continue statements
+	loopDepth int
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
@@ -85,6 +97,7 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.SELF, p.parseIdentifier)
+	p.registerPrefix(token.SUPER, p.parseIdentifier)
 	p.registerPrefix(token.NEW, p.parseNewExpression)
 
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
@@ -102,8 +115,12 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionExpression)
 	p.registerPrefix(token.CLASS, p.parseClassExpression)
+	p.registerPrefix(token.INTERFACE, p.parseInterfaceExpression)
+	p.registerPrefix(token.ENUM, p.parseEnumExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -114,6 +131,13 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.DOTDOT, p.parseInfixExpression)
+
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
@@ -152,6 +176,8 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		return nil
 	}
 
+	hash.Rbrace = p.curToken
+
 	return hash
 }
 
@@ -164,6 +190,8 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		return nil
 	}
 
+	exp.Rbracket = p.curToken
+
 	return exp
 
 }
@@ -183,41 +211,70 @@ func (p *Parser) parseNewExpression() ast.Expression {
 
 	if p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
-		exp.Data = p.parseHashLiteral()
+		exp.Arguments = p.parseArguments(token.RPAREN)
+	} else if !p.expectPeek(token.RPAREN) {
+		return nil
 	}
 
-	if !p.expectPeek(token.RPAREN) {
-		// return nil
-	}
+	exp.Rparen = p.curToken
 
 	return exp
 }
 
 func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
-	switch left := left.(type) {
-	case *ast.Identifier:
-		exp := &ast.DotExpression{Token: p.curToken, Left: left}
-		isSelf := (left.Value == "self")
+	exp := &ast.DotExpression{Token: p.curToken, Left: left}
 
-		p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
-		if isSelf {
-			exp.Right = p.parseExpression(CALL)
-		} else {
-			exp.Right = p.parseExpression(LOWEST)
-		}
+	// only the member name belongs to the dot expression, so `p.greet()`
+	// calls the member and `e.kind + "!"` adds to it
+	exp.Right = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// x = 1, arr[0] += 2, self.age = 33
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
 
-		return exp
+	// (a) = 1 assigns to a
+	for paren, ok := target.(*ast.ParenExpression); ok; paren, ok = target.(*ast.ParenExpression) {
+		target = paren.Expression
+	}
+	exp.Target = target
+
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if target.Value == "self" {
+			p.errorf(target.Pos(), "cannot assign to self")
+			return nil
+		}
+	case *ast.IndexExpression, *ast.DotExpression:
+	case nil:
+		return nil
 	default:
+		p.errorf(exp.Token.Pos, "cannot assign to %s", target.String())
 		return nil
 	}
 
+	p.nextToken()
+
+	// assignments are right associative: a = b = 1
+	exp.Value = p.parseExpression(LOWEST)
+
+	return exp
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
 	return array
 }
 
@@ -245,13 +302,55 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	return list
 }
 
+// parseArguments parses call arguments up to end, where each argument is
+// either an expression or `name: expression`.
+func (p *Parser) parseArguments(end token.TokenType) []ast.Expression {
+	args := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return args
+	}
+
+	p.nextToken()
+	args = append(args, p.parseArgument())
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		args = append(args, p.parseArgument())
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return args
+}
+
+func (p *Parser) parseArgument() ast.Expression {
+	if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+		arg := &ast.NamedArgument{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		p.nextToken()
+		p.nextToken()
+
+		arg.Value = p.parseExpression(LOWEST)
+
+		return arg
+	}
+
+	return p.parseExpression(LOWEST)
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseArguments(token.RPAREN)
+	exp.Rparen = p.curToken
 	return exp
 }
 
@@ -277,6 +376,35 @@ func (p *Parser) parseClassExpression() ast.Expression {
 
 	class.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	if p.peekTokenIs(token.EXTENDS) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		class.Super = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	// class Person implements Greeter, Named
+	if p.peekTokenIs(token.IMPLEMENTS) {
+		p.nextToken()
+
+		for {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+
+			iface := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			class.Implements = append(class.Implements, iface)
+
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -312,9 +440,76 @@ func (p *Parser) parseClassExpression() ast.Expression {
 		}
 	}
 
+	class.Rbrace = p.curToken
+
 	return class
 }
 
+// parse the following code:
+//
+//	interface Greeter {
+//	  func greet(name: String) -> String
+//	}
+func (p *Parser) parseInterfaceExpression() ast.Expression {
+	iface := &ast.InterfaceExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	iface.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	iface.Methods = make(map[string]*ast.InterfaceMethod)
+
+	for {
+		p.nextToken()
+
+		for p.curTokenIs(token.NEWLINE) {
+			p.nextToken()
+		}
+
+		if p.curTokenIs(token.RBRACE) {
+			break
+		}
+
+		if !p.curTokenIs(token.FUNCTION) {
+			p.errorf(p.curToken.Pos, "expected method signature in interface %s, got %s instead",
+				iface.Name.Value, p.curToken.Type)
+			return nil
+		}
+
+		method := &ast.InterfaceMethod{Token: p.curToken}
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		method.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		method.Parameters = p.parseFunctionParameters()
+
+		if p.peekTokenIs(token.ARROW) {
+			method.ReturnTypes = p.parseReturnTypes()
+		} else {
+			method.ReturnTypes = []*ast.Identifier{}
+		}
+
+		iface.Methods[method.Name.Value] = method
+	}
+
+	iface.Rbrace = p.curToken
+
+	return iface
+}
+
 func (p *Parser) parseClassField(class *ast.ClassExpression, isPublic bool) {
 	field := &ast.ClassField{}
 
@@ -325,6 +520,18 @@ func (p *Parser) parseClassField(class *ast.ClassExpression, isPublic bool) {
 	field.Field = p.parseTypedField()
 	field.Public = isPublic
 
+	if field.Field == nil {
+		return
+	}
+
+	// var age: Int = 18
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+
+		field.Value = p.parseExpression(LOWEST)
+	}
+
 	class.Fields[field.Field.Name.Value] = field
 }
 
@@ -391,7 +598,7 @@ func (p *Parser) parseFunctionDefinition() *ast.FunctionDefinition {
 		return nil
 	}
 
-	fn.Body = p.parseBlockStatement()
+	fn.Body = p.parseFunctionBody()
 
 	return fn
 }
@@ -416,11 +623,21 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Body = p.parseBlockStatement()
+	lit.Body = p.parseFunctionBody()
 
 	return lit
 }
 
+// parseFunctionBody parses the body of a function, where break and continue
+// cannot refer to loops around the function.
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	defer func() { p.loopDepth = loopDepth }()
+
+	return p.parseBlockStatement()
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.TypedField {
 	identifiers := []*ast.TypedField{}
 
@@ -431,16 +648,14 @@ func (p *Parser) parseFunctionParameters() []*ast.TypedField {
 
 	p.nextToken()
 
-	var tf = p.parseTypedField()
+	var tf = p.parseFunctionParameter()
 	identifiers = append(identifiers, tf)
 
-	// fmt.Println("current token after parse one params: ", p.curToken)
-	// fmt.Println("current token after parse one params, peek token: ", p.peekToken)
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 
-		ident := p.parseTypedField()
+		ident := p.parseFunctionParameter()
 		identifiers = append(identifiers, ident)
 	}
 
@@ -448,9 +663,60 @@ func (p *Parser) parseFunctionParameters() []*ast.TypedField {
 		return nil
 	}
 
+	p.checkFunctionParameters(identifiers)
+
 	return identifiers
 }
 
+// parseFunctionParameter parses `name: Type`, `name: Type = default` or
+// `...name: Type`.
+func (p *Parser) parseFunctionParameter() *ast.TypedField {
+	variadic := p.curTokenIs(token.ELLIPSIS)
+	if variadic && !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	tf := p.parseTypedField()
+	if tf == nil {
+		return nil
+	}
+
+	tf.Variadic = variadic
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+
+		tf.Default = p.parseExpression(LOWEST)
+	}
+
+	return tf
+}
+
+// checkFunctionParameters reports parameters that cannot be bound: a
+// variadic parameter must come last and has no default, and parameters
+// after one with a default need a default too.
+func (p *Parser) checkFunctionParameters(params []*ast.TypedField) {
+	hasDefault := false
+
+	for i, param := range params {
+		if param == nil {
+			return
+		}
+
+		switch {
+		case param.Variadic && i != len(params)-1:
+			p.errorf(param.Name.Pos(), "variadic parameter %s must be the last parameter", param.Name.Value)
+		case param.Variadic && param.Default != nil:
+			p.errorf(param.Name.Pos(), "variadic parameter %s cannot have a default value", param.Name.Value)
+		case param.Default != nil:
+			hasDefault = true
+		case hasDefault && !param.Variadic:
+			p.errorf(param.Name.Pos(), "parameter %s without a default value follows a parameter with one", param.Name.Value)
+		}
+	}
+}
+
 func (p *Parser) parseParameter() *ast.Identifier {
 	fmt.Println("parse one parameter: ", p.curToken)
 
@@ -469,12 +735,14 @@ func (p *Parser) parseReturnTypes() []*ast.Identifier {
 	// skip: `->`
 	p.nextToken()
 
-	if p.curTokenIs(token.LPAREN) {
+	if p.peekTokenIs(token.LPAREN) {
 		// parsing: (Foo, Bar)
 		p.nextToken()
 
 		for !p.peekTokenIs(token.RPAREN) {
-			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
 
 			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 			identifiers = append(identifiers, ident)
@@ -484,15 +752,19 @@ func (p *Parser) parseReturnTypes() []*ast.Identifier {
 			}
 		}
 
+		p.nextToken()
+
 		return identifiers
 	}
 
 	// if not starting with `(`, then it should be a single type
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
 	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, ident)
 
-	p.nextToken()
-
 	return identifiers
 }
 
@@ -521,6 +793,49 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return &expression
 }
 
+// try { ... } catch e { ... } finally { ... }
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.IDENT) {
+			p.nextToken()
+			expression.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errorf(expression.Token.Pos, "try must be followed by catch or finally")
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
@@ -536,18 +851,23 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	block.Rbrace = p.curToken
+
 	return block
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	exp := &ast.ParenExpression{Token: p.curToken}
 	p.nextToken()
 
-	exp := p.parseExpression(LOWEST)
+	exp.Expression = p.parseExpression(LOWEST)
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
+	exp.Rparen = p.curToken
+
 	return exp
 }
 
@@ -583,8 +903,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorf(p.curToken.Pos, "no prefix parse function for %s found", t)
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
@@ -596,8 +915,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken.Pos, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -610,11 +928,15 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// errorf records a parse error prefixed with the source position it refers to.
+func (p *Parser) errorf(pos token.Position, format string, a ...any) {
+	msg := fmt.Sprintf(format, a...)
+	p.errors = append(p.errors, fmt.Sprintf("%s: %s", pos, msg))
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %+v instead",
+	p.errorf(p.peekToken.Pos, "expected next token to be %s, got %+v instead",
 		t, p.peekToken)
-
-	p.errors = append(p.errors, msg)
 }
 
 func (p *Parser) nextToken() {
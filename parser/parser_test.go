@@ -6,6 +6,7 @@ import (
 
 	"github.com/emo-lang/emo/ast"
 	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/token"
 )
 
 func TestLetStatments(t *testing.T) {
@@ -426,3 +427,663 @@ func TestStringLiteralExpression(t *testing.T) {
 		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
 	}
 }
+
+func TestNodePositions(t *testing.T) {
+	input := `var total = add(1, 2)
+if total > 2 {
+  println(total)
+}`
+
+	l := lexer.NewFile("main.emo", input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	tests := []struct {
+		node        ast.Node
+		expectedPos string
+		expectedEnd string
+	}{
+		{program, "main.emo:1:1", "main.emo:4:2"},
+		{program.Statements[0], "main.emo:1:1", "main.emo:1:22"},
+		{program.Statements[0].(*ast.VarStatement).Value, "main.emo:1:13", "main.emo:1:22"},
+		{program.Statements[1], "main.emo:2:1", "main.emo:4:2"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%q, got=%q",
+				i, tt.expectedPos, tt.node.Pos().String())
+		}
+
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%q, got=%q",
+				i, tt.expectedEnd, tt.node.End().String())
+		}
+	}
+}
+
+func TestParenthesizedPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+		expectedEnd string
+	}{
+		{"-(4)", "main.emo:1:1", "main.emo:1:5"},
+		{"(1 + 2) * 3", "main.emo:1:1", "main.emo:1:12"},
+		{"3 * (1 + 2)", "main.emo:1:1", "main.emo:1:12"},
+		{"((x))", "main.emo:1:1", "main.emo:1:6"},
+		{"(f)(1)", "main.emo:1:1", "main.emo:1:7"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewFile("main.emo", tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+		if exp.Pos().String() != tt.expectedPos {
+			t.Errorf("%q - pos wrong. expected=%q, got=%q", tt.input, tt.expectedPos, exp.Pos().String())
+		}
+
+		if exp.End().String() != tt.expectedEnd {
+			t.Errorf("%q - end wrong. expected=%q, got=%q", tt.input, tt.expectedEnd, exp.End().String())
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := `var x = 1
+var = 2`
+
+	l := lexer.NewFile("main.emo", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+
+	expected := "main.emo:2:5: expected next token to be IDENT, got (=):<=> instead"
+	if errors[0] != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	input := `try { parse(x) } catch e { throw e } finally { close() }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.TryExpression. got=%T", stmt.Expression)
+	}
+
+	if exp.CatchParam == nil || exp.CatchParam.Value != "e" {
+		t.Fatalf("catch parameter is not 'e'. got=%v", exp.CatchParam)
+	}
+
+	if len(exp.Catch.Statements) != 1 {
+		t.Fatalf("catch block does not contain 1 statement. got=%d", len(exp.Catch.Statements))
+	}
+
+	if _, ok := exp.Catch.Statements[0].(*ast.ThrowStatement); !ok {
+		t.Fatalf("catch statement not *ast.ThrowStatement. got=%T", exp.Catch.Statements[0])
+	}
+
+	if exp.Finally == nil || exp.Finally.String() != "close()" {
+		t.Fatalf("finally block wrong. got=%v", exp.Finally)
+	}
+}
+
+func TestTryWithoutHandler(t *testing.T) {
+	l := lexer.New(`try { 1 }`)
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 parser error. got=%v", p.Errors())
+	}
+}
+
+func TestLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while x < 10 { x }", "while (x < 10) x"},
+		{"for x in items { x }", "for x in items x"},
+		{"for k, v in hash { k }", "for k, v in hash k"},
+		{"for i in 0..n + 1 { break }", "for i in (0 .. (n + 1)) break;"},
+		{"while true { continue }", "while true continue;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	tests := []string{
+		"break",
+		"continue",
+		"while true { func() { break } }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser error for %q", input)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "x = 5"},
+		{"x += y * 2", "x += (y * 2)"},
+		{"a = b = 1", "a = b = 1"},
+		{"arr[0] -= 1", "(arr[0]) -= 1"},
+		{"self.age = self.age + 1", "self.age = (self.age + 1)"},
+		{"a.b.c /= 2", "a.b.c /= 2"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.AssignExpression); !ok {
+			t.Fatalf("exp not *ast.AssignExpression. got=%T", stmt.Expression)
+		}
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestInvalidAssignmentTargets(t *testing.T) {
+	tests := []string{"1 = 2", "f() = 3", "self = 4"}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser error for %q", input)
+		}
+	}
+}
+
+func TestNewExpressionArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"new(Person)", "new(Person)"},
+		{"new(Person, \"Ann\", 30)", "new(Person, Ann, 30)"},
+		{"new(Person, name: \"Ann\", age: 1 + 2)", "new(Person, name: Ann, age: (1 + 2))"},
+		{"new(Person, {name: \"Ann\"})", "new(Person, {name:Ann})"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestClassExtends(t *testing.T) {
+	input := `class Admin extends User {
+  func greet() {
+    return super.greet()
+  }
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	class, ok := stmt.Expression.(*ast.ClassExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.ClassExpression. got=%T", stmt.Expression)
+	}
+
+	if class.Super == nil || class.Super.Value != "User" {
+		t.Fatalf("class.Super is not 'User'. got=%v", class.Super)
+	}
+
+	if _, ok := class.Methods["greet"]; !ok {
+		t.Fatalf("class has no method greet")
+	}
+}
+
+func TestInterfaceExpression(t *testing.T) {
+	input := `interface Greeter {
+  func greet(name: String) -> String
+  func pair() -> (Int, String)
+  func reset()
+}
+
+class Person extends User implements Greeter, Named {}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	iface, ok := stmt.Expression.(*ast.InterfaceExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.InterfaceExpression. got=%T", stmt.Expression)
+	}
+
+	tests := []struct {
+		name        string
+		params      int
+		returnTypes []string
+	}{
+		{"greet", 1, []string{"String"}},
+		{"pair", 0, []string{"Int", "String"}},
+		{"reset", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		method, ok := iface.Methods[tt.name]
+		if !ok {
+			t.Fatalf("interface has no method %s", tt.name)
+		}
+
+		if len(method.Parameters) != tt.params {
+			t.Errorf("method %s has %d parameters, want %d", tt.name, len(method.Parameters), tt.params)
+		}
+
+		if len(method.ReturnTypes) != len(tt.returnTypes) {
+			t.Fatalf("method %s has %d return types, want %d", tt.name, len(method.ReturnTypes), len(tt.returnTypes))
+		}
+
+		for i, rt := range tt.returnTypes {
+			if method.ReturnTypes[i].Value != rt {
+				t.Errorf("method %s return type %d is %q, want %q", tt.name, i, method.ReturnTypes[i].Value, rt)
+			}
+		}
+	}
+
+	stmt = program.Statements[1].(*ast.ExpressionStatement)
+	class, ok := stmt.Expression.(*ast.ClassExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.ClassExpression. got=%T", stmt.Expression)
+	}
+
+	if class.Super == nil || class.Super.Value != "User" {
+		t.Fatalf("class.Super is not 'User'. got=%v", class.Super)
+	}
+
+	if len(class.Implements) != 2 || class.Implements[0].Value != "Greeter" || class.Implements[1].Value != "Named" {
+		t.Fatalf("class.Implements is not [Greeter Named]. got=%v", class.Implements)
+	}
+}
+
+func TestFunctionParameterDefaults(t *testing.T) {
+	input := `func f(a: Int, b: Int = a + 1, ...rest: Array) {}
+g(1, b: 2)`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	fn, ok := stmt.Expression.(*ast.FunctionDefinition)
+	if !ok {
+		t.Fatalf("exp not *ast.FunctionDefinition. got=%T", stmt.Expression)
+	}
+
+	expected := []string{"<a:Int>", "<b:Int=(a + 1)>", "<...rest:Array>"}
+	if len(fn.Parameters) != len(expected) {
+		t.Fatalf("wrong number of parameters. want=%d, got=%d", len(expected), len(fn.Parameters))
+	}
+
+	for i, want := range expected {
+		if got := fn.Parameters[i].String(); got != want {
+			t.Errorf("parameter %d wrong. want=%q, got=%q", i, want, got)
+		}
+	}
+
+	stmt = program.Statements[1].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.CallExpression. got=%T", stmt.Expression)
+	}
+
+	if len(call.Arguments) != 2 {
+		t.Fatalf("wrong number of arguments. want=2, got=%d", len(call.Arguments))
+	}
+
+	named, ok := call.Arguments[1].(*ast.NamedArgument)
+	if !ok || named.Name.Value != "b" {
+		t.Fatalf("argument 1 is not the named argument b. got=%T (%+v)", call.Arguments[1], call.Arguments[1])
+	}
+}
+
+func TestInvalidFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"func f(...rest: Array, a: Int) {}", "1:11: variadic parameter rest must be the last parameter"},
+		{"func f(...rest: Array = []) {}", "1:11: variadic parameter rest cannot have a default value"},
+		{"func f(a: Int = 1, b: Int) {}", "1:20: parameter b without a default value follows a parameter with one"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestMultipleReturnValues(t *testing.T) {
+	input := `func add(a: Int, b: Int) -> (Int, String) {
+  return a + b, "ok"
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionDefinition)
+
+	if len(fn.ReturnTypes) != 2 || fn.ReturnTypes[0].Value != "Int" || fn.ReturnTypes[1].Value != "String" {
+		t.Fatalf("fn.ReturnTypes is not (Int, String). got=%v", fn.ReturnTypes)
+	}
+
+	ret, ok := fn.Body.Statements[0].(*ast.ReturnStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ReturnStatement. got=%T", fn.Body.Statements[0])
+	}
+
+	tuple, ok := ret.ReturnValue.(*ast.TupleLiteral)
+	if !ok {
+		t.Fatalf("return value is not *ast.TupleLiteral. got=%T", ret.ReturnValue)
+	}
+
+	if tuple.String() != `((a + b), ok)` {
+		t.Errorf("tuple.String() wrong. got=%q", tuple.String())
+	}
+
+	if tuple.Pos().String() != "2:10" {
+		t.Errorf("tuple.Pos() wrong. want=%q, got=%q", "2:10", tuple.Pos())
+	}
+}
+
+func TestDestructuringStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     token.TokenType
+		expected []string
+	}{
+		{"var (sum, status) = add(1, 2)", token.LPAREN, []string{"sum", "status"}},
+		{"var [a, b] = arr", token.LBRACKET, []string{"a", "b"}},
+		{"var {name, age} = hash", token.LBRACE, []string{"name", "age"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.DestructuringStatement)
+		if !ok {
+			t.Fatalf("statement is not *ast.DestructuringStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Kind != tt.kind {
+			t.Errorf("stmt.Kind wrong. want=%q, got=%q", tt.kind, stmt.Kind)
+		}
+
+		if len(stmt.Names) != len(tt.expected) {
+			t.Fatalf("wrong number of names. want=%d, got=%d", len(tt.expected), len(stmt.Names))
+		}
+
+		for i, name := range tt.expected {
+			if stmt.Names[i].Value != name {
+				t.Errorf("name %d wrong. want=%q, got=%q", i, name, stmt.Names[i].Value)
+			}
+		}
+	}
+
+	p := New(lexer.New("var (a, a) = f()"))
+	p.ParseProgram()
+
+	if errors := p.Errors(); len(errors) != 1 || errors[0] != "1:9: a is declared more than once" {
+		t.Errorf("wrong parser errors for a repeated name. got=%q", errors)
+	}
+}
+
+func TestEnumExpression(t *testing.T) {
+	input := `enum Status {
+  Active
+  Suspended(reason: String, days: Int), Closed
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	enum, ok := stmt.Expression.(*ast.EnumExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.EnumExpression. got=%T", stmt.Expression)
+	}
+
+	expected := []string{"Active", "Suspended(<reason:String>, <days:Int>)", "Closed"}
+	if len(enum.Variants) != len(expected) {
+		t.Fatalf("wrong number of variants. want=%d, got=%d", len(expected), len(enum.Variants))
+	}
+
+	for i, want := range expected {
+		if got := enum.Variants[i].String(); got != want {
+			t.Errorf("variant %d wrong. want=%q, got=%q", i, want, got)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match value {
+  Status.Active -> 1
+  Status.Suspended(reason, _) -> { reason }
+  [first, ...rest] -> first, {name, age: 30} -> 2
+  -1 -> "minus one"
+  x -> x
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	match, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if match.Value.String() != "value" {
+		t.Fatalf("match.Value is not value. got=%s", match.Value.String())
+	}
+
+	tests := []struct {
+		pattern string
+		typ     ast.Pattern
+	}{
+		{"Status.Active", &ast.VariantPattern{}},
+		{"Status.Suspended(reason, _)", &ast.VariantPattern{}},
+		{"[first, ...rest]", &ast.ArrayPattern{}},
+		{"{name: name, age: 30}", &ast.HashPattern{}},
+		{"(-1)", &ast.LiteralPattern{}},
+		{"x", &ast.BindingPattern{}},
+	}
+
+	if len(match.Arms) != len(tests) {
+		t.Fatalf("wrong number of arms. want=%d, got=%d", len(tests), len(match.Arms))
+	}
+
+	for i, tt := range tests {
+		pattern := match.Arms[i].Pattern
+		if pattern.String() != tt.pattern {
+			t.Errorf("pattern %d wrong. want=%q, got=%q", i, tt.pattern, pattern.String())
+		}
+
+		if fmt.Sprintf("%T", pattern) != fmt.Sprintf("%T", tt.typ) {
+			t.Errorf("pattern %d is not %T. got=%T", i, tt.typ, pattern)
+		}
+	}
+
+	if _, ok := match.Arms[1].Body.(*ast.BlockStatement); !ok {
+		t.Errorf("body of arm 1 is not *ast.BlockStatement. got=%T", match.Arms[1].Body)
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match x { 1 + 2 -> 1 }", "1:13: expected next token to be ->, got (+):<+> instead"},
+		{"match x { (a) -> 1 }", "1:11: unexpected ( in pattern"},
+		{"match x { [...rest, a] -> 1 }", "1:19: expected next token to be ], got (,):<,> instead"},
+		{"match x {}", "1:1: match has no arms"},
+		{"enum E { A, A }", "1:13: variant A is declared more than once in enum E"},
+		{"enum E { 1 }", "1:10: expected variant of enum E, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	input := `const LIMITS = [1, 2]`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.VarStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.VarStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Token.Type != token.CONST {
+		t.Errorf("stmt.Token.Type not %s. got=%s", token.CONST, stmt.Token.Type)
+	}
+
+	if stmt.String() != "const LIMITS = [1, 2];" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	tests := []struct {
+		input     string
+		path      string
+		namespace string
+	}{
+		{`import utils`, "utils", "utils"},
+		{`import "lib/strings.emo"`, "lib/strings.emo", "strings"},
+		{`import "lib/my-strings.emo" as text`, "lib/my-strings.emo", "text"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Path() != tt.path {
+			t.Errorf("stmt.Path() wrong. want=%q, got=%q", tt.path, stmt.Path())
+		}
+
+		if stmt.Namespace() != tt.namespace {
+			t.Errorf("stmt.Namespace() wrong. want=%q, got=%q", tt.namespace, stmt.Namespace())
+		}
+	}
+}
+
+func TestInvalidImportStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import 1`, "1:8: expected module name or path after import, got INT instead"},
+		{`import "lib/my-strings.emo"`, "1:8: cannot import \"lib/my-strings.emo\" without a name, use `import \"lib/my-strings.emo\" as name`"},
+		{`import "lib/strings" as "s"`, "1:25: expected next token to be IDENT, got (STRING):<s> instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. expected=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
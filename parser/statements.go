@@ -31,9 +31,24 @@ func (p *Parser) parseStatement() ast.Statement {
 	case token.DEFINE:
 		return p.parseDefineStatement()
 	case token.VAR:
+		if p.peekTokenIs(token.LPAREN) || p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+			return p.parseDestructuringStatement()
+		}
+		return p.parseVarStatement()
+	case token.CONST:
 		return p.parseVarStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	case token.NEWLINE:
 		// empty line
 		return nil
@@ -42,16 +57,50 @@ func (p *Parser) parseStatement() ast.Statement {
 	}
 }
 
+// import utils
+// import "lib/strings.emo"
+// import "lib/my-strings.emo" as strings
 func (p *Parser) parseImportStatement() *ast.ImportStatement {
 	stmt := &ast.ImportStatement{Token: p.curToken}
 
 	p.nextToken()
 
-	stmt.Name = p.parseExpression(LOWEST)
+	switch p.curToken.Type {
+	case token.IDENT:
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.STRING:
+		stmt.Name = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	default:
+		p.errorf(p.curToken.Pos, "expected module name or path after import, got %s instead", p.curToken.Type)
+		return nil
+	}
+
+	// `as` is only a keyword after an import
+	if p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "as" {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !isIdentifier(stmt.Namespace()) {
+		p.errorf(stmt.Name.Pos(), "cannot import %q without a name, use `import %q as name`", stmt.Path(), stmt.Path())
+		return nil
+	}
 
 	return stmt
 }
 
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isIdentifier reports whether name can be used as an identifier.
+func isIdentifier(name string) bool {
+	return identifierPattern.MatchString(name)
+}
+
 // Function to check if a string is all uppercase or contains underscores
 func isUppercaseOrUnderscore(s string) bool {
 	// Define the regex pattern: checks if the string is entirely uppercase letters or underscores
@@ -74,7 +123,7 @@ func (p *Parser) parseDefineStatement() *ast.DefineStatement {
 
 	// check if curToken is all uppercase or with underscore
 	if !isUppercaseOrUnderscore(p.curToken.Literal) {
-		p.errors = append(p.errors, "Define statement must have an uppercase identifier")
+		p.errorf(p.curToken.Pos, "Define statement must have an uppercase identifier")
 		return nil
 	}
 
@@ -92,6 +141,8 @@ func (p *Parser) parseDefineStatement() *ast.DefineStatement {
 		return nil
 	}
 
+	stmt.Rparen = p.curToken
+
 	return stmt
 }
 
@@ -115,16 +166,177 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	return stmt
 }
 
+// var (sum, status) = add(1, 2)
+// var [first, second] = array
+// var {name, age} = hash
+func (p *Parser) parseDestructuringStatement() *ast.DestructuringStatement {
+	stmt := &ast.DestructuringStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Kind = p.curToken.Type
+
+	end := map[token.TokenType]token.TokenType{
+		token.LPAREN:   token.RPAREN,
+		token.LBRACKET: token.RBRACKET,
+		token.LBRACE:   token.RBRACE,
+	}[stmt.Kind]
+
+	seen := map[string]bool{}
+
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[name.Value] {
+			p.errorf(name.Pos(), "%s is declared more than once", name.Value)
+		}
+		seen[name.Value] = true
+
+		stmt.Names = append(stmt.Names, name)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	return stmt
+}
+
+// return a + b
+// return a + b, "ok"
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
 	p.nextToken()
 
+	first := p.curToken
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COMMA) {
+		tuple := &ast.TupleLiteral{Token: first, Elements: []ast.Expression{stmt.ReturnValue}}
+
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+
+			tuple.Elements = append(tuple.Elements, p.parseExpression(LOWEST))
+		}
+
+		stmt.ReturnValue = tuple
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+// while n > 0 { ... }
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	return stmt
+}
+
+// for x in [1, 2, 3] { ... }
+// for key, value in {a: 1} { ... }
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Key = stmt.Value
+		stmt.Value = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
 	return stmt
 }
 
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	if p.loopDepth == 0 {
+		p.errorf(p.curToken.Pos, "break outside of a loop")
+		return nil
+	}
+
+	return &ast.BreakStatement{Token: p.curToken}
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	if p.loopDepth == 0 {
+		p.errorf(p.curToken.Pos, "continue outside of a loop")
+		return nil
+	}
+
+	return &ast.ContinueStatement{Token: p.curToken}
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -0,0 +1,377 @@
+// Package resolver resolves the names used in functions before a program
+// runs. Each local of a function, its parameters first, gets a slot in the
+// environment of a call to the function, and each name that refers to a
+// local gets the slot and the number of environments out of the one it is
+// used in where the local is. The evaluator reads and writes those locals
+// by slot instead of looking their names up.
+//
+// The top level of a program is not resolved: its names stay in the map of
+// the global environment, where the REPL and imports can add to them. Names
+// that are not locals of any enclosing function are globals, which the
+// evaluator looks up in the global environment without going through the
+// enclosing ones. The self, super and method names of methods are looked up
+// by name as before.
+//
+// A function that uses a local before the statement declaring it is an
+// error, as the use could only see a variable of the same name further out.
+// Functions nested in it, which may run after the declaration, can use the
+// local anywhere. The same holds for the globals the top level declares:
+// the statements of the top level must use them after their declaration,
+// while functions can use them anywhere.
+package resolver
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// Error is a name used before its declaration, which the evaluator and the
+// virtual machine report as a NameError.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + object.NAME_ERROR + ": " + e.Message
+}
+
+// Resolve resolves the names of program in place and returns the names
+// used before their declaration, ordered by position.
+func Resolve(program *ast.Program) []*Error {
+	r := &resolver{globals: map[string]bool{}, declared: map[string]bool{}}
+
+	r.collecting = true
+	for _, stmt := range program.Statements {
+		r.node(stmt)
+	}
+
+	r.collecting = false
+	for _, stmt := range program.Statements {
+		r.node(stmt)
+	}
+
+	sort.SliceStable(r.errors, func(i, j int) bool {
+		return r.errors[i].Pos.Offset < r.errors[j].Pos.Offset
+	})
+
+	return r.errors
+}
+
+// scope is a function being resolved.
+type scope struct {
+	outer *scope
+	// the environments between a call of the function and the one the
+	// function was created in: methods have one for self and super
+	hops int
+
+	slots    map[string]int
+	names    []string
+	declared map[string]bool // the locals declared so far
+	dynamic  map[string]bool // names bound by name, such as self
+}
+
+func (s *scope) add(name string) {
+	if _, ok := s.slots[name]; !ok {
+		s.slots[name] = len(s.names)
+		s.names = append(s.names, name)
+	}
+}
+
+type resolver struct {
+	scope *scope
+	// set while collecting the locals of the scope before resolving it
+	collecting bool
+	// set while resolving code that runs later, like class field
+	// initializers, which can use locals anywhere
+	deferred bool
+	errors   []*Error
+
+	// the globals the top level declares, and those declared so far
+	globals  map[string]bool
+	declared map[string]bool
+}
+
+// declare handles a name a statement binds in the current function.
+func (r *resolver) declare(name *ast.Identifier) {
+	if r.scope == nil {
+		if r.collecting {
+			r.globals[name.Value] = true
+		} else {
+			r.declared[name.Value] = true
+		}
+		return
+	}
+
+	if r.collecting {
+		r.scope.add(name.Value)
+		return
+	}
+
+	r.scope.declared[name.Value] = true
+	r.resolve(name)
+}
+
+// use handles a name that is read or assigned.
+func (r *resolver) use(name *ast.Identifier) {
+	if r.collecting {
+		return
+	}
+
+	if r.scope == nil {
+		if r.globals[name.Value] && !r.deferred && !r.declared[name.Value] {
+			r.usedBeforeDeclaration(name)
+		}
+		return
+	}
+
+	s := r.resolve(name)
+	if s == r.scope && !r.deferred && !s.declared[name.Value] {
+		r.usedBeforeDeclaration(name)
+	}
+}
+
+func (r *resolver) usedBeforeDeclaration(name *ast.Identifier) {
+	r.errors = append(r.errors, &Error{
+		Pos:     name.Pos(),
+		Message: fmt.Sprintf("variable %s used before declaration", name.Value),
+	})
+}
+
+// resolve sets the slot of name and returns the scope of the local it
+// refers to, or nil if it is not a local. A name of a function that is not
+// a local nor bound by a method is a global.
+func (r *resolver) resolve(name *ast.Identifier) *scope {
+	name.Resolved, name.Depth, name.Slot, name.Global = false, 0, 0, false
+
+	depth := 0
+	for s := r.scope; s != nil; s = s.outer {
+		if slot, ok := s.slots[name.Value]; ok {
+			name.Resolved, name.Depth, name.Slot = true, depth, slot
+			return s
+		}
+
+		if s.dynamic[name.Value] {
+			return nil
+		}
+
+		depth += s.hops
+	}
+
+	name.Global = true
+	return nil
+}
+
+// function resolves a function in a new scope, after collecting its locals,
+// and returns the locals and their slots. A method has its name, self and
+// super bound around its environment.
+func (r *resolver) function(params []*ast.TypedField, body *ast.BlockStatement, method *ast.Identifier) ([]string, map[string]int) {
+	s := &scope{
+		outer:    r.scope,
+		hops:     1,
+		slots:    map[string]int{},
+		declared: map[string]bool{},
+	}
+	if method != nil {
+		s.hops = 2
+		s.dynamic = map[string]bool{method.Value: true, "self": true, "super": true}
+	}
+
+	for _, param := range params {
+		s.slots[param.Name.Value] = len(s.names)
+		s.names = append(s.names, param.Name.Value)
+		s.declared[param.Name.Value] = true
+	}
+
+	outer, collecting, deferred := r.scope, r.collecting, r.deferred
+	defer func() { r.scope, r.collecting, r.deferred = outer, collecting, deferred }()
+
+	r.scope, r.collecting, r.deferred = s, true, false
+	r.node(body)
+
+	r.collecting = false
+	for _, param := range params {
+		r.resolve(param.Name)
+		if param.Default != nil {
+			r.node(param.Default)
+		}
+	}
+	r.node(body)
+
+	return s.names, s.slots
+}
+
+func (r *resolver) nodes(exps []ast.Expression) {
+	for _, exp := range exps {
+		r.node(exp)
+	}
+}
+
+func (r *resolver) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		r.node(node.Expression)
+	case *ast.VarStatement:
+		r.node(node.Value)
+		r.declare(node.Name)
+	case *ast.DefineStatement:
+		r.node(node.Value)
+		r.declare(node.Name)
+	case *ast.DestructuringStatement:
+		r.node(node.Value)
+		for _, name := range node.Names {
+			r.declare(name)
+		}
+	case *ast.ReturnStatement:
+		r.node(node.ReturnValue)
+	case *ast.ThrowStatement:
+		r.node(node.Value)
+	case *ast.ImportStatement:
+		r.declare(&ast.Identifier{Token: node.Token, Value: node.Namespace()})
+	case *ast.BlockStatement:
+		if node == nil {
+			return
+		}
+		for _, stmt := range node.Statements {
+			r.node(stmt)
+		}
+	case *ast.WhileStatement:
+		r.node(node.Condition)
+		r.node(node.Body)
+	case *ast.ForStatement:
+		r.node(node.Iterable)
+		if node.Key != nil {
+			r.declare(node.Key)
+		}
+		r.declare(node.Value)
+		r.node(node.Body)
+
+	case *ast.Identifier:
+		r.use(node)
+	case *ast.ParenExpression:
+		r.node(node.Expression)
+	case *ast.PrefixExpression:
+		r.node(node.Right)
+	case *ast.InfixExpression:
+		r.node(node.Left)
+		r.node(node.Right)
+	case *ast.AssignExpression:
+		r.node(node.Value)
+		r.node(node.Target)
+	case *ast.IfExpression:
+		r.node(node.Condition)
+		r.node(node.Consequence)
+		r.node(node.Alternative)
+	case *ast.ArrayLiteral:
+		r.nodes(node.Elements)
+	case *ast.TupleLiteral:
+		r.nodes(node.Elements)
+	case *ast.HashLiteral:
+		for _, value := range node.Pairs {
+			r.node(value)
+		}
+	case *ast.IndexExpression:
+		r.node(node.Left)
+		r.node(node.Index)
+	case *ast.DotExpression:
+		r.node(node.Left)
+	case *ast.CallExpression:
+		r.node(node.Function)
+		r.nodes(node.Arguments)
+	case *ast.NamedArgument:
+		r.node(node.Value)
+	case *ast.NewExpression:
+		r.use(node.What)
+		r.nodes(node.Arguments)
+	case *ast.TryExpression:
+		r.node(node.Block)
+		if node.CatchParam != nil {
+			r.declare(node.CatchParam)
+		}
+		r.node(node.Catch)
+		r.node(node.Finally)
+	case *ast.MatchExpression:
+		r.node(node.Value)
+		for _, arm := range node.Arms {
+			r.pattern(arm.Pattern)
+			r.node(arm.Body)
+		}
+
+	case *ast.FunctionLiteral:
+		if !r.collecting {
+			node.Locals, node.Slots = r.function(node.Parameters, node.Body, nil)
+		}
+	case *ast.FunctionDefinition:
+		if !r.collecting {
+			node.Locals, node.Slots = r.function(node.Parameters, node.Body, nil)
+		}
+		r.declare(node.Name)
+	case *ast.ClassExpression:
+		r.class(node)
+	case *ast.EnumExpression:
+		r.declare(node.Name)
+	case *ast.InterfaceExpression:
+		r.declare(node.Name)
+	}
+}
+
+// class resolves the field initializers and the methods of a class, which
+// run when the class is instantiated and when its methods are called.
+func (r *resolver) class(class *ast.ClassExpression) {
+	if class.Super != nil {
+		r.use(class.Super)
+	}
+	for _, name := range class.Implements {
+		r.use(name)
+	}
+	r.declare(class.Name)
+
+	if r.collecting {
+		return
+	}
+
+	deferred := r.deferred
+	r.deferred = true
+	for _, field := range class.Fields {
+		if field.Value != nil {
+			r.node(field.Value)
+		}
+	}
+	r.deferred = deferred
+
+	for _, method := range class.Methods {
+		def := method.Function
+		def.Locals, def.Slots = r.function(def.Parameters, def.Body, def.Name)
+	}
+}
+
+// pattern resolves the values a pattern compares with and declares the
+// names it binds.
+func (r *resolver) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		r.declare(pattern.Name)
+	case *ast.LiteralPattern:
+		r.node(pattern.Value)
+	case *ast.ArrayPattern:
+		for _, element := range pattern.Elements {
+			r.pattern(element)
+		}
+		if pattern.Rest != nil {
+			r.declare(pattern.Rest)
+		}
+	case *ast.HashPattern:
+		for _, pair := range pattern.Pairs {
+			r.pattern(pair.Pattern)
+		}
+	case *ast.VariantPattern:
+		r.use(pattern.Enum)
+		for _, arg := range pattern.Arguments {
+			r.pattern(arg)
+		}
+	}
+}
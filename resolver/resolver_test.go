@@ -0,0 +1,164 @@
+package resolver
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors in %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+func TestLocals(t *testing.T) {
+	program := parse(t, `func f(a: Int) {
+  var b = a
+  for i, x in [b] { b += x }
+  return try { b } catch e { e }
+}`)
+
+	if errs := Resolve(program); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionDefinition)
+
+	expected := []string{"a", "b", "i", "x", "e"}
+	if !slices.Equal(fn.Locals, expected) {
+		t.Errorf("wrong locals. want=%v, got=%v", expected, fn.Locals)
+	}
+
+	for i, name := range expected {
+		if fn.Slots[name] != i {
+			t.Errorf("wrong slot for %s. want=%d, got=%d", name, i, fn.Slots[name])
+		}
+	}
+
+	if fn.Name.Resolved {
+		t.Errorf("global %s is resolved", fn.Name.Value)
+	}
+}
+
+func TestSlots(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string // the last use of name is checked
+		depth  int
+		slot   int
+		local  bool
+		global bool
+	}{
+		{"func f(a: Int, b: Int) { return b }", "b", 0, 1, true, false},
+		{"func f(a: Int) { var c = 1\nreturn func(d: Int) { return a + c + d } }", "c", 1, 1, true, false},
+		{"func f() { return g }\nvar g = 1", "g", 0, 0, false, true},
+		{"var g = 1\ng", "g", 0, 0, false, false},
+		// methods have an environment for self and super around theirs
+		{"func f(n: Int) { class A { func get() { return n } } }", "n", 2, 0, true, false},
+		{"class A { func get() { return self } }", "self", 0, 0, false, false},
+		{"func f(get: Int) { class A { func get() { return get } } }", "get", 0, 0, false, false},
+		// the locals a function declares later hide the names further out
+		{"func f() { var x = 1\nfunc g() { return x }\nvar x = 2 }", "x", 1, 0, true, false},
+		{"func f(x: Int) { match x { [y] -> y } }", "y", 0, 1, true, false},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		if errs := Resolve(program); len(errs) != 0 {
+			t.Fatalf("unexpected errors for %q: %v", tt.input, errs)
+		}
+
+		var found *ast.Identifier
+		collect(program, tt.name, &found)
+		if found == nil {
+			t.Fatalf("%s not found in %q", tt.name, tt.input)
+		}
+
+		if found.Resolved != tt.local || found.Depth != tt.depth || found.Slot != tt.slot || found.Global != tt.global {
+			t.Errorf("wrong resolution of %s in %q. want=(%t, %d, %d, %t), got=(%t, %d, %d, %t)", tt.name, tt.input,
+				tt.local, tt.depth, tt.slot, tt.global, found.Resolved, found.Depth, found.Slot, found.Global)
+		}
+	}
+}
+
+// collect sets found to the last use of name in the statements it finds
+// them in.
+func collect(node ast.Node, name string, found **ast.Identifier) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			collect(stmt, name, found)
+		}
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			collect(stmt, name, found)
+		}
+	case *ast.ExpressionStatement:
+		collect(node.Expression, name, found)
+	case *ast.ReturnStatement:
+		collect(node.ReturnValue, name, found)
+	case *ast.VarStatement:
+		collect(node.Value, name, found)
+	case *ast.InfixExpression:
+		collect(node.Left, name, found)
+		collect(node.Right, name, found)
+	case *ast.FunctionLiteral:
+		collect(node.Body, name, found)
+	case *ast.FunctionDefinition:
+		collect(node.Body, name, found)
+	case *ast.ClassExpression:
+		for _, method := range node.Methods {
+			collect(method.Function.Body, name, found)
+		}
+	case *ast.MatchExpression:
+		for _, arm := range node.Arms {
+			collect(arm.Body, name, found)
+		}
+	case *ast.Identifier:
+		if node.Value == name {
+			*found = node
+		}
+	}
+}
+
+func TestUsedBeforeDeclaration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"func f() { print(x)\nvar x = 1 }", []string{"1:18: NameError: variable x used before declaration"}},
+		{"func f() { var n = n + 1 }", []string{"1:20: NameError: variable n used before declaration"}},
+		{"func f() { x = 2\nx += 1\nconst x = 1 }", []string{
+			"1:12: NameError: variable x used before declaration",
+			"2:1: NameError: variable x used before declaration",
+		}},
+		{"func f() { g()\nfunc g() {} }", []string{"1:12: NameError: variable g used before declaration"}},
+		{"print(x)\nvar x = 1", []string{"1:7: NameError: variable x used before declaration"}},
+		{"f()\nif true { func f() {} }", []string{"1:1: NameError: variable f used before declaration"}},
+		{"var q = 1\nprint(q)\nvar q = 2", nil},
+		// functions may use names declared later
+		{"func f() { return x }\nvar x = 1\nprint(f())", nil},
+		{"func f() { func g() { return x }\nvar x = 1\nreturn g() }", nil},
+		{"func f(n: Int) { var n = n + 1 }", nil},
+		{"func f() { var x = 1\nclass A { var n: Int = y }\nvar y = 2 }", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, err := range Resolve(parse(t, tt.input)) {
+			got = append(got, err.Error())
+		}
+
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("wrong errors for %q. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -4,9 +4,39 @@ import "fmt"
 
 type TokenType string
 
+// Position describes a location in the source code.
+type Position struct {
+	Filename string // empty when the source has no file, e.g. the REPL
+	Offset   int    // byte offset, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number (in bytes), starting at 1
+}
+
+// IsValid reports whether the position has been set.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+// String returns the position as "file:line:column", "line:column" when
+// there is no file name, or "-" when the position is not valid.
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first character of the token
+	End     Position // position immediately after the token
 }
 
 func (t Token) String() string {
@@ -33,7 +63,14 @@ const (
 	SLASH    = "/"
 	BANG     = "!"
 
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
 	DOT       = "."
+	DOTDOT    = ".."
+	ELLIPSIS  = "..."
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
@@ -70,15 +107,34 @@ const (
 	NEW   = "NEW"
 	SELF  = "SELF"
 	ENUM  = "ENUM"
+	MATCH = "MATCH"
+
+	EXTENDS = "EXTENDS"
+	SUPER   = "SUPER"
+
+	INTERFACE  = "INTERFACE"
+	IMPLEMENTS = "IMPLEMENTS"
 
 	PUBLIC  = "PUBLIC"
 	PRIVATE = "PRIVATE"
+
+	THROW   = "THROW"
+	TRY     = "TRY"
+	CATCH   = "CATCH"
+	FINALLY = "FINALLY"
+
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
 )
 
 var keywords = map[string]TokenType{
 	"import":  IMPORT,
 	"func":    FUNCTION,
 	"define":  DEFINE,
+	"const":   CONST,
 	"var":     VAR,
 	"if":      IF,
 	"else":    ELSE,
@@ -89,8 +145,25 @@ var keywords = map[string]TokenType{
 	"new":     NEW,
 	"self":    SELF,
 	"enum":    ENUM,
+	"match":   MATCH,
+	"extends": EXTENDS,
+	"super":   SUPER,
+
+	"interface":  INTERFACE,
+	"implements": IMPLEMENTS,
+
 	"public":  PUBLIC,
 	"private": PRIVATE,
+	"throw":   THROW,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+
+	"while":    WHILE,
+	"for":      FOR,
+	"in":       IN,
+	"break":    BREAK,
+	"continue": CONTINUE,
 }
 
 func LookupKeyword(ident string) TokenType {
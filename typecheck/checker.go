@@ -0,0 +1,1059 @@
+// Package typecheck checks a program against its type annotations before it
+// runs. It infers the types of expressions and reports calls, returns,
+// assignments and member accesses that do not match the declared types of
+// parameters, return values and class fields, and functions with return
+// values that can end without one.
+package typecheck
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/token"
+)
+
+// Error is a type error found by the checker.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String() + ": " + e.Message
+}
+
+// Check checks program and returns the errors it finds, ordered by position.
+func Check(program *ast.Program) []*Error {
+	c := &checker{
+		classes:    map[*ast.ClassExpression]*Class{},
+		interfaces: map[*ast.InterfaceExpression]*Interface{},
+		enums:      map[*ast.EnumExpression]*Enum{},
+		values:     map[*ast.ExpressionStatement]Type{},
+	}
+	c.checkBody(program.Statements, newUniverse())
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		return c.errors[i].Pos.Offset < c.errors[j].Pos.Offset
+	})
+
+	return c.errors
+}
+
+type scope struct {
+	names  map[string]Type
+	consts map[string]bool // names declared by const or define
+	outer  *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]Type{}, consts: map[string]bool{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) (Type, bool) {
+	for ; s != nil; s = s.outer {
+		if t, ok := s.names[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) set(name string, t Type) {
+	s.names[name] = t
+}
+
+// isConst reports whether the innermost declaration of name is a constant.
+func (s *scope) isConst(name string) bool {
+	for ; s != nil; s = s.outer {
+		if _, ok := s.names[name]; ok {
+			return s.consts[name]
+		}
+	}
+	return false
+}
+
+// newUniverse returns the scope of the builtins.
+func newUniverse() *scope {
+	s := newScope(nil)
+
+	s.set("len", &Func{Name: "len", Params: []*Param{{Name: "value", Type: Any}}, Results: []Type{Int}})
+	s.set("first", &Func{Name: "first", Params: []*Param{{Name: "array", Type: Array}}, Results: []Type{Any}})
+	s.set("last", &Func{Name: "last", Params: []*Param{{Name: "array", Type: Array}}, Results: []Type{Any}})
+	s.set("rest", &Func{Name: "rest", Params: []*Param{{Name: "array", Type: Array}}, Results: []Type{Any}})
+	s.set("push", &Func{Name: "push", Params: []*Param{{Name: "array", Type: Array}, {Name: "value", Type: Any}}, Results: []Type{Array}})
+	s.set("is_a?", &Func{Name: "is_a?", Params: []*Param{{Name: "value", Type: Any}, {Name: "type", Type: Any}}, Results: []Type{Bool}})
+	s.set("println", &Func{Name: "println", Variadic: true, Results: []Type{Nil}})
+	s.set("print", &Func{Name: "print", Variadic: true, Results: []Type{Nil}})
+
+	return s
+}
+
+type checker struct {
+	errors []*Error
+
+	classes    map[*ast.ClassExpression]*Class
+	interfaces map[*ast.InterfaceExpression]*Interface
+	enums      map[*ast.EnumExpression]*Enum
+
+	// the types of expression statements, which end bodies with their value
+	values map[*ast.ExpressionStatement]Type
+
+	// function bodies waiting to be checked at the end of the enclosing
+	// function, when the names it declares later are known
+	pending []func()
+
+	fn    *Func  // the function whose body is checked, nil at the top level
+	class *Class // the class whose method is checked
+}
+
+func (c *checker) errorf(pos token.Position, format string, a ...any) {
+	c.errors = append(c.errors, &Error{Pos: pos, Message: fmt.Sprintf(format, a...)})
+}
+
+// checkBody checks the statements of a program or function body, then the
+// functions defined in it.
+func (c *checker) checkBody(stmts []ast.Statement, s *scope) {
+	outer := c.pending
+	c.pending = nil
+
+	c.checkStatements(stmts, s)
+
+	// checking a body may queue more bodies
+	for len(c.pending) > 0 {
+		check := c.pending[0]
+		c.pending = c.pending[1:]
+		check()
+	}
+
+	c.pending = outer
+}
+
+func (c *checker) checkStatements(stmts []ast.Statement, s *scope) {
+	c.declareTypes(stmts, s)
+
+	for _, stmt := range stmts {
+		c.checkStatement(stmt, s)
+	}
+}
+
+// declareTypes declares the classes, interfaces and enums defined by stmts,
+// so annotations can refer to them before their definition.
+func (c *checker) declareTypes(stmts []ast.Statement, s *scope) {
+	classes := []*ast.ClassExpression{}
+	interfaces := []*ast.InterfaceExpression{}
+	enums := []*ast.EnumExpression{}
+
+	for _, stmt := range stmts {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			continue
+		}
+
+		switch node := es.Expression.(type) {
+		case *ast.ClassExpression:
+			klass := &Class{Name: node.Name.Value, Fields: map[string]*Field{}, Methods: map[string]*Method{}}
+			c.classes[node] = klass
+			s.set(node.Name.Value, &Meta{Of: klass})
+			classes = append(classes, node)
+		case *ast.InterfaceExpression:
+			iface := &Interface{Name: node.Name.Value, Methods: map[string]*Func{}}
+			c.interfaces[node] = iface
+			s.set(node.Name.Value, &Meta{Of: iface})
+			interfaces = append(interfaces, node)
+		case *ast.EnumExpression:
+			enum := &Enum{Name: node.Name.Value, Variants: map[string]*Variant{}}
+			c.enums[node] = enum
+			s.set(node.Name.Value, &Meta{Of: enum})
+			enums = append(enums, node)
+		}
+	}
+
+	for _, node := range enums {
+		c.declareEnum(node, s)
+	}
+
+	for _, node := range interfaces {
+		iface := c.interfaces[node]
+		for name, m := range node.Methods {
+			iface.Methods[name] = c.signature(iface.Name+"."+name, m.Parameters, m.ReturnTypes, s)
+		}
+	}
+
+	for _, node := range classes {
+		c.declareClass(node, s)
+	}
+}
+
+func (c *checker) declareClass(node *ast.ClassExpression, s *scope) {
+	klass := c.classes[node]
+
+	if node.Super != nil {
+		switch super := c.lookupMeta(node.Super, s).(type) {
+		case nil:
+		case *Class:
+			if super.IsA(klass) {
+				c.errorf(node.Super.Pos(), "%s cannot extend %s: inheritance cycle", klass.Name, super.Name)
+			} else {
+				klass.Super = super
+			}
+		default:
+			c.errorf(node.Super.Pos(), "%s cannot extend %s", klass.Name, super)
+		}
+	}
+
+	for _, name := range node.Implements {
+		switch iface := c.lookupMeta(name, s).(type) {
+		case nil:
+		case *Interface:
+			klass.Interfaces = append(klass.Interfaces, iface)
+		default:
+			c.errorf(name.Pos(), "%s cannot implement %s", klass.Name, iface)
+		}
+	}
+
+	for name, f := range node.Fields {
+		klass.Fields[name] = &Field{Name: name, Type: c.resolveType(f.Field.Type, s), Public: f.Public}
+	}
+
+	for name, m := range node.Methods {
+		fn := m.Function
+		sig := c.signature(klass.Name+"."+name, fn.Parameters, fn.ReturnTypes, s)
+		klass.Methods[name] = &Method{Sig: sig, Public: m.Public}
+	}
+}
+
+// lookupMeta returns the class or interface named by ident, or nil after
+// reporting an undefined name.
+func (c *checker) lookupMeta(ident *ast.Identifier, s *scope) Type {
+	t, ok := s.lookup(ident.Value)
+	if !ok {
+		c.errorf(ident.Pos(), "undefined: %s", ident.Value)
+		return nil
+	}
+
+	if meta, ok := t.(*Meta); ok {
+		return meta.Of
+	}
+
+	return t
+}
+
+// resolveType returns the type named by an annotation.
+func (c *checker) resolveType(ident *ast.Identifier, s *scope) Type {
+	if ident == nil {
+		return Any
+	}
+
+	if t, ok := basicTypes[ident.Value]; ok {
+		return t
+	}
+
+	if t, ok := s.lookup(ident.Value); ok {
+		if meta, ok := t.(*Meta); ok {
+			return meta.Of
+		}
+	}
+
+	c.errorf(ident.Pos(), "unknown type %s", ident.Value)
+
+	return Any
+}
+
+func (c *checker) signature(name string, params []*ast.TypedField, results []*ast.Identifier, s *scope) *Func {
+	fn := &Func{Name: name}
+
+	for _, p := range params {
+		if p.Variadic {
+			// the rest of the arguments can be of any type
+			c.resolveType(p.Type, s)
+			fn.Variadic = true
+			continue
+		}
+
+		fn.Params = append(fn.Params, &Param{Name: p.Name.Value, Type: c.resolveType(p.Type, s), Optional: p.Default != nil})
+	}
+
+	for _, r := range results {
+		fn.Results = append(fn.Results, c.resolveType(r, s))
+	}
+
+	return fn
+}
+
+// queueBody schedules the body of fn to be checked in a new scope enclosed
+// by s. The bodies of methods, named by method, are checked with self (and
+// super) bound to their class.
+func (c *checker) queueBody(fn *Func, params []*ast.TypedField, body *ast.BlockStatement, s *scope, class *Class, method string) {
+	c.pending = append(c.pending, func() {
+		inner := newScope(s)
+
+		if method != "" {
+			inner.set("self", class)
+			if class.Super != nil {
+				inner.set("super", class.Super)
+			}
+			inner.set(method, fn)
+		}
+
+		for i, p := range params {
+			if s.isConst(p.Name.Value) {
+				c.errorf(p.Name.Pos(), "cannot redeclare constant %s", p.Name.Value)
+			}
+
+			if p.Variadic {
+				inner.set(p.Name.Value, Array)
+				continue
+			}
+
+			inner.set(p.Name.Value, fn.Params[i].Type)
+
+			// defaults can refer to the parameters before them
+			if p.Default != nil {
+				if t := c.expr(p.Default, inner); !assignable(fn.Params[i].Type, t) {
+					c.errorf(p.Default.Pos(), "cannot use %s as %s for the default of parameter %s", t, fn.Params[i].Type, p.Name.Value)
+				}
+			}
+		}
+
+		outerFn, outerClass := c.fn, c.class
+		c.fn, c.class = fn, class
+
+		c.checkBody(body.Statements, inner)
+
+		if len(fn.Results) > 0 && c.reachesEnd(body.Statements, fn.Result()) {
+			c.errorf(body.Rbrace.Pos, "missing return in %s", fn.Name)
+		}
+
+		c.fn, c.class = outerFn, outerClass
+	})
+}
+
+// reachesEnd reports whether stmts can end without returning, throwing or
+// ending with a value of type want, the value of a function whose body they
+// are. Statements other than expressions end with nil.
+func (c *checker) reachesEnd(stmts []ast.Statement, want Type) bool {
+	if len(stmts) == 0 {
+		return !assignable(want, Nil)
+	}
+
+	switch last := stmts[len(stmts)-1].(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return false
+	case *ast.BlockStatement:
+		return c.reachesEnd(last.Statements, want)
+	case *ast.WhileStatement:
+		// a loop on true that does not break only ends by returning
+		if cond, ok := last.Condition.(*ast.Boolean); ok && cond.Value && !breaks(last.Body.Statements) {
+			return false
+		}
+	case *ast.ExpressionStatement:
+		switch exp := last.Expression.(type) {
+		case *ast.IfExpression:
+			if exp.Alternative == nil {
+				return !assignable(want, Nil) || c.reachesEnd(exp.Consequence.Statements, want)
+			}
+			return c.reachesEnd(exp.Consequence.Statements, want) || c.reachesEnd(exp.Alternative.Statements, want)
+		case *ast.TryExpression:
+			if exp.Finally != nil && !c.reachesEnd(exp.Finally.Statements, want) {
+				return false
+			}
+			return c.reachesEnd(exp.Block.Statements, want) || exp.Catch != nil && c.reachesEnd(exp.Catch.Statements, want)
+		case *ast.MatchExpression:
+			for _, arm := range exp.Arms {
+				// the values of the other arms are checked by strict mode
+				if body, ok := arm.Body.(*ast.BlockStatement); ok && c.reachesEnd(body.Statements, want) {
+					return true
+				}
+			}
+			return false
+		}
+
+		t, ok := c.values[last]
+		return ok && !assignable(want, t)
+	}
+
+	return !assignable(want, Nil)
+}
+
+// breaks reports whether stmts break out of the loop they are the body of.
+func breaks(stmts []ast.Statement) bool {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.BreakStatement:
+			return true
+		case *ast.BlockStatement:
+			if breaks(stmt.Statements) {
+				return true
+			}
+		case *ast.ExpressionStatement:
+			switch exp := stmt.Expression.(type) {
+			case *ast.IfExpression:
+				if breaks(exp.Consequence.Statements) || exp.Alternative != nil && breaks(exp.Alternative.Statements) {
+					return true
+				}
+			case *ast.TryExpression:
+				for _, block := range []*ast.BlockStatement{exp.Block, exp.Catch, exp.Finally} {
+					if block != nil && breaks(block.Statements) {
+						return true
+					}
+				}
+			case *ast.MatchExpression:
+				for _, arm := range exp.Arms {
+					if body, ok := arm.Body.(*ast.BlockStatement); ok && breaks(body.Statements) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *checker) checkStatement(stmt ast.Statement, s *scope) {
+	switch node := stmt.(type) {
+	case *ast.ExpressionStatement:
+		c.values[node] = c.expr(node.Expression, s)
+	case *ast.VarStatement:
+		c.declareVar(node.Name, c.expr(node.Value, s), s)
+		if node.Token.Type == token.CONST {
+			s.consts[node.Name.Value] = true
+		}
+	case *ast.DefineStatement:
+		c.declareVar(node.Name, c.expr(node.Value, s), s)
+		s.consts[node.Name.Value] = true
+	case *ast.ReturnStatement:
+		t := c.expr(node.ReturnValue, s)
+
+		if c.fn != nil && len(c.fn.Results) > 0 {
+			if want := c.fn.Result(); !assignable(want, t) {
+				c.errorf(node.ReturnValue.Pos(), "cannot return %s from %s, want %s", t, c.fn.Name, want)
+			}
+		}
+	case *ast.DestructuringStatement:
+		c.checkDestructuring(node, c.expr(node.Value, s), s)
+	case *ast.ImportStatement:
+		// imported modules are checked on their own
+		c.declare(&ast.Identifier{Token: node.Token, Value: node.Namespace()}, Any, s)
+	case *ast.ThrowStatement:
+		c.expr(node.Value, s)
+	case *ast.WhileStatement:
+		c.expr(node.Condition, s)
+		c.checkStatements(node.Body.Statements, s)
+	case *ast.ForStatement:
+		c.checkFor(node, s)
+	case *ast.BlockStatement:
+		c.checkStatements(node.Statements, s)
+	}
+}
+
+func (c *checker) declareVar(name *ast.Identifier, t Type, s *scope) {
+	// a variable that starts as nil can hold anything later
+	if t == Nil {
+		t = Any
+	}
+	c.declare(name, t, s)
+}
+
+// declare declares name in s, unless it names a constant.
+func (c *checker) declare(name *ast.Identifier, t Type, s *scope) {
+	if s.isConst(name.Value) {
+		c.errorf(name.Pos(), "cannot redeclare constant %s", name.Value)
+		return
+	}
+	s.set(name.Value, t)
+}
+
+func (c *checker) checkFor(node *ast.ForStatement, s *scope) {
+	iterable := c.expr(node.Iterable, s)
+
+	var key, value Type
+	switch iterable {
+	case Array:
+		key, value = Int, Any
+	case Hash:
+		key, value = Any, Any
+	case String:
+		key, value = Int, String
+	case Range:
+		key, value = Int, Int
+	case Any:
+		key, value = Any, Any
+	default:
+		c.errorf(node.Iterable.Pos(), "cannot iterate over %s", iterable)
+		key, value = Any, Any
+	}
+
+	if node.Key != nil {
+		c.declare(node.Key, key, s)
+		c.declare(node.Value, value, s)
+	} else if iterable == Hash {
+		c.declare(node.Value, key, s)
+	} else {
+		c.declare(node.Value, value, s)
+	}
+
+	c.checkStatements(node.Body.Statements, s)
+}
+
+// expr checks an expression and returns its type. Expressions with errors
+// have type Any, so an error is reported once.
+func (c *checker) expr(node ast.Expression, s *scope) Type {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		t, ok := s.lookup(node.Value)
+		if !ok {
+			c.errorf(node.Pos(), "undefined: %s", node.Value)
+			return Any
+		}
+		return t
+	case *ast.ParenExpression:
+		return c.expr(node.Expression, s)
+	case *ast.PrefixExpression:
+		right := c.expr(node.Right, s)
+
+		if node.Operator == "!" {
+			return Bool
+		}
+
+		if right != Int && right != Any {
+			c.errorf(node.Pos(), "invalid operation: %s%s", node.Operator, right)
+			return Any
+		}
+		return Int
+	case *ast.InfixExpression:
+		left := c.expr(node.Left, s)
+		right := c.expr(node.Right, s)
+
+		return c.infix(node.Operator, left, right, node.Pos())
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			c.expr(el, s)
+		}
+		return Array
+	case *ast.TupleLiteral:
+		tuple := &Tuple{}
+		for _, el := range node.Elements {
+			tuple.Types = append(tuple.Types, c.expr(el, s))
+		}
+		return tuple
+	case *ast.HashLiteral:
+		for _, value := range node.Pairs {
+			c.expr(value, s)
+		}
+		return Hash
+	case *ast.IndexExpression:
+		return c.index(node, s)
+	case *ast.IfExpression:
+		c.expr(node.Condition, s)
+		c.checkStatements(node.Consequence.Statements, s)
+		if node.Alternative != nil {
+			c.checkStatements(node.Alternative.Statements, s)
+		}
+		return Any
+	case *ast.TryExpression:
+		c.checkStatements(node.Block.Statements, s)
+		if node.Catch != nil {
+			if node.CatchParam != nil {
+				c.declare(node.CatchParam, Exception, s)
+			}
+			c.checkStatements(node.Catch.Statements, s)
+		}
+		if node.Finally != nil {
+			c.checkStatements(node.Finally.Statements, s)
+		}
+		return Any
+	case *ast.FunctionLiteral:
+		fn := c.signature("<anonymous>", node.Parameters, node.ReturnTypes, s)
+		c.queueBody(fn, node.Parameters, node.Body, s, c.class, "")
+		return fn
+	case *ast.FunctionDefinition:
+		fn := c.signature(node.Name.Value, node.Parameters, node.ReturnTypes, s)
+		c.declare(node.Name, fn, s)
+		c.queueBody(fn, node.Parameters, node.Body, s, c.class, "")
+		return fn
+	case *ast.CallExpression:
+		return c.call(node, s)
+	case *ast.DotExpression:
+		return c.member(node, s)
+	case *ast.AssignExpression:
+		return c.assign(node, s)
+	case *ast.ClassExpression:
+		return c.checkClass(node, s)
+	case *ast.InterfaceExpression:
+		return &Meta{Of: c.interfaces[node]}
+	case *ast.EnumExpression:
+		return &Meta{Of: c.enums[node]}
+	case *ast.MatchExpression:
+		return c.checkMatch(node, s)
+	case *ast.NewExpression:
+		return c.new(node, s)
+	}
+
+	return Any
+}
+
+func (c *checker) infix(operator string, left, right Type, pos token.Position) Type {
+	switch operator {
+	case "==", "!=":
+		return Bool
+	}
+
+	if left == Any || right == Any {
+		switch operator {
+		case "<", ">":
+			return Bool
+		case "..":
+			return Range
+		}
+		return Any
+	}
+
+	switch {
+	case operator == "+" && left == String && right == String:
+		return String
+	case left != Int || right != Int:
+		// only integers have the other operators
+	case operator == "+", operator == "-", operator == "*", operator == "/":
+		return Int
+	case operator == "<", operator == ">":
+		return Bool
+	case operator == "..":
+		return Range
+	}
+
+	c.errorf(pos, "invalid operation: %s %s %s", left, operator, right)
+
+	return Any
+}
+
+func (c *checker) index(node *ast.IndexExpression, s *scope) Type {
+	left := c.expr(node.Left, s)
+	index := c.expr(node.Index, s)
+
+	switch left {
+	case Array:
+		if !assignable(Int, index) {
+			c.errorf(node.Index.Pos(), "invalid index type %s for Array", index)
+		}
+	case Hash, Any:
+	default:
+		tuple, ok := left.(*Tuple)
+		if !ok {
+			c.errorf(node.Pos(), "cannot index %s", left)
+			break
+		}
+
+		if !assignable(Int, index) {
+			c.errorf(node.Index.Pos(), "invalid index type %s for %s", index, tuple)
+		}
+
+		// the type of a tuple value is known when the index is
+		if lit, ok := node.Index.(*ast.IntegerLiteral); ok && tuple != anyTuple {
+			if lit.Value < 0 || lit.Value >= int64(len(tuple.Types)) {
+				c.errorf(node.Index.Pos(), "index %d out of range for tuple of %d values", lit.Value, len(tuple.Types))
+				return Any
+			}
+			return tuple.Types[lit.Value]
+		}
+	}
+
+	return Any
+}
+
+// checkDestructuring declares the variables of a destructuring statement
+// whose value has type t.
+func (c *checker) checkDestructuring(node *ast.DestructuringStatement, t Type, s *scope) {
+	types := make([]Type, len(node.Names))
+	for i := range types {
+		types[i] = Any
+	}
+
+	switch node.Kind {
+	case token.LPAREN:
+		tuple, ok := t.(*Tuple)
+		switch {
+		case ok && tuple != anyTuple && len(tuple.Types) != len(node.Names):
+			c.errorf(node.Value.Pos(), "cannot destructure %d values into %d names", len(tuple.Types), len(node.Names))
+		case ok && tuple != anyTuple:
+			copy(types, tuple.Types)
+		case !ok && t != Any:
+			c.errorf(node.Value.Pos(), "cannot destructure %s as a tuple", t)
+		}
+	case token.LBRACKET:
+		if t != Array && t != Any {
+			c.errorf(node.Value.Pos(), "cannot destructure %s as an array", t)
+		}
+	case token.LBRACE:
+		if t != Hash && t != Any {
+			c.errorf(node.Value.Pos(), "cannot destructure %s as a hash", t)
+		}
+	}
+
+	for i, name := range node.Names {
+		c.declareVar(name, types[i], s)
+	}
+}
+
+func (c *checker) call(node *ast.CallExpression, s *scope) Type {
+	callee := c.expr(node.Function, s)
+	args := c.arguments(node.Arguments, s)
+
+	switch fn := callee.(type) {
+	case *Func:
+		c.checkArgs(fn, args, node.Pos())
+		return fn.Result()
+	default:
+		if callee != Any {
+			c.errorf(node.Function.Pos(), "cannot call %s", callee)
+		}
+		return Any
+	}
+}
+
+// arguments are the checked arguments of a call, positional and named.
+type arguments struct {
+	positional      []Type
+	positionalNodes []ast.Expression
+	named           []*ast.NamedArgument
+	namedTypes      []Type
+}
+
+func (c *checker) arguments(nodes []ast.Expression, s *scope) *arguments {
+	args := &arguments{}
+
+	for _, arg := range nodes {
+		if n, ok := arg.(*ast.NamedArgument); ok {
+			args.named = append(args.named, n)
+			args.namedTypes = append(args.namedTypes, c.expr(n.Value, s))
+		} else {
+			args.positional = append(args.positional, c.expr(arg, s))
+			args.positionalNodes = append(args.positionalNodes, arg)
+		}
+	}
+
+	return args
+}
+
+// checkArgs checks the number, names and types of the arguments of a call
+// to fn.
+func (c *checker) checkArgs(fn *Func, args *arguments, pos token.Position) {
+	if len(args.positional) > len(fn.Params) && !fn.Variadic {
+		c.errorf(pos, "wrong number of arguments to %s: got %d, want %s",
+			fn.Name, len(args.positional)+len(args.named), fn.arity())
+		return
+	}
+
+	passed := make([]bool, len(fn.Params))
+
+	for i, t := range args.positional {
+		if i >= len(fn.Params) {
+			break
+		}
+
+		passed[i] = true
+		if param := fn.Params[i]; !assignable(param.Type, t) {
+			c.errorf(args.positionalNodes[i].Pos(), "cannot use %s as %s in argument %s to %s", t, param.Type, param.Name, fn.Name)
+		}
+	}
+
+	for i, arg := range args.named {
+		idx := fn.paramIndex(arg.Name.Value)
+
+		if idx < 0 {
+			c.errorf(arg.Name.Pos(), "%s has no parameter %s", fn.Name, arg.Name.Value)
+			continue
+		}
+
+		if passed[idx] {
+			c.errorf(arg.Name.Pos(), "argument %s passed more than once", arg.Name.Value)
+			continue
+		}
+
+		passed[idx] = true
+		if param := fn.Params[idx]; !assignable(param.Type, args.namedTypes[i]) {
+			c.errorf(arg.Value.Pos(), "cannot use %s as %s in argument %s to %s", args.namedTypes[i], param.Type, param.Name, fn.Name)
+		}
+	}
+
+	for i, ok := range passed {
+		if ok || fn.Params[i].Optional {
+			continue
+		}
+
+		if len(args.named) == 0 {
+			c.errorf(pos, "wrong number of arguments to %s: got %d, want %s", fn.Name, len(args.positional), fn.arity())
+			return
+		}
+
+		c.errorf(pos, "missing argument %s to %s", fn.Params[i].Name, fn.Name)
+	}
+}
+
+func (c *checker) member(node *ast.DotExpression, s *scope) Type {
+	left := c.expr(node.Left, s)
+	name := node.Right.Value
+
+	switch left := left.(type) {
+	case *Class:
+		if field, owner := left.LookupField(name); field != nil {
+			if !field.Public && !c.canAccessPrivate(owner) {
+				c.errorf(node.Right.Pos(), "cannot access private field %s of %s outside the class", name, owner.Name)
+			}
+			return field.Type
+		}
+
+		if method, owner := left.LookupMethod(name); method != nil {
+			if !method.Public && !c.canAccessPrivate(owner) {
+				c.errorf(node.Right.Pos(), "cannot access private method %s of %s outside the class", name, owner.Name)
+			}
+			return method.Sig
+		}
+	case *Interface:
+		if method, ok := left.Methods[name]; ok {
+			return method
+		}
+	case *Enum:
+		if t := left.LookupField(name); t != nil {
+			return t
+		}
+	case *Meta:
+		if enum, ok := left.Of.(*Enum); ok {
+			return c.variant(enum, node.Right)
+		}
+	default:
+		switch {
+		case left == Any:
+			return Any
+		case left == Exception && (name == "message" || name == "kind"):
+			return String
+		case left == Exception && name == "value":
+			return Any
+		case left == Exception && name == "stack":
+			return Array
+		}
+	}
+
+	c.errorf(node.Right.Pos(), "%s has no member %s", left, name)
+
+	return Any
+}
+
+// canAccessPrivate reports whether the code being checked may use the
+// private members declared by owner.
+func (c *checker) canAccessPrivate(owner *Class) bool {
+	return c.class != nil && (c.class.IsA(owner) || owner.IsA(c.class))
+}
+
+func (c *checker) assign(node *ast.AssignExpression, s *scope) Type {
+	value := c.expr(node.Value, s)
+
+	var target Type
+	var describe string
+
+	switch t := node.Target.(type) {
+	case *ast.Identifier:
+		current, ok := s.lookup(t.Value)
+		if !ok {
+			c.errorf(t.Pos(), "cannot assign to undeclared variable: %s", t.Value)
+			return Any
+		}
+		if s.isConst(t.Value) {
+			c.errorf(t.Pos(), "cannot assign to constant %s", t.Value)
+			return Any
+		}
+		target, describe = current, t.Value
+	case *ast.DotExpression:
+		target, describe = c.member(t, s), "field "+t.Right.Value
+	case *ast.IndexExpression:
+		// the arrays and hashes of constants are frozen
+		if root := indexRoot(t); root != nil && s.isConst(root.Value) {
+			c.errorf(t.Pos(), "cannot modify constant %s", root.Value)
+		}
+		c.expr(node.Target, s)
+		target = Any
+	default:
+		c.expr(node.Target, s)
+		target = Any
+	}
+
+	if node.Operator != "=" {
+		// x += y is x = x + y
+		value = c.infix(node.Operator[:1], target, value, node.Pos())
+	}
+
+	if !assignable(target, value) {
+		c.errorf(node.Value.Pos(), "cannot assign %s to %s of type %s", value, describe, target)
+	}
+
+	return value
+}
+
+// indexRoot returns the variable indexed by `x[i][j]...`, or nil if the
+// indexed value is not a variable.
+func indexRoot(node *ast.IndexExpression) *ast.Identifier {
+	for {
+		switch left := node.Left.(type) {
+		case *ast.Identifier:
+			return left
+		case *ast.IndexExpression:
+			node = left
+		default:
+			return nil
+		}
+	}
+}
+
+// checkClass checks the field initializers and method bodies of a class,
+// and that it implements the methods of its interfaces.
+func (c *checker) checkClass(node *ast.ClassExpression, s *scope) Type {
+	klass := c.classes[node]
+
+	for _, name := range node.Implements {
+		if t, ok := s.lookup(name.Value); ok {
+			if meta, ok := t.(*Meta); ok {
+				if iface, ok := meta.Of.(*Interface); ok {
+					c.checkConformance(klass, iface, name.Pos())
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(node.Fields))
+	for name := range node.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := node.Fields[name]
+		if field.Value == nil {
+			continue
+		}
+
+		t := c.expr(field.Value, s)
+		if want := klass.Fields[name].Type; !assignable(want, t) {
+			c.errorf(field.Value.Pos(), "cannot use %s as %s for field %s", t, want, name)
+		}
+	}
+
+	for name, m := range node.Methods {
+		fn := m.Function
+		c.queueBody(klass.Methods[name].Sig, fn.Parameters, fn.Body, s, klass, name)
+	}
+
+	return &Meta{Of: klass}
+}
+
+// checkConformance reports the methods of iface that klass does not
+// implement with the same number of parameters and return types.
+func (c *checker) checkConformance(klass *Class, iface *Interface, pos token.Position) {
+	for _, name := range iface.MethodNames() {
+		want := iface.Methods[name]
+
+		method, _ := klass.LookupMethod(name)
+		switch {
+		case method == nil:
+			c.errorf(pos, "%s does not implement %s: missing method %s", klass.Name, iface.Name, name)
+		case !method.Public:
+			c.errorf(pos, "%s does not implement %s: method %s is private", klass.Name, iface.Name, name)
+		case len(method.Sig.Params) != len(want.Params):
+			c.errorf(pos, "%s does not implement %s: method %s takes %d parameters, want %d",
+				klass.Name, iface.Name, name, len(method.Sig.Params), len(want.Params))
+		case !sameTypes(method.Sig.Results, want.Results):
+			c.errorf(pos, "%s does not implement %s: method %s returns %s, want %s",
+				klass.Name, iface.Name, name, typeList(method.Sig.Results), typeList(want.Results))
+		}
+	}
+}
+
+// new checks `new(Class, args...)` against the init method of the class,
+// or else against its fields.
+func (c *checker) new(node *ast.NewExpression, s *scope) Type {
+	what := c.expr(node.What, s)
+
+	meta, ok := what.(*Meta)
+	if !ok {
+		if what != Any {
+			c.errorf(node.What.Pos(), "cannot create an instance of %s", what)
+		}
+		c.arguments(node.Arguments, s)
+		return Any
+	}
+
+	klass, ok := meta.Of.(*Class)
+	if !ok {
+		c.errorf(node.What.Pos(), "cannot create an instance of %s", what)
+		c.arguments(node.Arguments, s)
+		return Any
+	}
+
+	init, _ := klass.LookupMethod("init")
+
+	// the fields of a class without init can come in a hash
+	if hash, ok := singleHash(node.Arguments); ok && init == nil {
+		names := make([]string, 0, len(hash.Pairs))
+		for name := range hash.Pairs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			value := hash.Pairs[name]
+			c.checkField(klass, name, value.Pos(), c.expr(value, s), value.Pos())
+		}
+		return klass
+	}
+
+	args := c.arguments(node.Arguments, s)
+
+	if init != nil {
+		c.checkArgs(init.Sig, args, node.Pos())
+		return klass
+	}
+
+	if len(args.positional) == 1 && len(args.named) == 0 && assignable(Hash, args.positional[0]) {
+		return klass
+	}
+
+	if len(args.positional) > 0 {
+		c.errorf(node.Pos(), "%s has no init method, fields must be passed by name", klass.Name)
+		return klass
+	}
+
+	for i, arg := range args.named {
+		c.checkField(klass, arg.Name.Value, arg.Name.Pos(), args.namedTypes[i], arg.Value.Pos())
+	}
+
+	return klass
+}
+
+// singleHash returns the hash literal that is the only argument of a call.
+func singleHash(args []ast.Expression) (*ast.HashLiteral, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+	hash, ok := args[0].(*ast.HashLiteral)
+	return hash, ok
+}
+
+// checkField checks a value of type t passed to new for the field name of
+// klass.
+func (c *checker) checkField(klass *Class, name string, namePos token.Position, t Type, pos token.Position) {
+	field, _ := klass.LookupField(name)
+	if field == nil {
+		c.errorf(namePos, "%s has no field %s", klass.Name, name)
+		return
+	}
+
+	if !assignable(field.Type, t) {
+		c.errorf(pos, "cannot use %s as %s for field %s", t, field.Type, field.Name)
+	}
+}
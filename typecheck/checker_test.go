@@ -0,0 +1,335 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/parser"
+)
+
+func TestCheckValidPrograms(t *testing.T) {
+	tests := []string{
+		`func add(a: Int, b: Int) -> Int { return a + b }
+var x = add(1, 2) * 3`,
+		`func greet(name: String) -> String { return "hi " + name }
+println(greet("ann"), len("x"))`,
+		// functions may use names defined after them
+		`func too_old?(age: Int) -> Bool { return age > MAX_AGE }
+define(MAX_AGE, 35)`,
+		`var fx = func(n: Int) -> Int { return n }
+fx(1)`,
+		`func apply(f: Func, n: Int) { return f(n) }
+apply(func(n: Int) { return n }, 1)`,
+		`for i in 0..3 { println(i + 1) }`,
+		`for k, v in {a: 1} { println(k, v) }`,
+		`try { throw "x" } catch e { println(e.message + "!") }`,
+		`class Person {
+  public var name: String
+  var age: Int = 18
+  func init(name: String) { self.name = name }
+  func older() -> Int { return self.age + 1 }
+}
+var p = new(Person, "ann")
+p.name = "bob"
+p.older() + 1`,
+		`class User {
+  public var name: String
+}
+class Admin extends User {}
+func show(u: User) -> String { return u.name }
+show(new(Admin, name: "x"))`,
+		`interface Greeter {
+  func greet(name: String) -> String
+}
+class Person implements Greeter {
+  func greet(name: String) -> String { return name }
+}
+func hello(g: Greeter) -> String { return g.greet("x") }
+hello(new(Person))`,
+		// classes can be used in annotations before their definition
+		`func make() -> Box { return new(Box) }
+class Box {}`,
+		`func inc(n: Int, by: Int = n) { return n + by }
+inc(1) + inc(1, 2) + inc(by: 2, n: 1)`,
+		`func log(level: String, ...parts: Array) { return len(parts) }
+log("info") + log("info", 1, "a", true)`,
+		`func divmod(a: Int, b: Int) -> (Int, Int) { return a / b, a - b }
+var (q, r) = divmod(7, 2)
+q + r + divmod(1, 1)[0]
+var [x, y] = [1, 2]
+var {name, age} = {name: "ann", age: 1}`,
+		`var x = first([1, 2])
+x + 1
+x + "s"`,
+		// functions with results can end with a value instead of a return
+		`func one() -> Int { 1 }
+func sign(n: Int) -> Int { if n < 0 { -1 } else { return 1 } }
+func loop() -> Int { while true { return 1 } }
+func fail() -> Int { throw "x" }
+func safe() -> Int { try { fail() } catch e { 0 } }
+func box() -> Box { if true { new(Box) } }
+class Box {}`,
+	}
+
+	for _, input := range tests {
+		errors := check(t, input)
+		for _, err := range errors {
+			t.Errorf("unexpected error in %q: %s", input, err)
+		}
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`1 + "x"`, []string{"1:1: invalid operation: Int + String"}},
+		{`-"x"`, []string{"1:1: invalid operation: -String"}},
+		{`"a" < "b"`, []string{"1:1: invalid operation: String < String"}},
+		{`foo`, []string{"1:1: undefined: foo"}},
+		{`func add(a: Int, b: Int) -> Int { return a + b }
+add(1)`, []string{"2:1: wrong number of arguments to add: got 1, want 2"}},
+		{`func add(a: Int, b: Int) -> Int { return a + b }
+add(1, "2")`, []string{`2:8: cannot use String as Int in argument b to add`}},
+		{`func name() -> String { return 1 }`, []string{"1:32: cannot return Int from name, want String"}},
+		{`func f(x: Widget) {}`, []string{"1:11: unknown type Widget"}},
+		{`var x = 1
+x = "s"`, []string{"2:5: cannot assign String to x of type Int"}},
+		{`y = 1`, []string{"1:1: cannot assign to undeclared variable: y"}},
+		{`var n = 1
+n()`, []string{"2:1: cannot call Int"}},
+		{`var n = 1
+n[0]`, []string{"2:1: cannot index Int"}},
+		{`[1]["a"]`, []string{"1:5: invalid index type String for Array"}},
+		{`for x in 5 {}`, []string{"1:10: cannot iterate over Int"}},
+		{`try {} catch e { e.code }`, []string{"1:20: Exception has no member code"}},
+		{`len(1, 2)`, []string{"1:1: wrong number of arguments to len: got 2, want 1"}},
+		{`push(1, 2)`, []string{"1:6: cannot use Int as Array in argument array to push"}},
+		// errors in function bodies are found before they are called
+		{`func f() { return 1 + true }`, []string{"1:19: invalid operation: Int + Bool"}},
+		{`func inc(n: Int, by: Int = 1) { return n + by }
+inc()`, []string{"2:1: wrong number of arguments to inc: got 0, want 1 to 2"}},
+		{`func inc(n: Int, by: Int = 1) { return n + by }
+inc(1, by: "2")`, []string{`2:12: cannot use String as Int in argument by to inc`}},
+		{`func inc(n: Int, by: Int = 1) { return n + by }
+inc(n: 1, step: 2)`, []string{"2:11: inc has no parameter step"}},
+		{`func sub(a: Int, b: Int) { return a - b }
+sub(b: 1)`, []string{"2:1: missing argument a to sub"}},
+		{`func f(n: Int = "x") {}`, []string{`1:17: cannot use String as Int for the default of parameter n`}},
+		{`func count(first: Int, ...rest: Array) { return rest + 1 }
+count()`, []string{
+			"1:49: invalid operation: Array + Int",
+			"2:1: wrong number of arguments to count: got 0, want at least 1",
+		}},
+		{`func f() -> (Int, String) { return 1, 2 }`, []string{"1:36: cannot return (Int, Int) from f, want (Int, String)"}},
+		{`func f() -> (Int, String) { return 1, "x" }
+var (a, b, c) = f()`, []string{"2:17: cannot destructure 2 values into 3 names"}},
+		{`func f() -> (Int, String) { return 1, "x" }
+var (a, b) = f()
+a + b`, []string{"3:1: invalid operation: Int + String"}},
+		{`func f() -> (Int, String) { return 1, "x" }
+f()[2]`, []string{"2:5: index 2 out of range for tuple of 2 values"}},
+		{`var (a, b) = 1`, []string{"1:14: cannot destructure Int as a tuple"}},
+		{`var [a, b] = "ab"`, []string{"1:14: cannot destructure String as an array"}},
+		{`var {a} = [1]`, []string{"1:11: cannot destructure Array as a hash"}},
+		// functions with results must end with a value
+		{`func noret() -> Int { println("x") }`, []string{"1:36: missing return in noret"}},
+		{`func f(n: Int) -> Int { if n > 0 { return 1 } }`, []string{"1:47: missing return in f"}},
+		{`func f(n: Int) -> Int { while n > 0 { return 1 } }`, []string{"1:50: missing return in f"}},
+		{`func f() -> Int {}`, []string{"1:18: missing return in f"}},
+		// every error is reported
+		{`1 + "x"
+foo
+bar`, []string{"1:1: invalid operation: Int + String", "2:1: undefined: foo", "3:1: undefined: bar"}},
+	}
+
+	for _, tt := range tests {
+		assertErrors(t, tt.input, tt.expected)
+	}
+}
+
+func TestCheckClasses(t *testing.T) {
+	classes := `class Person {
+  public var name: String
+  var age: Int
+
+  func init(name: String, age: Int) {
+    self.name = name
+    self.age = age
+  }
+
+  private func secret() -> String { return self.name }
+}
+
+class Point {
+  public var x: Int
+}
+`
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`new(Person, "ann")`, []string{"16:1: wrong number of arguments to Person.init: got 1, want 2"}},
+		{`new(Person, "ann", "old")`, []string{"16:20: cannot use String as Int in argument age to Person.init"}},
+		{`new(Person, age: 1, nick: "x")`, []string{
+			"16:1: missing argument name to Person.init",
+			`16:21: Person.init has no parameter nick`,
+		}},
+		{`new(Person, "a", 1).nick`, []string{"16:21: Person has no member nick"}},
+		{`new(Person, "a", 1).age`, []string{"16:21: cannot access private field age of Person outside the class"}},
+		{`new(Person, "a", 1).secret()`, []string{"16:21: cannot access private method secret of Person outside the class"}},
+		{`new(Point, y: 1)`, []string{"16:12: Point has no field y"}},
+		{`new(Point, x: "1")`, []string{"16:15: cannot use String as Int for field x"}},
+		{`new(Point, 1)`, []string{"16:1: Point has no init method, fields must be passed by name"}},
+		{`new(Point, {"x": "1"})`, []string{"16:18: cannot use String as Int for field x"}},
+		{`new(Point, {x: 1, y: 2})`, []string{"16:22: Point has no field y"}},
+		{`new(Point, x: 1).x = "s"`, []string{`16:22: cannot assign String to field x of type Int`}},
+		{`class Bad { var x: Int = "s" }`, []string{"16:26: cannot use String as Int for field x"}},
+		{`class Bad { func f() -> Int { return self.y } }`, []string{"16:43: Bad has no member y"}},
+		{`class Bad extends Missing {}`, []string{"16:19: undefined: Missing"}},
+		{`interface Named {}
+class Bad extends Named {}`, []string{"17:19: Bad cannot extend Named"}},
+		{`class A extends B {}
+class B extends A {}`, []string{"17:17: B cannot extend A: inheritance cycle"}},
+		{`var n = 1
+new(n)`, []string{"17:5: cannot create an instance of Int"}},
+	}
+
+	for _, tt := range tests {
+		assertErrors(t, classes+tt.input, tt.expected)
+	}
+}
+
+func TestCheckInterfaces(t *testing.T) {
+	iface := `interface Greeter {
+  func greet(name: String) -> String
+}
+`
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`class Rock implements Greeter {}`, []string{"4:23: Rock does not implement Greeter: missing method greet"}},
+		{`class Rock implements Greeter {
+  func greet() -> String { return "" }
+}`, []string{"4:23: Rock does not implement Greeter: method greet takes 0 parameters, want 1"}},
+		{`class Rock implements Greeter {
+  func greet(name: String) -> Int { return 1 }
+}`, []string{"4:23: Rock does not implement Greeter: method greet returns (Int), want (String)"}},
+		{`class Rock {}
+func hello(g: Greeter) {}
+hello(new(Rock))`, []string{"6:7: cannot use Rock as Greeter in argument g to hello"}},
+		{`func hello(g: Greeter) { g.wave() }`, []string{"4:28: Greeter has no member wave"}},
+	}
+
+	for _, tt := range tests {
+		assertErrors(t, iface+tt.input, tt.expected)
+	}
+}
+
+func TestCheckEnums(t *testing.T) {
+	enum := `enum Status {
+  Active
+  Suspended(reason: String)
+  Closed
+}
+`
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`func f(s: Status) -> String {
+  return match s {
+    Status.Active -> "active"
+    Status.Suspended(r) -> r
+    Status.Closed -> "closed"
+  }
+}
+f(Status.Suspended("late"))
+match 1 { 1 -> "one", n -> n + 1 }
+match [1] { [x, ...rest] -> len(rest), _ -> 0 }`, []string{}},
+		{`func f(s: Status) { return match s { Status.Active -> 1, _ -> 2 } }`, []string{}},
+		{`func f(s: Status) {
+  return match s {
+    Status.Active -> 1
+    Status.Suspended("x") -> 2
+  }
+}`, []string{"7:10: match on Status is not exhaustive: missing Status.Suspended, Status.Closed"}},
+		// the enum of an untyped value is taken from the patterns
+		{`func f(s: Any) { return match s { Status.Closed -> 1 } }`, []string{
+			"6:25: match on Status is not exhaustive: missing Status.Active, Status.Suspended",
+		}},
+		{`Status.Suspended(1)`, []string{"6:18: cannot use Int as String in argument reason to Status.Suspended"}},
+		{`Status.Paused`, []string{"6:8: Status has no variant Paused"}},
+		{`func f(s: Status) -> Int { return s }`, []string{"6:35: cannot return Status from f, want Int"}},
+		{`match 1 { "x" -> 1, [a] -> a, Status.Active -> 2, _ -> 3 }`, []string{
+			"6:11: cannot match String against Int",
+			"6:21: cannot match Array against Int",
+			"6:31: cannot match Status against Int",
+		}},
+		{`match Status.Active { Status.Suspended(a, b) -> 1, _ -> 2 }`, []string{"6:23: Status.Suspended has 1 fields, pattern has 2"}},
+		{`var n = 1
+match 1 { n.Active -> 1, _ -> 2 }`, []string{"7:11: n is not an enum"}},
+	}
+
+	for _, tt := range tests {
+		assertErrors(t, enum+tt.input, tt.expected)
+	}
+}
+
+func TestCheckConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`const MAX = 3
+func f(MAX: Int) { return MAX }
+MAX + f(1)`, []string{"2:8: cannot redeclare constant MAX"}},
+		{`const MAX = 3
+MAX = 4`, []string{"2:1: cannot assign to constant MAX"}},
+		{`define(MAX, 3)
+func f() { MAX += 1 }`, []string{"2:12: cannot assign to constant MAX"}},
+		{`define(MAX, 3)
+var MAX = 99`, []string{"2:5: cannot redeclare constant MAX"}},
+		{`const X = 1
+for X in 0..2 {}`, []string{"2:5: cannot redeclare constant X"}},
+		{`const XS = {a: [1]}
+XS["a"][0] = 2`, []string{"2:1: cannot modify constant XS"}},
+	}
+
+	for _, tt := range tests {
+		assertErrors(t, tt.input, tt.expected)
+	}
+}
+
+func check(t *testing.T, input string) []*Error {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors in %q: %v", input, p.Errors())
+	}
+
+	return Check(program)
+}
+
+func assertErrors(t *testing.T, input string, expected []string) {
+	t.Helper()
+
+	errors := check(t, input)
+	if len(errors) != len(expected) {
+		t.Errorf("wrong number of errors for %q. expected=%q, got=%v", input, expected, errors)
+		return
+	}
+
+	for i, err := range errors {
+		if err.Error() != expected[i] {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", input, expected[i], err.Error())
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package typecheck
+
+import (
+	"strings"
+
+	"github.com/emo-lang/emo/ast"
+)
+
+func (c *checker) declareEnum(node *ast.EnumExpression, s *scope) {
+	enum := c.enums[node]
+
+	for _, v := range node.Variants {
+		variant := &Variant{Name: v.Name.Value}
+		for _, f := range v.Fields {
+			variant.Fields = append(variant.Fields, &Param{Name: f.Name.Value, Type: c.resolveType(f.Type, s)})
+		}
+
+		enum.Variants[variant.Name] = variant
+		enum.Order = append(enum.Order, variant.Name)
+	}
+}
+
+// variant returns the type of `Enum.Variant`: a value of the enum for a
+// variant without fields, or a function that creates one from its fields.
+func (c *checker) variant(enum *Enum, name *ast.Identifier) Type {
+	variant, ok := enum.Variants[name.Value]
+	if !ok {
+		c.errorf(name.Pos(), "%s has no variant %s", enum.Name, name.Value)
+		return Any
+	}
+
+	if len(variant.Fields) == 0 {
+		return enum
+	}
+
+	return &Func{Name: enum.Name + "." + variant.Name, Params: variant.Fields, Results: []Type{enum}}
+}
+
+// checkMatch checks the arms of a match and that a match over an enum
+// handles every variant.
+func (c *checker) checkMatch(node *ast.MatchExpression, s *scope) Type {
+	t := c.expr(node.Value, s)
+
+	for _, arm := range node.Arms {
+		c.checkPattern(arm.Pattern, t, s)
+
+		switch body := arm.Body.(type) {
+		case *ast.BlockStatement:
+			c.checkStatements(body.Statements, s)
+		case ast.Expression:
+			c.expr(body, s)
+		}
+	}
+
+	enum, ok := t.(*Enum)
+	if t == Any {
+		// an untyped value is matched against the enum of the patterns
+		enum, ok = c.patternsEnum(node.Arms, s)
+	}
+
+	if ok {
+		if missing := missingVariants(enum, node.Arms); len(missing) > 0 {
+			c.errorf(node.Pos(), "match on %s is not exhaustive: missing %s", enum.Name, strings.Join(missing, ", "))
+		}
+	}
+
+	return Any
+}
+
+// checkPattern checks that pattern can match a value of type t and declares
+// the names it binds.
+func (c *checker) checkPattern(pattern ast.Pattern, t Type, s *scope) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		c.declare(pattern.Name, t, s)
+	case *ast.LiteralPattern:
+		lit := c.expr(pattern.Value, s)
+		if !assignable(t, lit) {
+			c.errorf(pattern.Pos(), "cannot match %s against %s", lit, t)
+		}
+	case *ast.ArrayPattern:
+		if !assignable(t, Array) {
+			c.errorf(pattern.Pos(), "cannot match Array against %s", t)
+		}
+
+		for _, el := range pattern.Elements {
+			c.checkPattern(el, Any, s)
+		}
+
+		if pattern.Rest != nil {
+			c.declare(pattern.Rest, Array, s)
+		}
+	case *ast.HashPattern:
+		if !assignable(t, Hash) {
+			c.errorf(pattern.Pos(), "cannot match Hash against %s", t)
+		}
+
+		for _, pair := range pattern.Pairs {
+			c.checkPattern(pair.Pattern, Any, s)
+		}
+	case *ast.VariantPattern:
+		c.checkVariantPattern(pattern, t, s)
+	}
+}
+
+func (c *checker) checkVariantPattern(pattern *ast.VariantPattern, t Type, s *scope) {
+	fields := make([]*Param, len(pattern.Arguments))
+
+	switch enum := c.lookupMeta(pattern.Enum, s).(type) {
+	case nil:
+	case *Enum:
+		variant, ok := enum.Variants[pattern.Variant.Value]
+		if !ok {
+			c.errorf(pattern.Variant.Pos(), "%s has no variant %s", enum.Name, pattern.Variant.Value)
+			break
+		}
+
+		if pattern.Arguments != nil && len(pattern.Arguments) != len(variant.Fields) {
+			c.errorf(pattern.Pos(), "%s.%s has %d fields, pattern has %d",
+				enum.Name, variant.Name, len(variant.Fields), len(pattern.Arguments))
+		} else {
+			copy(fields, variant.Fields)
+		}
+
+		if !assignable(t, enum) {
+			c.errorf(pattern.Pos(), "cannot match %s against %s", enum, t)
+		}
+	default:
+		c.errorf(pattern.Enum.Pos(), "%s is not an enum", pattern.Enum.Value)
+	}
+
+	for i, arg := range pattern.Arguments {
+		var ft Type = Any
+		if fields[i] != nil {
+			ft = fields[i].Type
+		}
+
+		c.checkPattern(arg, ft, s)
+	}
+}
+
+// patternsEnum returns the enum of the first variant pattern of arms.
+func (c *checker) patternsEnum(arms []*ast.MatchArm, s *scope) (*Enum, bool) {
+	for _, arm := range arms {
+		if pattern, ok := arm.Pattern.(*ast.VariantPattern); ok {
+			t, _ := s.lookup(pattern.Enum.Value)
+			meta, ok := t.(*Meta)
+			if !ok {
+				return nil, false
+			}
+
+			enum, ok := meta.Of.(*Enum)
+			return enum, ok
+		}
+	}
+	return nil, false
+}
+
+// missingVariants returns the variants of enum, as `Enum.Variant`, that no
+// arm matches whatever the values of their fields.
+func missingVariants(enum *Enum, arms []*ast.MatchArm) []string {
+	covered := map[string]bool{}
+
+	for _, arm := range arms {
+		switch pattern := arm.Pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+			return nil
+		case *ast.VariantPattern:
+			if pattern.Enum.Value == enum.Name && irrefutable(pattern.Arguments) {
+				covered[pattern.Variant.Value] = true
+			}
+		}
+	}
+
+	missing := []string{}
+	for _, name := range enum.Order {
+		if !covered[name] {
+			missing = append(missing, enum.Name+"."+name)
+		}
+	}
+
+	return missing
+}
+
+// irrefutable reports whether patterns match any values.
+func irrefutable(patterns []ast.Pattern) bool {
+	for _, p := range patterns {
+		switch p.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,349 @@
+package typecheck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type is the static type of an expression.
+type Type interface {
+	String() string
+}
+
+// Basic is a built-in type, named as in annotations.
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	// Any is the type of expressions the checker knows nothing about. Every
+	// type can be used as Any and Any can be used as every type.
+	Any = &Basic{Name: "Any"}
+
+	Int       = &Basic{Name: "Int"}
+	String    = &Basic{Name: "String"}
+	Bool      = &Basic{Name: "Bool"}
+	Nil       = &Basic{Name: "Nil"}
+	Array     = &Basic{Name: "Array"}
+	Hash      = &Basic{Name: "Hash"}
+	Range     = &Basic{Name: "Range"}
+	Exception = &Basic{Name: "Exception"}
+)
+
+// basicTypes are the types that annotations can name besides classes and
+// interfaces.
+var basicTypes = map[string]Type{
+	"Any":       Any,
+	"Int":       Int,
+	"String":    String,
+	"Bool":      Bool,
+	"Nil":       Nil,
+	"Array":     Array,
+	"Hash":      Hash,
+	"Range":     Range,
+	"Exception": Exception,
+	"Func":      anyFunc,
+	"Tuple":     anyTuple,
+}
+
+type Param struct {
+	Name     string
+	Type     Type
+	Optional bool // has a default value
+}
+
+// Func is the type of a function. A function without declared return types
+// returns Any.
+type Func struct {
+	Name     string
+	Params   []*Param
+	Results  []Type
+	Variadic bool // takes any number of arguments after Params
+}
+
+// anyFunc is the type annotated as `Func`: a function of any signature.
+var anyFunc = &Func{Name: "Func", Variadic: true}
+
+func (f *Func) String() string {
+	if f == anyFunc {
+		return "Func"
+	}
+
+	params := []string{}
+	for _, p := range f.Params {
+		params = append(params, p.Type.String())
+	}
+	if f.Variadic {
+		params = append(params, "...")
+	}
+
+	return "func(" + strings.Join(params, ", ") + ") -> " + typeList(f.Results)
+}
+
+// arity describes how many arguments f takes.
+func (f *Func) arity() string {
+	required := 0
+	for _, p := range f.Params {
+		if !p.Optional {
+			required++
+		}
+	}
+
+	switch {
+	case f.Variadic:
+		return fmt.Sprintf("at least %d", required)
+	case required == len(f.Params):
+		return fmt.Sprint(required)
+	default:
+		return fmt.Sprintf("%d to %d", required, len(f.Params))
+	}
+}
+
+// paramIndex returns the index of the parameter of f with the given name,
+// or -1 if there is none.
+func (f *Func) paramIndex(name string) int {
+	for i, p := range f.Params {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Result is the type of a call to f: a tuple when f has several return
+// types.
+func (f *Func) Result() Type {
+	switch len(f.Results) {
+	case 0:
+		return Any
+	case 1:
+		return f.Results[0]
+	default:
+		return &Tuple{Types: f.Results}
+	}
+}
+
+// Tuple is the type of the values returned together by `return a, b`.
+type Tuple struct {
+	Types []Type
+}
+
+// anyTuple is the type annotated as `Tuple`: a tuple of any values.
+var anyTuple = &Tuple{}
+
+func (t *Tuple) String() string {
+	if t == anyTuple {
+		return "Tuple"
+	}
+	return typeList(t.Types)
+}
+
+// typeList formats return types: `(Int, String)`, or `nothing` when there
+// are none.
+func typeList(types []Type) string {
+	if len(types) == 0 {
+		return "nothing"
+	}
+
+	names := []string{}
+	for _, t := range types {
+		names = append(names, t.String())
+	}
+
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+type Field struct {
+	Name   string
+	Type   Type
+	Public bool
+}
+
+type Method struct {
+	Sig    *Func
+	Public bool
+}
+
+// Class is the type of the instances of a class.
+type Class struct {
+	Name       string
+	Super      *Class
+	Interfaces []*Interface
+	Fields     map[string]*Field
+	Methods    map[string]*Method
+}
+
+func (klass *Class) String() string { return klass.Name }
+
+// LookupField finds a field declared by the class or its superclasses and
+// returns it together with the class that declares it.
+func (klass *Class) LookupField(name string) (*Field, *Class) {
+	for c := klass; c != nil; c = c.Super {
+		if field, ok := c.Fields[name]; ok {
+			return field, c
+		}
+	}
+	return nil, nil
+}
+
+// LookupMethod finds a method defined by the class or its superclasses and
+// returns it together with the class that defines it.
+func (klass *Class) LookupMethod(name string) (*Method, *Class) {
+	for c := klass; c != nil; c = c.Super {
+		if method, ok := c.Methods[name]; ok {
+			return method, c
+		}
+	}
+	return nil, nil
+}
+
+// IsA reports whether klass is other or inherits from it.
+func (klass *Class) IsA(other *Class) bool {
+	for c := klass; c != nil; c = c.Super {
+		if c == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Implements reports whether klass or one of its superclasses declares to
+// implement iface.
+func (klass *Class) Implements(iface *Interface) bool {
+	for c := klass; c != nil; c = c.Super {
+		for _, i := range c.Interfaces {
+			if i == iface {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Interface is the type of the instances of classes that implement it.
+type Interface struct {
+	Name    string
+	Methods map[string]*Func
+}
+
+func (iface *Interface) String() string { return iface.Name }
+
+// MethodNames returns the names of the methods of iface, sorted.
+func (iface *Interface) MethodNames() []string {
+	names := make([]string, 0, len(iface.Methods))
+	for name := range iface.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Enum is the type of the values of the variants of an enum.
+type Enum struct {
+	Name     string
+	Variants map[string]*Variant
+	Order    []string // variant names in declaration order
+}
+
+func (e *Enum) String() string { return e.Name }
+
+// Variant is a variant of an enum with the fields of its values.
+type Variant struct {
+	Name   string
+	Fields []*Param
+}
+
+// LookupField returns the type of the field with the given name in the
+// first variant that has it, or nil if no variant has it.
+func (e *Enum) LookupField(name string) Type {
+	for _, v := range e.Order {
+		for _, f := range e.Variants[v].Fields {
+			if f.Name == name {
+				return f.Type
+			}
+		}
+	}
+	return nil
+}
+
+// Meta is the type of the name of a class, interface or enum, as opposed to
+// the type of its instances.
+type Meta struct {
+	Of Type
+}
+
+func (m *Meta) String() string {
+	switch m.Of.(type) {
+	case *Interface:
+		return "interface " + m.Of.String()
+	case *Enum:
+		return "enum " + m.Of.String()
+	}
+	return "class " + m.Of.String()
+}
+
+// assignable reports whether a value of type src can be used where dst is
+// expected.
+func assignable(dst, src Type) bool {
+	if dst == Any || src == Any || dst == src {
+		return true
+	}
+
+	switch dst := dst.(type) {
+	case *Func:
+		_, ok := src.(*Func)
+		return ok
+	case *Tuple:
+		tuple, ok := src.(*Tuple)
+		if !ok || dst == anyTuple {
+			return ok
+		}
+
+		if len(tuple.Types) != len(dst.Types) {
+			return false
+		}
+
+		for i := range dst.Types {
+			if !assignable(dst.Types[i], tuple.Types[i]) {
+				return false
+			}
+		}
+
+		return true
+	case *Class:
+		// fields of class types start as nil
+		if src == Nil {
+			return true
+		}
+
+		klass, ok := src.(*Class)
+		return ok && klass.IsA(dst)
+	case *Interface:
+		if src == Nil {
+			return true
+		}
+
+		klass, ok := src.(*Class)
+		return ok && klass.Implements(dst)
+	}
+
+	return false
+}
+
+func sameTypes(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
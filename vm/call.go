@@ -0,0 +1,362 @@
+package vm
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/compiler"
+	"github.com/emo-lang/emo/evaluator"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// callValue calls the function below the argc arguments on top of the
+// stack. names holds the name of each named argument, and an empty string
+// for each positional one, or is nil when all arguments are positional.
+// Closures run on the machine, other functions run in the evaluator.
+func (vm *VM) callValue(argc int, names *object.Array, callPos token.Position) *object.Error {
+	if _, ok := vm.stack[vm.sp-1-argc].(*Closure); ok {
+		return vm.enter(argc, names, callPos)
+	}
+
+	fn := vm.stack[vm.sp-1-argc]
+	args, named, values := splitArguments(vm.stack[vm.sp-argc:vm.sp], names)
+	vm.sp -= argc + 1
+
+	return vm.result(evaluator.Apply(fn, args, named, values, callPos))
+}
+
+// splitArguments splits the arguments of a call into the positional ones
+// and the names and values of the named ones.
+func splitArguments(all []object.Object, names *object.Array) (args []object.Object, named []string, values []object.Object) {
+	args = []object.Object{}
+	for i, arg := range all {
+		if name := argumentName(names, i); name != "" {
+			named = append(named, name)
+			values = append(values, arg)
+		} else {
+			args = append(args, arg)
+		}
+	}
+
+	return args, named, values
+}
+
+func argumentName(names *object.Array, i int) string {
+	if names == nil {
+		return ""
+	}
+	return names.Elements[i].(*object.String).Value
+}
+
+// enter starts a call of the closure below the argc arguments on top of the
+// stack. The arguments are bound to the parameters like the evaluator binds
+// them, leaving the parameters to default unset, and the other locals of
+// the closure follow them.
+func (vm *VM) enter(argc int, names *object.Array, callPos token.Position) *object.Error {
+	// the first frame runs the program, not a call
+	if len(vm.frames) > evaluator.MaxCallDepth {
+		return evaluator.RecursionError()
+	}
+
+	bp := vm.sp - argc
+	cl := vm.stack[bp-1].(*Closure)
+	fn := cl.Fn
+	params := fn.Parameters
+
+	simple := names == nil && argc == len(params)
+	for _, param := range params {
+		if param.Variadic {
+			simple = false
+		}
+	}
+
+	if !simple {
+		values, err := bindArguments(fn.DisplayName(), params, vm.stack[bp:vm.sp], names)
+		if err != nil {
+			return err
+		}
+
+		vm.sp = bp
+		for _, val := range values {
+			vm.push(val)
+		}
+	}
+
+	if evaluator.Strict {
+		for i, param := range params {
+			if val := vm.stack[bp+i]; val != nil {
+				if err := evaluator.CheckArgument(fn.DisplayName(), param, val, cl); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	top := bp + len(fn.Locals)
+	if need := top + fn.MaxStack + 1; need > len(vm.stack) {
+		stack := make([]object.Object, 2*need)
+		copy(stack, vm.stack[:vm.sp])
+		vm.stack = stack
+	}
+
+	for i := vm.sp; i < top; i++ {
+		vm.stack[i] = nil
+	}
+
+	// a method finds self, super and itself after its parameters
+	if cl.self != nil {
+		n := bp + len(params)
+		vm.stack[n] = cl.self
+		if cl.class.Super != nil {
+			vm.stack[n+1] = &object.Super{Instance: cl.self, Klass: cl.class.Super}
+		}
+		vm.stack[n+2] = cl
+	}
+
+	for _, slot := range fn.Cells {
+		vm.stack[bp+slot] = &cell{value: vm.stack[bp+slot]}
+	}
+	vm.sp = top
+
+	vm.frames = append(vm.frames, &frame{cl: cl, bp: bp, callPos: callPos})
+
+	return nil
+}
+
+// tailCall makes the call of the closure below the argc arguments on top of
+// the stack in place of the call fr is running, which returns its result.
+// The stack traces of errors and the checks of the result in strict mode
+// still see the call fr was running.
+func (vm *VM) tailCall(fr *frame, argc int, names *object.Array, callPos token.Position) *object.Error {
+	// fr is done with, so its frames move to the next call as runFunction
+	// moves them in the evaluator
+	tail := evaluator.TrimTailFrames(append(fr.tail, object.StackFrame{Function: fr.cl.Fn.DisplayName(), Call: fr.callPos}))
+
+	checks := fr.checks
+	if evaluator.Strict && !slices.ContainsFunc(checks, func(cl *Closure) bool { return cl.Fn == fr.cl.Fn }) {
+		checks = append(slices.Clip(checks), fr.cl)
+	}
+	instance := fr.instance
+
+	// the closure and the arguments replace the closure and the locals of fr
+	n := argc + 1
+	copy(vm.stack[fr.bp-1:], vm.stack[vm.sp-n:vm.sp])
+	vm.sp = fr.bp - 1 + n
+	vm.frames = vm.frames[:len(vm.frames)-1]
+
+	if err := vm.enter(argc, names, callPos); err != nil {
+		// the call fails as a call made by fr would
+		err.Pos = callPos
+		for i := len(tail) - 1; i >= 0; i-- {
+			err.Stack = append(err.Stack, tail[i])
+		}
+		return err
+	}
+
+	next := vm.frames[len(vm.frames)-1]
+	next.tail, next.checks, next.instance = tail, checks, instance
+
+	return nil
+}
+
+// newInstance creates an instance of the class below the argc arguments
+// on top of the stack, named name in the program. The init method of the
+// class, if it has one, is called with the arguments and returns the
+// instance, else the arguments set the fields of the instance.
+func (vm *VM) newInstance(argc int, names *object.Array, name string, callPos token.Position) *object.Error {
+	what := vm.stack[vm.sp-1-argc]
+	klass, ok := what.(*object.Class)
+	if !ok {
+		return newError(object.TYPE_ERROR, "cannot create an instance of %s", what.Type())
+	}
+
+	instance, err := evaluator.NewInstance(klass, name)
+	if err != nil {
+		return err
+	}
+
+	init := evaluator.Init(instance)
+	if init == nil {
+		args, named, values := splitArguments(vm.stack[vm.sp-argc:vm.sp], names)
+		vm.sp -= argc + 1
+
+		if err := evaluator.SetFields(instance, args, named, values); err != nil {
+			return err
+		}
+		vm.push(instance)
+
+		return nil
+	}
+
+	vm.stack[vm.sp-1-argc] = init
+	if _, ok := init.(*Closure); ok {
+		if err := vm.enter(argc, names, callPos); err != nil {
+			return err
+		}
+		vm.frames[len(vm.frames)-1].instance = instance
+
+		return nil
+	}
+
+	// the init of a class of a module run by the evaluator
+	if err := vm.callValue(argc, names, callPos); err != nil {
+		return err
+	}
+	vm.stack[vm.sp-1] = instance
+
+	return nil
+}
+
+// newClass creates the class t on top of the closures of its methods and
+// of its field initializers, and of its superclass, if any.
+func (vm *VM) newClass(t *compiler.Class) *object.Error {
+	node := t.Node
+	klass := &object.Class{Name: node.Name, Fields: node.Fields, Methods: node.Methods}
+	code := &class{klass: klass, methods: map[string]*Closure{}, fields: map[string]*Closure{}}
+	klass.Compiled = code
+
+	methods := slices.Sorted(maps.Keys(node.Methods))
+	fields := []string{}
+	for _, name := range slices.Sorted(maps.Keys(node.Fields)) {
+		if node.Fields[name].Value != nil {
+			fields = append(fields, name)
+		}
+	}
+
+	base := vm.sp - len(methods) - len(fields)
+	for i, name := range methods {
+		code.methods[name] = vm.stack[base+i].(*Closure)
+	}
+	for i, name := range fields {
+		code.fields[name] = vm.stack[base+len(methods)+i].(*Closure)
+	}
+	vm.sp = base
+
+	if node.Super != nil {
+		if err := evaluator.Extend(klass, vm.pop()); err != nil {
+			return err
+		}
+	}
+	vm.push(klass)
+
+	return nil
+}
+
+// importModule pushes the module imported by imp. A module of the program
+// runs the first time it is imported, with globals of its own, and is
+// imported again after an error as the evaluator evaluates it again.
+func (vm *VM) importModule(imp *compiler.Import) *object.Error {
+	if imp.Err != nil {
+		return &object.Error{Kind: imp.Err.Kind, Message: imp.Err.Message}
+	}
+
+	if imp.Module < 0 {
+		module, err := evaluator.Import(imp.Node)
+		if err != nil {
+			return err
+		}
+		vm.push(module)
+		return nil
+	}
+
+	m := vm.modules[imp.Module]
+	if m.value != nil {
+		vm.push(m.value)
+		return nil
+	}
+
+	if err := evaluator.ImportCycle(vm.importing, m.path); err != nil {
+		return err
+	}
+
+	for _, slot := range m.globals {
+		vm.globals[slot], vm.constGlobals[slot] = nil, false
+	}
+
+	vm.importing = append(vm.importing, m.path)
+	defer func() { vm.importing = vm.importing[:len(vm.importing)-1] }()
+
+	if err, ok := vm.call(&Closure{Fn: m.fn, module: m, vm: vm}, nil, imp.Node.Pos()).(*object.Error); ok {
+		return err
+	}
+
+	m.value = &object.Module{Name: m.name, Path: m.path, Env: &moduleScope{vm: vm, module: m}}
+	vm.push(m.value)
+
+	return nil
+}
+
+// bindArguments returns the value of each parameter for a call with the
+// arguments args, nil for the parameters left to their default. A variadic
+// last parameter collects the remaining positional arguments in an array.
+func bindArguments(name string, params []*ast.TypedField, args []object.Object, names *object.Array) ([]object.Object, *object.Error) {
+	var rest *ast.TypedField
+	if n := len(params); n > 0 && params[n-1].Variadic {
+		params, rest = params[:n-1], params[n-1]
+	}
+
+	var positional []object.Object
+	var named []int
+
+	for i, arg := range args {
+		if argumentName(names, i) == "" {
+			positional = append(positional, arg)
+		} else {
+			named = append(named, i)
+		}
+	}
+
+	all := params
+	if rest != nil {
+		all = append(all[:len(all):len(all)], rest)
+	}
+
+	if len(positional) > len(params) && rest == nil {
+		return nil, evaluator.ArityError(name, all, len(args))
+	}
+
+	values := make([]object.Object, len(all))
+	copy(values, positional[:min(len(positional), len(params))])
+
+	for _, i := range named {
+		argName := argumentName(names, i)
+
+		idx := -1
+		for j, param := range params {
+			if param.Name.Value == argName {
+				idx = j
+			}
+		}
+
+		if idx < 0 {
+			return nil, newError(object.ARGUMENT_ERROR, "%s has no parameter %s", name, argName)
+		}
+
+		if values[idx] != nil {
+			return nil, newError(object.ARGUMENT_ERROR, "argument %s passed more than once", argName)
+		}
+
+		values[idx] = args[i]
+	}
+
+	for i, param := range params {
+		if values[i] == nil && param.Default == nil {
+			if len(named) == 0 {
+				return nil, evaluator.ArityError(name, all, len(positional))
+			}
+			return nil, newError(object.ARGUMENT_ERROR, "missing argument %s to %s", param.Name.Value, name)
+		}
+	}
+
+	if rest != nil {
+		elements := []object.Object{}
+		if len(positional) > len(params) {
+			elements = append(elements, positional[len(params):]...)
+		}
+		values[len(params)] = &object.Array{Elements: elements}
+	}
+
+	return values, nil
+}
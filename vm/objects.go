@@ -0,0 +1,179 @@
+package vm
+
+import (
+	"github.com/emo-lang/emo/compiler"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// Closure is a compiled function with the variables it captured. To the
+// program it is a function like any other. A method bound to an instance
+// also has the instance and the class defining the method.
+type Closure struct {
+	Fn   *compiler.Function
+	Free []*cell
+
+	self  *object.ClassInstance
+	class *object.Class
+
+	module *module // where the function was declared
+	vm     *VM
+}
+
+func (c *Closure) Type() object.ObjectType { return object.FUNCTION_OBJ }
+func (c *Closure) Inspect() string         { return c.Fn.Inspect() }
+
+// Call runs the closure with positional arguments, for the builtins that
+// take functions.
+func (c *Closure) Call(args ...object.Object) object.Object {
+	return c.vm.call(c, args, token.Position{})
+}
+
+// Get looks up name where the closure was created, in the variables it
+// captured and then in the globals of its module.
+func (c *Closure) Get(name string) (object.Object, bool) {
+	return c.vm.lookup(c.module, c.Fn.Free, c.Free, name)
+}
+
+// class is the code of a class compiled for the machine: the closures of
+// its methods and of its field initializers.
+type class struct {
+	klass   *object.Class
+	methods map[string]*Closure
+	fields  map[string]*Closure
+}
+
+// Method returns the method name bound to instance.
+func (c *class) Method(instance *object.ClassInstance, name string) object.Object {
+	method := *c.methods[name]
+	method.self, method.class = instance, c.klass
+
+	return &method
+}
+
+// InitialValue runs the initializer of the field name. The evaluator runs
+// initializers without a call, so their calls are left out of the stack
+// traces of their errors.
+func (c *class) InitialValue(name string) object.Object {
+	init := c.fields[name]
+
+	val := init.vm.call(init, nil, token.Position{})
+	if err, ok := val.(*object.Error); ok && len(err.Stack) > 0 {
+		err.Stack = err.Stack[:len(err.Stack)-1]
+	}
+
+	return val
+}
+
+// enumScope is where an enum looks up the types of the fields of its
+// variants: the variables named names it captured, and the globals of
+// the module declaring it.
+type enumScope struct {
+	vm     *VM
+	module *module
+	names  []string
+	cells  []*cell
+}
+
+func (s *enumScope) Get(name string) (object.Object, bool) {
+	return s.vm.lookup(s.module, s.names, s.cells, name)
+}
+
+// module is a file of a compiled program, the program itself or a file it
+// imports, with the global slots of its top-level names.
+type module struct {
+	fn      *compiler.Function // the top-level statements
+	name    string
+	path    string
+	globals []int
+	slots   map[string]int // the slots by name
+
+	value *object.Module // once it is imported
+}
+
+// moduleScope is where the members of an imported module are looked up:
+// its globals.
+type moduleScope struct {
+	vm     *VM
+	module *module
+}
+
+func (s *moduleScope) Get(name string) (object.Object, bool) {
+	return s.vm.lookup(s.module, nil, nil, name)
+}
+
+// cell holds a local captured by closures, shared by the function that
+// declares it and the closures. A nil value is an undeclared local.
+type cell struct {
+	value object.Object
+}
+
+func (c *cell) Type() object.ObjectType { return "CELL" }
+func (c *cell) Inspect() string {
+	if c.value == nil {
+		return "<unset>"
+	}
+	return c.value.Inspect()
+}
+
+// iterator steps through the value of a for loop, like the evaluator does:
+// the elements of arrays, the pairs of hashes by key, the characters of
+// strings and the numbers of ranges, each with its index or key.
+type iterator struct {
+	elements []object.Object
+	pairs    []object.HashPair
+	runes    []rune
+	rng      *object.Range
+
+	kind object.ObjectType
+	next int
+}
+
+func (it *iterator) Type() object.ObjectType { return "ITERATOR" }
+func (it *iterator) Inspect() string         { return "<iterator>" }
+
+func newIterator(iterable object.Object) (*iterator, bool) {
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		return &iterator{kind: object.ARRAY_OBJ, elements: iterable.Elements}, true
+	case *object.Hash:
+		return &iterator{kind: object.HASH_OBJ, pairs: iterable.SortedPairs()}, true
+	case *object.String:
+		return &iterator{kind: object.STRING_OBJ, runes: []rune(iterable.Value)}, true
+	case *object.Range:
+		return &iterator{kind: object.RANGE_OBJ, rng: iterable}, true
+	default:
+		return nil, false
+	}
+}
+
+// step returns the key and value of the next iteration, or false when the
+// iteration is over.
+func (it *iterator) step() (key, value object.Object, ok bool) {
+	i := it.next
+	it.next++
+
+	switch it.kind {
+	case object.HASH_OBJ:
+		if i >= len(it.pairs) {
+			return nil, nil, false
+		}
+		return it.pairs[i].Key, it.pairs[i].Value, true
+	case object.STRING_OBJ:
+		if i >= len(it.runes) {
+			return nil, nil, false
+		}
+		return &object.Integer{Value: int64(i)}, &object.String{Value: string(it.runes[i])}, true
+	case object.RANGE_OBJ:
+		n := it.rng.Start + int64(i)
+		if n >= it.rng.End {
+			return nil, nil, false
+		}
+		return &object.Integer{Value: int64(i)}, &object.Integer{Value: n}, true
+	default:
+		if i >= len(it.elements) {
+			return nil, nil, false
+		}
+		return &object.Integer{Value: int64(i)}, it.elements[i], true
+	}
+}
@@ -0,0 +1,838 @@
+// Package vm runs programs compiled by the compiler package on a stack
+// machine. The locals of a call live on the stack rather than in an
+// environment, and the operations on values are those of the evaluator, so
+// a compiled program computes what the evaluator computes.
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/compiler"
+	"github.com/emo-lang/emo/evaluator"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/token"
+)
+
+// StackSize is the initial size of the stack, which grows with the calls.
+const StackSize = 2048
+
+type VM struct {
+	main      *module
+	modules   []*module
+	constants []object.Object
+	imports   []*compiler.Import
+	importing []string // the paths of the modules being imported, the innermost last
+
+	globals      []object.Object
+	globalNames  []string
+	constGlobals []bool // globals declared with const or define
+
+	stack []object.Object
+	sp    int // the next free slot, the top of the stack is stack[sp-1]
+
+	frames []*frame
+}
+
+// frame is a call of a closure. Its arguments and locals start at bp, right
+// after the closure.
+type frame struct {
+	cl       *Closure
+	ip       int // offset of the next instruction
+	op       int // offset of the instruction being run
+	bp       int
+	callPos  token.Position
+	handlers []handler
+
+	// the calls the call replaced by calls in tail position, outermost
+	// first, and their closures, whose return types the result is checked
+	// against in strict mode
+	tail   []object.StackFrame
+	checks []*Closure
+
+	// the instance created by new, which the call of init returns
+	instance *object.ClassInstance
+}
+
+// Get looks up name where fr runs, in its locals and then where its
+// closure was created.
+func (fr *frame) Get(name string) (object.Object, bool) {
+	locals := fr.cl.Fn.Locals
+	for i := len(locals) - 1; i >= 0; i-- {
+		if locals[i] != name {
+			continue
+		}
+		if val := fr.cl.vm.local(fr, i); val != nil {
+			return val, true
+		}
+	}
+
+	return fr.cl.Get(name)
+}
+
+// handler is the catch or finally code of a try expression being run. An
+// error raised before the handler is removed resets the stack to sp and
+// continues at addr with the error on the stack.
+type handler struct {
+	addr int
+	sp   int
+}
+
+func New(bytecode *compiler.Bytecode) *VM {
+	vm := &VM{
+		constants:    bytecode.Constants,
+		imports:      bytecode.Imports,
+		globals:      make([]object.Object, len(bytecode.Globals)),
+		globalNames:  bytecode.Globals,
+		constGlobals: make([]bool, len(bytecode.Globals)),
+		stack:        make([]object.Object, StackSize),
+	}
+
+	// the program has the global slots the modules do not have
+	owned := make([]bool, len(bytecode.Globals))
+	for _, code := range bytecode.Modules {
+		m := &module{fn: code.Main, name: code.Name, path: code.Path, globals: code.Globals, slots: map[string]int{}}
+		for _, slot := range code.Globals {
+			m.slots[bytecode.Globals[slot]] = slot
+			owned[slot] = true
+		}
+		vm.modules = append(vm.modules, m)
+	}
+
+	vm.main = &module{fn: bytecode.Main, slots: map[string]int{}}
+	for slot, name := range bytecode.Globals {
+		if !owned[slot] {
+			vm.main.slots[name] = slot
+		}
+	}
+
+	return vm
+}
+
+// Run runs the program and returns its value, or the error that ended it.
+// The instructions of programs read from files are checked when they are
+// decoded, but not the kinds of the values they leave on the stack, so a
+// program that does not come from the compiler may still go wrong: that
+// ends it with an error rather than the process.
+func (vm *VM) Run() (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = newError(object.RUNTIME_ERROR, "bad bytecode: %v", r)
+		}
+	}()
+
+	vm.push(&Closure{Fn: vm.main.fn, module: vm.main, vm: vm})
+	if err := vm.enter(0, nil, token.Position{}); err != nil {
+		return err
+	}
+
+	return vm.run(0)
+}
+
+// call runs cl with positional arguments while the machine is running the
+// builtin or the import that calls it. callPos is the location of the call,
+// if it has one.
+func (vm *VM) call(cl *Closure, args []object.Object, callPos token.Position) object.Object {
+	stop := len(vm.frames)
+	sp := vm.sp
+
+	vm.push(cl)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+
+	if err := vm.enter(len(args), nil, callPos); err != nil {
+		vm.sp = sp
+		return err
+	}
+
+	return vm.run(stop)
+}
+
+// run runs instructions until the frame above the first stop frames
+// returns, and returns the returned value, or the error that unwound it.
+func (vm *VM) run(stop int) object.Object {
+	fr := vm.frames[len(vm.frames)-1]
+
+	for {
+		ins := fr.cl.Fn.Instructions
+		ip := fr.ip
+		op := compiler.Opcode(ins[ip])
+		fr.op = ip
+
+		var err *object.Error
+
+		switch op {
+		case compiler.OpConstant:
+			fr.ip = ip + 3
+			vm.push(vm.constants[binary.BigEndian.Uint16(ins[ip+1:])])
+
+		case compiler.OpPop:
+			fr.ip = ip + 1
+			vm.sp--
+
+		case compiler.OpDup:
+			fr.ip = ip + 1
+			vm.push(vm.stack[vm.sp-1])
+
+		case compiler.OpTrue:
+			fr.ip = ip + 1
+			vm.push(evaluator.TRUE)
+
+		case compiler.OpFalse:
+			fr.ip = ip + 1
+			vm.push(evaluator.FALSE)
+
+		case compiler.OpNil:
+			fr.ip = ip + 1
+			vm.push(evaluator.NIL)
+
+		case compiler.OpAdd, compiler.OpSub, compiler.OpMul, compiler.OpDiv, compiler.OpEqual,
+			compiler.OpNotEqual, compiler.OpLess, compiler.OpGreater, compiler.OpRange:
+			fr.ip = ip + 1
+			right := vm.pop()
+			left := vm.pop()
+			err = vm.result(infix(op, left, right))
+
+		case compiler.OpMinus:
+			fr.ip = ip + 1
+			err = vm.result(evaluator.Prefix("-", vm.pop()))
+
+		case compiler.OpBang:
+			fr.ip = ip + 1
+			err = vm.result(evaluator.Prefix("!", vm.pop()))
+
+		case compiler.OpJump:
+			fr.ip = int(binary.BigEndian.Uint16(ins[ip+1:]))
+
+		case compiler.OpJumpNotTruthy:
+			fr.ip = ip + 3
+			if !evaluator.IsTruthy(vm.pop()) {
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+1:]))
+			}
+
+		case compiler.OpGetGlobal:
+			fr.ip = ip + 3
+			err = vm.getGlobal(int(binary.BigEndian.Uint16(ins[ip+1:])))
+
+		case compiler.OpSetGlobal:
+			fr.ip = ip + 3
+			vm.globals[binary.BigEndian.Uint16(ins[ip+1:])] = vm.pop()
+
+		case compiler.OpDefineGlobal:
+			fr.ip = ip + 4
+			err = vm.defineGlobal(int(binary.BigEndian.Uint16(ins[ip+1:])), ins[ip+3] == 1)
+
+		case compiler.OpCheckGlobal:
+			fr.ip = ip + 3
+			i := binary.BigEndian.Uint16(ins[ip+1:])
+			if vm.globals[i] == nil {
+				err = newError(object.NAME_ERROR, "cannot assign to undeclared variable: %s", vm.globalNames[i])
+			} else if vm.constGlobals[i] {
+				err = newError(object.TYPE_ERROR, "cannot assign to constant %s", vm.globalNames[i])
+			}
+
+		case compiler.OpCheckConst:
+			fr.ip = ip + 3
+			if i := binary.BigEndian.Uint16(ins[ip+1:]); vm.constGlobals[i] {
+				err = newError(object.NAME_ERROR, "cannot redeclare constant %s", vm.globalNames[i])
+			}
+
+		case compiler.OpGetLocal:
+			fr.ip = ip + 2
+			slot := int(ins[ip+1])
+			if val := vm.stack[fr.bp+slot]; val != nil {
+				vm.push(val)
+			} else {
+				err = newNotFoundError(fr.cl.Fn.Locals[slot])
+			}
+
+		case compiler.OpSetLocal:
+			fr.ip = ip + 2
+			vm.stack[fr.bp+int(ins[ip+1])] = vm.pop()
+
+		case compiler.OpGetCell:
+			fr.ip = ip + 2
+			slot := int(ins[ip+1])
+			if val := vm.stack[fr.bp+slot].(*cell).value; val != nil {
+				vm.push(val)
+			} else {
+				err = newNotFoundError(fr.cl.Fn.Locals[slot])
+			}
+
+		case compiler.OpSetCell:
+			fr.ip = ip + 2
+			vm.stack[fr.bp+int(ins[ip+1])].(*cell).value = vm.pop()
+
+		case compiler.OpLoadCell:
+			fr.ip = ip + 2
+			vm.push(vm.stack[fr.bp+int(ins[ip+1])])
+
+		case compiler.OpGetFree:
+			fr.ip = ip + 2
+			i := int(ins[ip+1])
+			if val := fr.cl.Free[i].value; val != nil {
+				vm.push(val)
+			} else {
+				// declared later by the enclosing function, which has
+				// not got there yet
+				err = vm.getGlobalNamed(fr.cl.module, fr.cl.Fn.Free[i])
+			}
+
+		case compiler.OpSetFree:
+			fr.ip = ip + 2
+			fr.cl.Free[ins[ip+1]].value = vm.pop()
+
+		case compiler.OpLoadFree:
+			fr.ip = ip + 2
+			vm.push(fr.cl.Free[ins[ip+1]])
+
+		case compiler.OpFreeze:
+			fr.ip = ip + 1
+			vm.stack[vm.sp-1] = object.Freeze(vm.stack[vm.sp-1])
+
+		case compiler.OpArray, compiler.OpTuple:
+			fr.ip = ip + 3
+			n := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			elements := make([]object.Object, n)
+			copy(elements, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+
+			if op == compiler.OpArray {
+				vm.push(&object.Array{Elements: elements})
+			} else {
+				vm.push(&object.Tuple{Elements: elements})
+			}
+
+		case compiler.OpHash:
+			fr.ip = ip + 3
+			n := int(binary.BigEndian.Uint16(ins[ip+1:]))
+			pairs := make(map[object.HashKey]object.HashPair, n)
+			for i := vm.sp - 2*n; i < vm.sp; i += 2 {
+				key := vm.stack[i].(*object.String)
+				pairs[key.HashKey()] = object.HashPair{Key: key, Value: vm.stack[i+1]}
+			}
+			vm.sp -= 2 * n
+			vm.push(&object.Hash{Pairs: pairs})
+
+		case compiler.OpIndex:
+			fr.ip = ip + 1
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.result(evaluator.Index(left, index))
+
+		case compiler.OpSetIndex:
+			fr.ip = ip + 2
+			val := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.result(evaluator.AssignIndex(left, index, compiler.AssignOperator(int(ins[ip+1])), val))
+
+		case compiler.OpGetMember:
+			fr.ip = ip + 3
+			name := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*object.String)
+			err = vm.result(evaluator.Member(vm.pop(), name.Value, fr))
+
+		case compiler.OpSetMember:
+			fr.ip = ip + 4
+			name := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*object.String)
+			val := vm.pop()
+			receiver := vm.pop()
+			err = vm.result(evaluator.AssignMember(receiver, name.Value, compiler.AssignOperator(int(ins[ip+3])), val, fr))
+
+		case compiler.OpDestructure:
+			fr.ip = ip + 4
+			kind := compiler.DestructuringKinds[ins[ip+1]]
+			names := vm.constants[binary.BigEndian.Uint16(ins[ip+2:])].(*object.Array)
+			err = vm.destructure(kind, names, vm.pop())
+
+		case compiler.OpClosure:
+			fr.ip = ip + 4
+			fn := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*compiler.Function)
+			n := int(ins[ip+3])
+
+			free := make([]*cell, n)
+			for i := 0; i < n; i++ {
+				free[i] = vm.stack[vm.sp-n+i].(*cell)
+			}
+			vm.sp -= n
+			vm.push(&Closure{Fn: fn, Free: free, module: fr.cl.module, vm: vm})
+
+		case compiler.OpCall:
+			fr.ip = ip + 4
+			argc := int(ins[ip+1])
+
+			var names *object.Array
+			if i := binary.BigEndian.Uint16(ins[ip+2:]); i != compiler.NoNames {
+				names = vm.constants[i].(*object.Array)
+			}
+
+			err = vm.callValue(argc, names, fr.cl.Fn.Pos(ip))
+			fr = vm.frames[len(vm.frames)-1]
+
+		case compiler.OpTailCall:
+			fr.ip = ip + 4
+			argc := int(ins[ip+1])
+
+			var names *object.Array
+			if i := binary.BigEndian.Uint16(ins[ip+2:]); i != compiler.NoNames {
+				names = vm.constants[i].(*object.Array)
+			}
+
+			// other functions run in the evaluator and the OpReturn after
+			// the call returns their result
+			if _, ok := vm.stack[vm.sp-1-argc].(*Closure); ok {
+				err = vm.tailCall(fr, argc, names, fr.cl.Fn.Pos(ip))
+			} else {
+				err = vm.callValue(argc, names, fr.cl.Fn.Pos(ip))
+			}
+			fr = vm.frames[len(vm.frames)-1]
+
+		case compiler.OpReturn:
+			result := vm.pop()
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.sp = fr.bp - 1
+
+			if evaluator.Strict {
+				err = evaluator.CheckResult(fr.cl.Fn.DisplayName(), fr.cl.Fn.ReturnTypes, result, fr.cl)
+				for i := len(fr.checks) - 1; i >= 0 && err == nil; i-- {
+					check := fr.checks[i]
+					err = evaluator.CheckResult(check.Fn.DisplayName(), check.Fn.ReturnTypes, result, check)
+				}
+			}
+
+			if fr.instance != nil {
+				result = fr.instance
+			}
+
+			if len(vm.frames) == stop {
+				if err != nil {
+					return err
+				}
+				return result
+			}
+
+			if err == nil {
+				vm.push(result)
+			}
+			fr = vm.frames[len(vm.frames)-1]
+
+		case compiler.OpJumpIfSet:
+			fr.ip = ip + 4
+			if vm.local(fr, int(ins[ip+1])) != nil {
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+2:]))
+			}
+
+		case compiler.OpSetParam:
+			fr.ip = ip + 2
+			slot := int(ins[ip+1])
+			val := vm.pop()
+
+			if evaluator.Strict {
+				err = evaluator.CheckArgument(fr.cl.Fn.DisplayName(), fr.cl.Fn.Parameters[slot], val, fr.cl)
+			}
+			if err == nil {
+				vm.setLocal(fr, slot, val)
+			}
+
+		case compiler.OpIter:
+			fr.ip = ip + 1
+			iterable := vm.pop()
+			if it, ok := newIterator(iterable); ok {
+				vm.push(it)
+			} else {
+				err = newError(object.TYPE_ERROR, "cannot iterate over %s", iterable.Type())
+			}
+
+		case compiler.OpIterNext:
+			fr.ip = ip + 4
+			it := vm.stack[vm.sp-1].(*iterator)
+
+			key, value, ok := it.step()
+			switch {
+			case !ok:
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+2:]))
+			case ins[ip+1] == 2:
+				vm.push(key)
+				vm.push(value)
+			case it.kind == object.HASH_OBJ:
+				vm.push(key)
+			default:
+				vm.push(value)
+			}
+
+		case compiler.OpTry:
+			fr.ip = ip + 3
+			fr.handlers = append(fr.handlers, handler{addr: int(binary.BigEndian.Uint16(ins[ip+1:])), sp: vm.sp})
+
+		case compiler.OpEndTry:
+			fr.ip = ip + 1
+			fr.handlers = fr.handlers[:len(fr.handlers)-1]
+
+		case compiler.OpException:
+			fr.ip = ip + 1
+			vm.stack[vm.sp-1] = &object.Exception{Error: vm.stack[vm.sp-1].(*object.Error)}
+
+		case compiler.OpThrow:
+			fr.ip = ip + 1
+			err = evaluator.Throw(vm.pop())
+
+		case compiler.OpRethrow:
+			fr.ip = ip + 1
+			err = vm.pop().(*object.Error)
+
+		case compiler.OpRaise:
+			fr.ip = ip + 3
+			template := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*object.Error)
+			err = &object.Error{Kind: template.Kind, Message: template.Message}
+
+		case compiler.OpImport:
+			fr.ip = ip + 3
+			err = vm.importModule(vm.imports[binary.BigEndian.Uint16(ins[ip+1:])])
+
+		case compiler.OpClass:
+			fr.ip = ip + 3
+			err = vm.newClass(vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*compiler.Class))
+
+		case compiler.OpImplement:
+			fr.ip = ip + 4
+			t := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*compiler.Class)
+			iface := vm.pop()
+			klass := vm.stack[vm.sp-1].(*object.Class)
+			err = evaluator.Implement(klass, iface, t.Node.Implements[ins[ip+3]])
+
+		case compiler.OpInterface:
+			fr.ip = ip + 3
+			t := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*compiler.Interface)
+			vm.push(&object.Interface{Name: t.Node.Name, Methods: t.Node.Methods})
+
+		case compiler.OpEnum:
+			fr.ip = ip + 4
+			t := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*compiler.Enum)
+			n := int(ins[ip+3])
+
+			scope := &enumScope{vm: vm, module: fr.cl.module, names: t.Free, cells: make([]*cell, n)}
+			for i := 0; i < n; i++ {
+				scope.cells[i] = vm.stack[vm.sp-n+i].(*cell)
+			}
+			vm.sp -= n
+			vm.push(evaluator.NewEnum(t.Node, scope))
+
+		case compiler.OpNew:
+			fr.ip = ip + 6
+			argc := int(ins[ip+1])
+
+			var names *object.Array
+			if i := binary.BigEndian.Uint16(ins[ip+2:]); i != compiler.NoNames {
+				names = vm.constants[i].(*object.Array)
+			}
+			name := vm.constants[binary.BigEndian.Uint16(ins[ip+4:])].(*object.String)
+
+			err = vm.newInstance(argc, names, name.Value, fr.cl.Fn.Pos(ip))
+			fr = vm.frames[len(vm.frames)-1]
+
+		case compiler.OpSame:
+			fr.ip = ip + 1
+			right := vm.pop()
+			left := vm.pop()
+			vm.push(boolean(evaluator.SameValue(left, right)))
+
+		case compiler.OpMatchArray:
+			fr.ip = ip + 5
+			n, rest := int(ins[ip+1]), ins[ip+2] == 1
+
+			array, ok := vm.pop().(*object.Array)
+			if !ok || len(array.Elements) < n || !rest && len(array.Elements) != n {
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+3:]))
+				break
+			}
+
+			if rest {
+				elements := make([]object.Object, len(array.Elements)-n)
+				copy(elements, array.Elements[n:])
+				vm.push(&object.Array{Elements: elements})
+			}
+			for i := n - 1; i >= 0; i-- {
+				vm.push(array.Elements[i])
+			}
+
+		case compiler.OpMatchHash:
+			fr.ip = ip + 3
+			if _, ok := vm.stack[vm.sp-1].(*object.Hash); !ok {
+				vm.sp--
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+1:]))
+			}
+
+		case compiler.OpMatchKey:
+			fr.ip = ip + 5
+			key := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*object.String)
+			hash := vm.stack[vm.sp-1].(*object.Hash)
+
+			if pair, ok := hash.Pairs[key.HashKey()]; ok {
+				vm.push(pair.Value)
+			} else {
+				vm.sp--
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+3:]))
+			}
+
+		case compiler.OpMatchVariant:
+			fr.ip = ip + 6
+			names := vm.constants[binary.BigEndian.Uint16(ins[ip+1:])].(*object.Array)
+			args := int(ins[ip+3])
+			if args == compiler.NoArguments {
+				args = -1
+			}
+
+			enum := vm.pop()
+			val := vm.pop()
+
+			variant, verr := evaluator.PatternVariant(enum, names.Elements[0].(*object.String).Value,
+				names.Elements[1].(*object.String).Value, args)
+			if verr != nil {
+				err = verr
+				break
+			}
+
+			value, ok := val.(*object.EnumValue)
+			if !ok || value.Variant != variant {
+				fr.ip = int(binary.BigEndian.Uint16(ins[ip+4:]))
+				break
+			}
+
+			for i := args - 1; i >= 0; i-- {
+				vm.push(value.Values[i])
+			}
+
+		case compiler.OpNoMatch:
+			fr.ip = ip + 1
+			err = evaluator.MatchError(vm.pop())
+
+		default:
+			panic(fmt.Sprintf("vm: unknown opcode %d", op))
+		}
+
+		if err != nil {
+			if !vm.raise(err, stop) {
+				return err
+			}
+			fr = vm.frames[len(vm.frames)-1]
+		}
+	}
+}
+
+// raise hands err to the innermost handler of the frames above the first
+// stop ones, unwinding the frames without handlers, and reports false
+// when there is none. Errors are located at the instruction raising them.
+func (vm *VM) raise(err *object.Error, stop int) bool {
+	fr := vm.frames[len(vm.frames)-1]
+	if !err.Pos.IsValid() {
+		err.Pos = fr.cl.Fn.Pos(fr.op)
+	}
+
+	for len(vm.frames) > stop {
+		fr := vm.frames[len(vm.frames)-1]
+
+		if n := len(fr.handlers); n > 0 {
+			h := fr.handlers[n-1]
+			fr.handlers = fr.handlers[:n-1]
+
+			vm.sp = h.sp
+			vm.push(err)
+			fr.ip = h.addr
+
+			return true
+		}
+
+		vm.frames = vm.frames[:len(vm.frames)-1]
+		vm.sp = fr.bp - 1
+
+		if len(vm.frames) > 0 {
+			err.Stack = append(err.Stack, object.StackFrame{Function: fr.cl.Fn.DisplayName(), Call: fr.callPos})
+			for i := len(fr.tail) - 1; i >= 0; i-- {
+				err.Stack = append(err.Stack, fr.tail[i])
+			}
+		}
+	}
+
+	return false
+}
+
+// result pushes the result of an operation of the evaluator, or returns
+// it if it is an error.
+func (vm *VM) result(obj object.Object) *object.Error {
+	if err, ok := obj.(*object.Error); ok {
+		return err
+	}
+
+	if obj == nil {
+		obj = evaluator.NIL
+	}
+	vm.push(obj)
+
+	return nil
+}
+
+var operators = map[compiler.Opcode]string{
+	compiler.OpAdd:      "+",
+	compiler.OpSub:      "-",
+	compiler.OpMul:      "*",
+	compiler.OpDiv:      "/",
+	compiler.OpEqual:    "==",
+	compiler.OpNotEqual: "!=",
+	compiler.OpLess:     "<",
+	compiler.OpGreater:  ">",
+	compiler.OpRange:    "..",
+}
+
+// infix applies a binary operator, computing arithmetic on integers
+// directly.
+func infix(op compiler.Opcode, left, right object.Object) object.Object {
+	l, ok := left.(*object.Integer)
+	if !ok {
+		return evaluator.Infix(operators[op], left, right)
+	}
+	r, ok := right.(*object.Integer)
+	if !ok {
+		return evaluator.Infix(operators[op], left, right)
+	}
+
+	switch op {
+	case compiler.OpAdd:
+		return &object.Integer{Value: l.Value + r.Value}
+	case compiler.OpSub:
+		return &object.Integer{Value: l.Value - r.Value}
+	case compiler.OpMul:
+		return &object.Integer{Value: l.Value * r.Value}
+	case compiler.OpEqual:
+		return boolean(l.Value == r.Value)
+	case compiler.OpNotEqual:
+		return boolean(l.Value != r.Value)
+	case compiler.OpLess:
+		return boolean(l.Value < r.Value)
+	case compiler.OpGreater:
+		return boolean(l.Value > r.Value)
+	default:
+		return evaluator.Infix(operators[op], left, right)
+	}
+}
+
+func boolean(b bool) *object.Boolean {
+	if b {
+		return evaluator.TRUE
+	}
+	return evaluator.FALSE
+}
+
+func (vm *VM) getGlobal(i int) *object.Error {
+	if val := vm.globals[i]; val != nil {
+		vm.push(val)
+		return nil
+	}
+
+	if builtin, ok := evaluator.LookupBuiltin(vm.globalNames[i]); ok {
+		vm.push(builtin)
+		return nil
+	}
+
+	return newNotFoundError(vm.globalNames[i])
+}
+
+// lookup returns the value of name in the variables names of a closure,
+// captured in cells, or else of the global name of the module m.
+func (vm *VM) lookup(m *module, names []string, cells []*cell, name string) (object.Object, bool) {
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] == name && cells[i].value != nil {
+			return cells[i].value, true
+		}
+	}
+
+	if i, ok := m.slots[name]; ok && vm.globals[i] != nil {
+		return vm.globals[i], true
+	}
+
+	return nil, false
+}
+
+// getGlobalNamed pushes the global name of the module m, like getGlobal.
+func (vm *VM) getGlobalNamed(m *module, name string) *object.Error {
+	if i, ok := m.slots[name]; ok {
+		return vm.getGlobal(i)
+	}
+
+	if builtin, ok := evaluator.LookupBuiltin(name); ok {
+		vm.push(builtin)
+		return nil
+	}
+
+	return newNotFoundError(name)
+}
+
+func (vm *VM) defineGlobal(i int, constant bool) *object.Error {
+	if vm.constGlobals[i] {
+		return newError(object.NAME_ERROR, "cannot redeclare constant %s", vm.globalNames[i])
+	}
+
+	val := vm.pop()
+	if constant {
+		val = object.Freeze(val)
+		vm.constGlobals[i] = true
+	}
+	vm.globals[i] = val
+
+	return nil
+}
+
+// local returns the value of a local of fr, nil if it is not set.
+func (vm *VM) local(fr *frame, slot int) object.Object {
+	val := vm.stack[fr.bp+slot]
+	if c, ok := val.(*cell); ok {
+		return c.value
+	}
+	return val
+}
+
+func (vm *VM) setLocal(fr *frame, slot int, val object.Object) {
+	if c, ok := vm.stack[fr.bp+slot].(*cell); ok {
+		c.value = val
+		return
+	}
+	vm.stack[fr.bp+slot] = val
+}
+
+// destructure pushes the parts of val named by names, the last first.
+func (vm *VM) destructure(kind token.TokenType, names *object.Array, val object.Object) *object.Error {
+	idents := make([]*ast.Identifier, len(names.Elements))
+	for i, name := range names.Elements {
+		idents[i] = &ast.Identifier{Value: name.(*object.String).Value}
+	}
+
+	values, err := evaluator.Destructure(kind, idents, val)
+	if err != nil {
+		return err
+	}
+
+	for i := len(values) - 1; i >= 0; i-- {
+		vm.push(values[i])
+	}
+
+	return nil
+}
+
+func (vm *VM) push(obj object.Object) {
+	vm.stack[vm.sp] = obj
+	vm.sp++
+}
+
+func (vm *VM) pop() object.Object {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+func newError(kind string, format string, a ...any) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+func newNotFoundError(name string) *object.Error {
+	return newError(object.NAME_ERROR, "identifier not found: %s", name)
+}
@@ -0,0 +1,734 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/emo-lang/emo/ast"
+	"github.com/emo-lang/emo/compiler"
+	"github.com/emo-lang/emo/evaluator"
+	"github.com/emo-lang/emo/lexer"
+	"github.com/emo-lang/emo/object"
+	"github.com/emo-lang/emo/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors in %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+func testRun(t *testing.T, input string) object.Object {
+	bytecode, err := compiler.New().Compile(parse(t, input))
+	if err != nil {
+		t.Fatalf("compiler error in %q: %s", input, err)
+	}
+
+	return New(bytecode).Run()
+}
+
+// describe renders a result for comparison, errors by kind and message.
+// The evaluator returns no object for some statements where the machine
+// returns nil.
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+	case nil:
+		return "nil"
+	case *object.Error:
+		return obj.Kind + ": " + obj.Message
+	default:
+		return obj.Inspect()
+	}
+}
+
+// testSameAsEvaluator runs each program on the machine and in the
+// evaluator, which must agree on the result.
+func testSameAsEvaluator(t *testing.T, inputs []string) {
+	t.Helper()
+
+	for _, input := range inputs {
+		expected := describe(evaluator.Eval(parse(t, input), object.NewEnvironment()))
+
+		result := testRun(t, input)
+		if result == nil {
+			t.Errorf("no object returned for %q", input)
+			continue
+		}
+
+		if got := describe(result); got != expected {
+			t.Errorf("wrong result for %q. evaluator=%q, vm=%q", input, expected, got)
+		}
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		"(5 + 10 * 2 + 15 / 3) * 2 + -10",
+		"7 / 2",
+		"1 / 0",
+		"\"a\" + \"b\"",
+		"1 < 2 == true",
+		"!true != !!false",
+		"5 + true",
+		"-\"a\"",
+		"[1, 2 * 2, 3][1]",
+		"{a: 1, b: [2]}[\"b\"][0]",
+		"len(0..5)",
+		"if 1 > 2 { 10 } else { 20 }",
+		"if false { 10 }",
+		"foo",
+		"len",
+	})
+}
+
+func TestVariablesAndAssignments(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		"var x = 1\nx = 2\nx",
+		"var x = 1\nx += 4\nx",
+		"var x = 10\nx -= 4\nx *= 2\nx /= 3\nx",
+		"var x = 1\nfunc f() { x = 5 }\nf()\nx",
+		"var x = 1\nfunc f() { var x = 2\nx = 3 }\nf()\nx",
+		"var a = [1, 2, 3]\nvar b = a\nb[0] += 10\na[0]",
+		"var h = {a: 1}\nh[\"a\"] += 1\nh[\"b\"] = 5\nh[\"a\"] + h[\"b\"]",
+		"y = 1",
+		"func f() { y = 1 }\nf()",
+		"var a = [1]\na[3] = 1",
+		"func f() { return x }\nvar x = 3\nf()",
+		"func f() { var a = 1\nif true { var a = 2 }\nreturn a }\nf()",
+	})
+}
+
+func TestConstants(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		"const MAX = 3\nMAX * 2",
+		"define(MAX, 3)\nfunc f() { return MAX }\nf()",
+		"const MAX = 3\nfunc f(MAX: Int) { return MAX }\nf(4)",
+		"func g() { const N = 1\nreturn func(N: Int) { return N } }\ng()",
+		"func f(MAX: Int) { return MAX }\nconst MAX = 3\nf(4)",
+		"var h = {a: 1}\nconst H = h\nh[\"a\"] = 2\nh[\"a\"] + H[\"a\"]",
+		"var inner = [1]\nconst W = [inner]\ninner[0] = 2\ninner[0] + W[0][0]",
+		"const MAX = 3\nMAX = 4",
+		"define(MAX, 3)\nMAX += 1",
+		"define(MAX, 3)\nvar MAX = 99",
+		"const MAX = 3\nfunc f() { var MAX = 4 }\nf()",
+		"const X = 3\nfor X in 0..2 {}",
+		"const X = 3\nvar [X] = [1]",
+		"const XS = [1, [2]]\nXS[1][0] = 5",
+		"func f() { const N = 1\nN = 2 }\nf()",
+		"func f() { const N = [1]\nN[0] = 2 }\nf()",
+		"func f() { const N = 1\nvar N = 2 }\nf()",
+		"const P = 1\nclass P {}\nP",
+		"define(G, 2)\ninterface G { func f() }\nG",
+		"const E = 3\nenum E { A }\nE",
+		"func f() { const E = 3\nenum E { A } }\nf()",
+		"func f() { const P = 3\nfunc g() { class P {} }\ng() }\nf()",
+		"class P {}\nclass P {}\nP",
+	})
+}
+
+func TestLoops(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		"var i = 0\nwhile i < 5 { var i = i + 1 }\ni",
+		"var i = 0\nwhile true { var i = i + 1\nif i == 3 { break } }\ni",
+		"var s = 0\nfor i, x in [10, 20] { var s = s + i + x }\ns",
+		"var s = 0\nfor n in 1..5 { if n == 2 { continue }\nvar s = s + n }\ns",
+		"var s = \"\"\nfor k, v in {b: 1, a: 2} { var s = s + k }\ns",
+		"var s = \"\"\nfor i, c in \"abc\" { var s = c + s }\ns",
+		"func f() { for x in 0..10 { if x == 4 { return x } } }\nf()",
+		"var n = 0\nfor i in 0..3 { for j in 0..3 { if j == 1 { break }\nvar n = n + 1 } }\nn",
+		"for x in 1 {}",
+		"func f() { var s = 0\nfor x in [1, 2, 3] { s += x }\nreturn s }\nf()",
+	})
+}
+
+func TestFunctions(t *testing.T) {
+	defs := `func sub(a: Int, b: Int) { return a - b }
+func inc(n: Int, by: Int = 1) { return n + by }
+func span(from: Int, to: Int = from + 10) { return to - from }
+func count(first: Int, ...rest: Array) { return first + len(rest) }
+func fib(n: Int) { if n < 2 { return n }
+return fib(n - 1) + fib(n - 2) }
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + "fib(15)",
+		defs + "sub(b: 2, a: 5)",
+		defs + "sub(5, b: 2)",
+		defs + "inc(1) + inc(1, 5) + inc(by: 3, n: 1)",
+		defs + "span(5)",
+		defs + "count(10, 1, 2, 3)",
+		defs + "sub(1)",
+		defs + "sub(1, 2, 3)",
+		defs + "inc()",
+		defs + "count()",
+		defs + "sub(a: 1)",
+		defs + "sub(1, c: 2)",
+		defs + "sub(1, a: 2)",
+		defs + "sub(1, b: 2, b: 3)",
+		defs + "len(value: \"x\")",
+		defs + "sub",
+		"func(x: Int) { return x * 2 }(4)",
+		"func f() {}\nf()",
+		"func f() { 5 }\nf()",
+		"1(2)",
+	})
+}
+
+func TestClosures(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		`func counter() {
+  var n = 0
+  return func() { n += 1
+return n }
+}
+var c = counter()
+c()
+c()
+var d = counter()
+d()
+c()`,
+		`func adder(x: Int) { return func(y: Int) { return func(z: Int) { return x + y + z } } }
+adder(1)(2)(3)`,
+		`func f() {
+  var fs = []
+  for i in 0..3 { var fs = push(fs, func() { return i }) }
+  return fs[0]() + fs[2]()
+}
+f()`,
+		`func outer() {
+  func even(n: Int) { if n == 0 { return true }
+return odd(n - 1) }
+  func odd(n: Int) { if n == 0 { return false }
+return even(n - 1) }
+  return even(10)
+}
+outer()`,
+		`var g = 1
+func f() {
+  func h() { return g }
+  var a = h()
+  var g = 2
+  return a * 10 + h()
+}
+f()`,
+	})
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		`try { 1 } catch e { 2 }`,
+		`try { throw "boom" } catch e { e.message }`,
+		`try { len(1) } catch e { e.kind }`,
+		`try { foo } catch e { e.kind }`,
+		`try { 1 / 0 } catch e { e.message }`,
+		`try { throw {kind: "ParseError", message: "bad"} } catch e { e.kind + ": " + e.message }`,
+		`try { throw 42 } catch e { e.value }`,
+		`try { try { throw "a" } catch e { throw e } } catch e { e.message }`,
+		"var x = 0\ntry { x } finally { 5 }",
+		"func f() { try { return 1 } finally { 5 } }\nf()",
+		"func f() { try { return 1 } finally { return 2 } }\nf()",
+		`try { throw "a" } finally { 1 }`,
+		"var n = 0\nfunc f() { try { throw \"a\" } finally { n = 7 } }\ntry { f() } catch e { n }",
+		"var n = 0\nfor i in 0..5 { try { if i == 2 { break } } finally { n += 1 } }\nn",
+		"var n = 0\nfor i in 0..5 { try { continue } finally { n += i } }\nn",
+		"func fail() { throw \"boom\" }\ntry { fail() } catch e { e.stack }",
+		`func f() { return [1, 2, try { throw "x" } catch e { 3 }] }
+f()`,
+	})
+}
+
+func TestTuplesAndDestructuring(t *testing.T) {
+	defs := `func divmod(a: Int, b: Int) -> (Int, Int) {
+  return a / b, a - (a / b) * b
+}
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + "var (q, r) = divmod(17, 5)\nq * 10 + r",
+		defs + "divmod(7, 2)",
+		defs + "func f() { var [a, b] = [1, 2, 3]\nreturn a + b }\nf()",
+		defs + "var {name, age} = {name: \"ann\", age: 30}\nage",
+		defs + "var (a, b, c) = divmod(1, 1)",
+		defs + "var [a, b] = [1]",
+		defs + "var {name, age} = {name: \"ann\"}",
+	})
+}
+
+func TestStdlibCallbacks(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		"import collections\ncollections.map([1, 2, 3], func(x: Int) { return x * 2 })",
+		"import collections\ncollections.reduce([1, 2, 3], func(a: Int, b: Int) { return a + b }, 0)",
+		"import collections\ncollections.map([1], func(x: Int) { return x + \"a\" })",
+		"import strings\nstrings.join(strings.split(\"a,b,c\", \",\"), \"-\")",
+		"import strings as s\ns.upper(\"hi\")",
+	})
+}
+
+func TestStrictMode(t *testing.T) {
+	evaluator.Strict = true
+	defer func() { evaluator.Strict = false }()
+
+	defs := `func add(a: Int, b: Int) -> Int { return a + b }
+func name(n: Int) -> String { return n }
+func nothing() -> Int {}
+func pair() -> (Int, String) { return 1, 2 }
+func opt(n: Int = "x") { return n }
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + `add(1, 2)`,
+		defs + `add(1, "2")`,
+		defs + `name(1)`,
+		defs + `nothing()`,
+		defs + `pair()`,
+		defs + `opt()`,
+		defs + `func(x: String) { return x }(1)`,
+	})
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+	}{
+		{"5 + true", "1:1"},
+		{"var a = 1\nvar b = a + foo", "2:13"},
+		{"if (1 > 0) {\n  -true\n}", "2:3"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testRun(t, tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q", tt.input)
+			continue
+		}
+
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position. expected=%q, got=%q",
+				tt.expectedPos, errObj.Pos.String())
+		}
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	input := `func inner() {
+  return missing
+}
+
+func outer() {
+  return inner()
+}
+
+outer()`
+
+	errObj, ok := testRun(t, input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+
+	expected := `  at inner (2:10)
+  at outer (6:10)
+  at <main> (9:1)
+`
+	if errObj.StackTrace() != expected {
+		t.Errorf("wrong stack trace. expected=%q, got=%q", expected, errObj.StackTrace())
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	testSameAsEvaluator(t, []string{
+		"func count(n: Int, acc: Int) { if n == 0 { return acc }\nreturn count(n - 1, acc + 1) }\ncount(100000, 0)",
+		"func even(n: Int) { if n == 0 { return true }\nreturn odd(n - 1) }\nfunc odd(n: Int) { if n == 0 { return false }\nreturn even(n - 1) }\neven(100001)",
+		"func f() { return len([1, 2]) }\nf()",
+		"func g() { throw \"boom\" }\nfunc f() { try { return g() } catch e { return e.message } }\nf()",
+		"func down(n: Int) { if n == 0 { return 0 }\nreturn 1 + down(n - 1) }\ndown(20000)",
+		"func down(n: Int) { if n == 0 { return 0 }\nreturn 1 + down(n - 1) }\ntry { down(20000) } catch e { e.kind }",
+		"func loop(n: Int) { if n == 0 { return missing }\nreturn loop(n - 1) }\ntry { loop(500) } catch e { len(e.stack) }",
+	})
+}
+
+// The programs below are those of the tests of classes, enums and match in
+// the evaluator.
+
+func TestClasses(t *testing.T) {
+	person := `class Person {
+  public var name: String
+  var age: Int
+
+  func older?(other: Person) -> Bool {
+    return self.age > other.age
+  }
+
+  private func secret() {
+    return self.age
+  }
+
+  func reveal() {
+    return self.secret()
+  }
+}
+var a = new(Person, {name: "A", age: 40})
+var b = new(Person, {name: "B", age: 30})
+`
+
+	account := `class Account {
+  public var owner: String
+  public var balance: Int
+  public var active: Bool
+  public var tags: Array
+  public var limit: Int = 100 * 2
+  public var history: Array = []
+}
+`
+
+	testSameAsEvaluator(t, []string{
+		person + "a.name",
+		person + "a.older?(b)",
+		person + "a.reveal()",
+		person + "a.age",
+		person + "a.age = 1",
+		person + "a.secret()",
+		person + "var f = func() { return a.age }\nf()",
+		account + "new(Account).owner",
+		account + "new(Account).balance",
+		account + "new(Account).active",
+		account + "new(Account).tags",
+		account + "new(Account).limit",
+		account + "new(Account, {limit: 5}).limit",
+		account + "var a = new(Account)\nvar b = new(Account)\na.history = push(a.history, 1)\nlen(b.history)",
+		account + "new(Account, {owner: \"x\", age: 3})",
+		account + "new(Account).missing",
+		account + "var x = 1\nnew(x)",
+		"class C { public var x: Int = missing }\nnew(C)",
+		"func make(n: Int) {\n  class Box {\n    public var n: Int = n\n    func twice() { return self.n * 2 }\n  }\n  return new(Box)\n}\nmake(21).twice()",
+	})
+}
+
+func TestClassInit(t *testing.T) {
+	class := `class Person {
+  public var name: String
+  public var age: Int
+  public var adult: Bool
+
+  func init(name: String, age: Int) {
+    if age < 0 {
+      throw "age must not be negative"
+    }
+
+    self.name = name
+    self.age = age
+    self.adult = age > 17
+  }
+}
+`
+
+	testSameAsEvaluator(t, []string{
+		class + `new(Person, "Ann", 30).age`,
+		class + `new(Person, "Ann", 30).adult`,
+		class + `new(Person, age: 12, name: "Bob").adult`,
+		class + `new(Person, "Bob", age: 12).name`,
+		class + `new(Person, "Bob", -1)`,
+		class + `new(Person, "Bob", old: 1)`,
+		class + `new(Person, "Bob", name: "x")`,
+		class + `new(Person, age: 3)`,
+		class + "class P { public var x: Int }\nnew(P, x: 5).x",
+		class + "class P { public var x: Int }\nnew(P, 5)",
+		class + "class Q { func init() { return 1 } }\nnew(Q)",
+		class + "map([1, 2], func(n: Int) { return new(Person, \"x\", n).age })",
+	})
+}
+
+func TestClassInheritance(t *testing.T) {
+	classes := `class User {
+  public var name: String
+  var role: String = "user"
+
+  func init(name: String) {
+    self.name = name
+  }
+
+  func describe() -> String {
+    return self.name + " is a " + self.role
+  }
+}
+
+class Admin extends User {
+  public var level: Int
+
+  func init(name: String, level: Int) {
+    super.init(name)
+    self.level = level
+    self.role = "admin"
+  }
+
+  func describe() -> String {
+    return super.describe() + "!"
+  }
+}
+
+class Guest extends User {}
+`
+
+	testSameAsEvaluator(t, []string{
+		classes + `new(Admin, "Ann", 2).level`,
+		classes + `new(Admin, "Ann", 2).name`,
+		classes + `new(Admin, "Ann", 2).describe()`,
+		classes + `new(Guest, "Bob").describe()`,
+		classes + `is_a?(new(Admin, "Ann", 2), User)`,
+		classes + `is_a?(new(Guest, "Bob"), Admin)`,
+		classes + `is_a?(1, User)`,
+		classes + `new(Admin, "Ann", 2).role`,
+		classes + "var x = 1\nclass Bad extends x {}",
+	})
+}
+
+func TestInterfaces(t *testing.T) {
+	interfaces := `interface Greeter {
+  func greet(name: String) -> String
+}
+
+class Person implements Greeter {
+  func greet(name: String) -> String {
+    return "hello " + name
+  }
+}
+
+class Student extends Person {}
+`
+
+	testSameAsEvaluator(t, []string{
+		interfaces + `new(Person).greet("bob")`,
+		interfaces + `is_a?(new(Person), Greeter)`,
+		interfaces + `is_a?(new(Student), Greeter)`,
+		interfaces + `class Other {}` + "\n" + `is_a?(new(Other), Greeter)`,
+		interfaces + `class Heir extends Person implements Greeter {}` + "\n" + `new(Heir).greet("ann")`,
+		interfaces + `class Rock implements Greeter {}`,
+		interfaces + `class Rock implements Greeter {
+  private func greet(name: String) -> String { return name }
+}`,
+		interfaces + `class Rock implements Greeter {
+  func greet() -> String { return "" }
+}`,
+		interfaces + `class Rock implements Greeter {
+  func greet(name: String) { return name }
+}`,
+		interfaces + `class Rock implements Greeter {
+  func greet(name: String) -> (String, Int) { return name }
+}`,
+		interfaces + `class Rock implements Person {}`,
+	})
+}
+
+func TestStrictClasses(t *testing.T) {
+	evaluator.Strict = true
+	defer func() { evaluator.Strict = false }()
+
+	defs := `func apply(f: Func, x: Any) { return f(x) }
+class User {}
+class Admin extends User {}
+interface Named {}
+func greet(u: User) -> Bool { return true }
+func named(n: Named) -> Bool { return true }
+func odd(w: Widget) {}
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + `apply(len, "abc")`,
+		defs + `apply(1, 2)`,
+		defs + `greet(new(Admin))`,
+		defs + `greet(new(User))`,
+		defs + `greet([1])`,
+		defs + `named(new(User))`,
+		defs + `odd(1)`,
+		"func f() {\n  class Local {}\n  func g(l: Local) { return 1 }\n  return g(new(Local))\n}\nf()",
+		"class Point {\n  public var x: Int\n  func init(x: Int) { self.x = x }\n  func moved(by: Int) -> Point { return new(Point, self.x + by) }\n}\nnew(Point, 1).moved(2).x",
+		"class Point {\n  func init(x: Int) {}\n}\nnew(Point, \"1\")",
+	})
+}
+
+func TestEnumsAndMatch(t *testing.T) {
+	defs := `enum Status {
+  Active
+  Suspended(reason: String, days: Int)
+}
+func describe(s: Status) {
+  return match s {
+    Status.Active -> "active"
+    Status.Suspended(reason, 0) -> reason
+    Status.Suspended(_, days) -> { "suspended for " + days }
+  }
+}
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + `describe(Status.Active)`,
+		defs + `describe(Status.Suspended("late", 0))`,
+		defs + `describe(Status.Suspended("late", 3))`,
+		defs + `Status.Suspended("late", 3).days`,
+		defs + `Status.Suspended("late", 3)`,
+		defs + `Status.Active == Status.Active`,
+		defs + `is_a?(Status.Active, Status)`,
+		defs + `match 2 { 1 -> "one", 2 -> "two", _ -> "many" }`,
+		defs + `match -1 { -1 -> "minus one", n -> n }`,
+		defs + `match 5 { 1 -> 1, n -> n * 2 }`,
+		defs + `match [1, 2, 3] { [] -> 0, [x] -> x, [x, ...rest] -> len(rest) }`,
+		defs + `match [1, 2] { [1, x] -> x, _ -> 0 }`,
+		defs + `match {name: "ann", age: 30} { {name: "bob"} -> 0, {age} -> age }`,
+		defs + `match true { false -> 0, true -> 1 }`,
+		defs + "match [[1, 2], {a: [3]}] { [[x, 2], {a: [y]}] -> x + y }",
+		defs + "var n = 0\nfor x in [1, 2, 3] { match x { 2 -> { continue }, _ -> { n = n + x } } }\nn",
+		defs + "func f(v: Any) { match v { [x, ...r] -> { return x } _ -> 0 } }\nf([7, 8])",
+	})
+}
+
+func TestMatchErrors(t *testing.T) {
+	defs := `enum Status { Active, Suspended(reason: String) }
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + `match Status.Suspended("x") { Status.Active -> 1 }`,
+		defs + `match 3 { 1 -> 1 }`,
+		defs + `Status.Closed`,
+		defs + `Status.Suspended()`,
+		defs + `Status.Suspended("x").days`,
+		defs + `match 1 { Status.Suspended(a, b) -> 1 }`,
+		defs + "var n = 1\nmatch 1 { n.Active -> 1 }",
+		defs + "const x = 1\nmatch 2 { x -> x }",
+		defs + "func f() {\n  const x = 1\n  return match 2 { x -> x }\n}\nf()",
+	})
+}
+
+func TestStrictEnums(t *testing.T) {
+	evaluator.Strict = true
+	defer func() { evaluator.Strict = false }()
+
+	defs := `enum Status { Active, Suspended(reason: String) }
+func f(s: Status) { return 1 }
+`
+
+	testSameAsEvaluator(t, []string{
+		defs + `Status.Suspended(1)`,
+		defs + `f(1)`,
+		defs + `f(Status.Active)`,
+		"func f() {\n  enum List { Empty, Node(next: List) }\n  return List.Node(List.Node(List.Empty))\n}\nf()",
+		"func f() {\n  enum List { Empty, Node(next: List) }\n  return List.Node(1)\n}\nf()",
+	})
+}
+
+func TestDecodedBytecode(t *testing.T) {
+	inputs := []string{
+		`func inner(n: Int, by: Int = 2) {
+  return n * by + missing
+}
+
+try { inner(1) } catch e { e.message + " " + e.stack[0] }`,
+		`interface Named { func name() -> String }
+class Base { public var n: Int = 1 }
+class Person extends Base implements Named {
+  var first: String = "Ann"
+  public func name() -> String { return self.first }
+}
+[new(Person).name(), new(Person).n]`,
+		`class Person implements Missing {}`,
+		`enum Shape { Dot, Circle(r: Int) }
+func area(s: Shape) {
+  return match s {
+    Shape.Circle(r) -> r * r * 3
+    Shape.Dot -> 0
+  }
+}
+[area(Shape.Circle(2)), area(Shape.Dot), match [1, 2] { [a, ...rest] -> rest, _ -> 0 }]`,
+	}
+
+	for _, input := range inputs {
+		bytecode, err := compiler.New().Compile(parse(t, input))
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		decoded := encodeAndDecode(t, bytecode)
+
+		expected := describe(New(bytecode).Run())
+		if got := describe(New(decoded).Run()); got != expected {
+			t.Errorf("wrong result for %q. want=%q, got=%q", input, expected, got)
+		}
+	}
+}
+
+func TestDecodedModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib.emo":  "import \"util.emo\"\nvar x = 10\nfunc get() { return x + util.y }\n",
+		"util.emo": "var y = 5\nconst X = 1\n",
+		"bad.emo":  "var z = 1 + \"s\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	input := `import "` + dir + `/lib.emo"
+import "` + dir + `/lib.emo" as again
+var x = 1
+func bad() { import "` + dir + `/bad.emo" }
+var first = try { bad() } catch e { e.message }
+[lib.get(), again.x, x, first, try { bad() } catch e { e.stack[0] }]`
+
+	bytecode, err := compiler.New().Compile(parse(t, input))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	expected := describe(evaluator.Eval(parse(t, input), object.NewEnvironment()))
+
+	decoded := encodeAndDecode(t, bytecode)
+
+	// the modules are in the bytecode file, which runs without them
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := describe(New(decoded).Run()); got != expected {
+		t.Errorf("wrong result. want=%q, got=%q", expected, got)
+	}
+}
+
+func encodeAndDecode(t *testing.T, bytecode *compiler.Bytecode) *compiler.Bytecode {
+	t.Helper()
+
+	data, err := compiler.Encode(bytecode)
+	if err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	decoded, err := compiler.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	return decoded
+}
+
+func TestBadBytecode(t *testing.T) {
+	// the instructions decode, but rethrow an integer
+	bytecode, err := compiler.New().Compile(parse(t, "1"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode.Main.Instructions = slices.Concat(compiler.Make(compiler.OpConstant, 0), compiler.Make(compiler.OpRethrow),
+		compiler.Make(compiler.OpConstant, 0), compiler.Make(compiler.OpReturn))
+
+	result := New(encodeAndDecode(t, bytecode)).Run()
+
+	rerr, ok := result.(*object.Error)
+	if !ok || rerr.Kind != object.RUNTIME_ERROR || !strings.HasPrefix(rerr.Message, "bad bytecode: ") {
+		t.Errorf("wrong result. got=%s", describe(result))
+	}
+}